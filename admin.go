@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
+
+	"mystrom-exporter/pkg/discover"
+	"mystrom-exporter/pkg/inventory"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/storage"
+	"mystrom-exporter/pkg/web"
+)
+
+// -- the longest name stored on a device
+const maxDeviceName = 64
+
+// targetByMac -- resolves the mac address of a device into its target, using previous scrapes
+// and the discovery, returns an empty string for unknown devices
+func targetByMac(mac string) string {
+	normalized := mystrom.NormalizeMac(mac)
+	if target := mystrom.TargetByMac(normalized); target != "" {
+		return target
+	}
+
+	if *enableDiscovery {
+		if hw, err := hex.DecodeString(normalized); err == nil {
+			return discover.TargetByMacaddr(net.HardwareAddr(hw).String())
+		}
+	}
+	return ""
+}
+
+// deviceTarget -- resolves the {mac} of the route, writes a 404 and returns false for unknown devices
+func deviceTarget(w http.ResponseWriter, r *http.Request) (string, bool) {
+	mac := mux.Vars(r)["mac"]
+	target := targetByMac(mac)
+	if target == "" {
+		web.Error(w, r, "", fmt.Sprintf("device '%v' is unknown, it must be scraped or discovered first", mac), http.StatusNotFound)
+		return "", false
+	}
+	return target, true
+}
+
+// rebootHandler -- asks the device to restart
+func rebootHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := deviceTarget(w, r)
+	if !ok {
+		return
+	}
+
+	log.Infof("got reboot request from '%v' for target '%v'", r.RemoteAddr, target)
+	if err := mystrom.NewExporter(target).Reboot(); err != nil {
+		mystromAdminCounterVec.WithLabelValues(target, "reboot", "error").Inc()
+		log.Errorf("failed to reboot target '%v': %v", target, err)
+		web.Error(w, r, target, fmt.Sprintf("failed to reboot target '%v': %v", target, err), http.StatusBadGateway)
+		return
+	}
+	mystromAdminCounterVec.WithLabelValues(target, "reboot", "ok").Inc()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// firmwareCheckHandler -- asks the device to look for a firmware update and passes its answer on
+func firmwareCheckHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := deviceTarget(w, r)
+	if !ok {
+		return
+	}
+
+	log.Infof("got firmware check request from '%v' for target '%v'", r.RemoteAddr, target)
+	data, err := mystrom.NewExporter(target).CheckFirmware()
+	if err != nil {
+		mystromAdminCounterVec.WithLabelValues(target, "firmware_check", "error").Inc()
+		log.Errorf("failed to check firmware of target '%v': %v", target, err)
+		web.Error(w, r, target, fmt.Sprintf("failed to check firmware of target '%v': %v", target, err), http.StatusBadGateway)
+		return
+	}
+	mystromAdminCounterVec.WithLabelValues(target, "firmware_check", "ok").Inc()
+	log.Infof("firmware check of target '%v': %s", target, data)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// settingsHandler -- passes the settings of the device on and archives them if they changed
+func settingsHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := deviceTarget(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := mystrom.NewExporter(target).FetchSettings()
+	if err != nil {
+		mystromAdminCounterVec.WithLabelValues(target, "settings", "error").Inc()
+		web.Error(w, r, target, fmt.Sprintf("failed to fetch settings of target '%v': %v", target, err), http.StatusBadGateway)
+		return
+	}
+	mystromAdminCounterVec.WithLabelValues(target, "settings", "ok").Inc()
+
+	mac := mystrom.NormalizeMac(mux.Vars(r)["mac"])
+	if archived, err := storage.ArchiveSettings(mac, data); err != nil {
+		log.Errorf("failed to archive settings of target '%v': %v", target, err)
+	} else if archived {
+		log.Infof("archived changed settings of target '%v'", target)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// settingsDiffHandler -- compares the current settings of the device with the latest snapshot
+func settingsDiffHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := deviceTarget(w, r)
+	if !ok {
+		return
+	}
+
+	mac := mystrom.NormalizeMac(mux.Vars(r)["mac"])
+	archived, taken, err := storage.LatestSettings(mac)
+	if err != nil {
+		web.Error(w, r, target, fmt.Sprintf("failed to read archived settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if archived == nil {
+		web.Error(w, r, target, fmt.Sprintf("no archived settings for device '%v'", mac), http.StatusNotFound)
+		return
+	}
+
+	current, err := mystrom.NewExporter(target).FetchSettings()
+	if err != nil {
+		web.Error(w, r, target, fmt.Sprintf("failed to fetch settings of target '%v': %v", target, err), http.StatusBadGateway)
+		return
+	}
+
+	changes, err := storage.DiffSettings(archived, current)
+	if err != nil {
+		web.Error(w, r, target, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"target":   target,
+		"archived": taken,
+		"changes":  changes,
+	})
+}
+
+// setNameHandler -- stores the name of the JSON object of the request on the device, the name shown in the
+// myStrom app and exposed by the exporter
+func setNameHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := deviceTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&request); err != nil {
+		web.Error(w, r, target, fmt.Sprintf("name must be a JSON object with a name: %v", err), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > maxDeviceName {
+		web.Error(w, r, target, fmt.Sprintf("name must have 1 to %d characters", maxDeviceName), http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("got name request from '%v' for target '%v': %v", r.RemoteAddr, target, name)
+	if err := mystrom.NewExporter(target).SetName(name); err != nil {
+		mystromAdminCounterVec.WithLabelValues(target, "name", "error").Inc()
+		log.Errorf("failed to set name of target '%v': %v", target, err)
+		web.Error(w, r, target, fmt.Sprintf("failed to set name of target '%v': %v", target, err), http.StatusBadGateway)
+		return
+	}
+	mystromAdminCounterVec.WithLabelValues(target, "name", "ok").Inc()
+
+	// -- the labels of the discovery don't wait for the next scrape
+	inventory.Observe(inventory.Device{Mac: mystrom.NormalizeMac(mux.Vars(r)["mac"]), Name: name})
+
+	writeJSON(w, http.StatusOK, map[string]string{"target": target, "name": name})
+}
+
+// proxyHandler -- passes GET requests for the allowed paths on to the device, without any query
+// as some device endpoints change state when called with parameters
+func proxyHandler(allowed []string) http.Handler {
+	paths := make(map[string]bool, len(allowed))
+	for _, path := range allowed {
+		paths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, ok := deviceTarget(w, r)
+		if !ok {
+			return
+		}
+
+		path := "/" + mux.Vars(r)["path"]
+		if !paths[path] {
+			web.Error(w, r, target, fmt.Sprintf("path '%v' isn't allowed to be proxied", path), http.StatusForbidden)
+			return
+		}
+
+		data, err := mystrom.NewExporter(target).Fetch(path)
+		if err != nil {
+			mystromAdminCounterVec.WithLabelValues(target, "proxy", "error").Inc()
+			web.Error(w, r, target, fmt.Sprintf("failed to fetch %v from target '%v': %v", path, target, err), http.StatusBadGateway)
+			return
+		}
+		mystromAdminCounterVec.WithLabelValues(target, "proxy", "ok").Inc()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// annotationsHandler -- returns the annotations of the device, they are kept by mac address and
+// don't require the device to be known
+func annotationsHandler(w http.ResponseWriter, r *http.Request) {
+	mac, ok := annotationsMac(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, storage.Annotations(mac))
+}
+
+// setAnnotationsHandler -- replaces the annotations of the device by the JSON object of the request,
+// the keys must be valid label names as they are exposed as labels
+func setAnnotationsHandler(w http.ResponseWriter, r *http.Request) {
+	mac, ok := annotationsMac(w, r)
+	if !ok {
+		return
+	}
+
+	values := make(map[string]string)
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&values); err != nil {
+		web.Error(w, r, "", fmt.Sprintf("annotations must be a JSON object of strings: %v", err), http.StatusBadRequest)
+		return
+	}
+	for key := range values {
+		if !model.LabelName(key).IsValid() || strings.HasPrefix(key, "__") {
+			web.Error(w, r, "", fmt.Sprintf("invalid annotation key '%v'", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := storage.SetAnnotations(mac, values); err != nil {
+		log.Errorf("failed to save annotations of device '%v': %v", mac, err)
+		web.Error(w, r, "", fmt.Sprintf("failed to save annotations: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("annotations of device '%v' set by '%v'", mac, r.RemoteAddr)
+
+	writeJSON(w, http.StatusOK, values)
+}
+
+// annotationsMac -- returns the normalized {mac} of the route, writes a 400 and returns false if it is invalid
+func annotationsMac(w http.ResponseWriter, r *http.Request) (string, bool) {
+	mac := mystrom.NormalizeMac(mux.Vars(r)["mac"])
+	if _, err := hex.DecodeString(mac); err != nil || len(mac) != 12 {
+		web.Error(w, r, "", fmt.Sprintf("invalid mac address '%v'", mux.Vars(r)["mac"]), http.StatusBadRequest)
+		return "", false
+	}
+	return mac, true
+}
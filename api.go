@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/control"
+	"mystrom-exporter/pkg/discover"
+	"mystrom-exporter/pkg/inventory"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/poller"
+	"mystrom-exporter/pkg/provider"
+	"mystrom-exporter/pkg/web"
+)
+
+// maxRelayWaitTimeout -- upper bound of the timeout a client can request for the long-poll
+const maxRelayWaitTimeout = 5 * time.Minute
+
+// relayWaitHandler -- long-poll returning as soon as the relay of the target changes,
+// responds with 204 when the timeout expires without a change
+func relayWaitHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := targetParam(w, r)
+	if !ok {
+		return
+	}
+
+	timeout := 30 * time.Second
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 {
+			web.Error(w, r, target, fmt.Sprintf("invalid 'timeout' parameter '%v'", value), http.StatusBadRequest)
+			return
+		}
+		if timeout > maxRelayWaitTimeout {
+			timeout = maxRelayWaitTimeout
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	event, polled := poller.WaitRelayChange(ctx, target)
+	if !polled {
+		web.Error(w, r, target, fmt.Sprintf("relay of target '%v' isn't polled", target), http.StatusNotFound)
+		return
+	}
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, event)
+}
+
+// relayControlHandler -- switches the relay of the target, the action is one of on, off or toggle
+func relayControlHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := targetParam(w, r)
+	if !ok {
+		return
+	}
+
+	action, err := control.ParseAction(r.URL.Query().Get("action"))
+	if err != nil {
+		web.Error(w, r, target, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("got control request from '%v' to turn relay of target '%v' %v", r.RemoteAddr, target, action)
+	if err := control.Execute(target, action, "api"); err != nil {
+		if _, ok := err.(*control.BlockedError); ok {
+			web.Error(w, r, target, err.Error(), http.StatusConflict)
+			return
+		}
+		if _, ok := err.(*control.NoRelayError); ok {
+			web.Error(w, r, target, err.Error(), http.StatusBadRequest)
+			return
+		}
+		web.Error(w, r, target, fmt.Sprintf("failed to %v relay of target '%v': %v", action, target, err), http.StatusBadGateway)
+		return
+	}
+
+	if control.DryRun() {
+		w.Header().Set("X-Dry-Run", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// targetsHandler -- returns the targets of all providers
+func targetsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, provider.Targets())
+}
+
+// inventoryHandler -- returns all devices seen through scrapes or the discovery, as JSON or as CSV
+// with the parameter format=csv or when requested by the Accept header
+func inventoryHandler(w http.ResponseWriter, r *http.Request) {
+	devices := inventory.Devices()
+
+	if r.URL.Query().Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="mystrom-inventory.csv"`)
+		if err := inventory.WriteCSV(w, devices); err != nil {
+			log.Errorf("failed to write inventory: %v", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, devices)
+}
+
+// rawDiscoveryHandler -- returns the recent discovery announcements as received, only the ones after
+// the optional parameter since (RFC 3339), so a client can poll with the time of the last one it got
+func rawDiscoveryHandler(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			web.Error(w, r, "", fmt.Sprintf("invalid 'since' parameter '%v'", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, discover.RawPackets(since))
+}
+
+// discoveryRegistryHandler -- returns the devices announced to this exporter, synced by its peers
+func discoveryRegistryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, discover.Registry())
+}
+
+// groupSummaryHandler -- returns the aggregated readings of a device group for dashboards, the optional
+// parameter label restricts the group to one group label
+func groupSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	summary, ok := mystrom.SummarizeGroup(name, r.URL.Query().Get("label"), time.Now())
+	if !ok {
+		web.Error(w, r, "", fmt.Sprintf("no configured device in group '%v'", name), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// writeJSON --
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("failed to encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/storage"
+)
+
+// persistedCounters -- the exporter counters kept across restarts by their metric name
+func persistedCounters() map[string]*prometheus.CounterVec {
+	return map[string]*prometheus.CounterVec{
+		namespace + "_requests_total":                 mystromRequestsCounterVec,
+		namespace + "_request_duration_seconds_total": mystromDurationCounterVec,
+		namespace + "_admin_requests_total":           mystromAdminCounterVec,
+	}
+}
+
+// restoreCounters -- adds the values of the last checkpoint to the exporter counters
+func restoreCounters() {
+	saved, err := storage.LoadCounters()
+	if err != nil {
+		log.Errorf("Failed to restore counters: %v", err)
+		return
+	}
+
+	for name, vec := range persistedCounters() {
+		for _, sample := range saved[name] {
+			counter, err := vec.GetMetricWith(sample.Labels)
+			if err != nil {
+				log.Warnf("Skipping checkpointed counter %v%v: %v", name, sample.Labels, err)
+				continue
+			}
+			counter.Add(sample.Value)
+		}
+	}
+	log.Infof("restored exporter counters from checkpoint")
+}
+
+// saveCounters --
+func saveCounters(gatherer prometheus.Gatherer) {
+	names := make([]string, 0)
+	for name := range persistedCounters() {
+		names = append(names, name)
+	}
+	if err := storage.SaveCounters(gatherer, names); err != nil {
+		log.Errorf("Failed to checkpoint counters: %v", err)
+	}
+}
+
+// checkpointCounters -- writes the exporter counters to disk in the given interval
+func checkpointCounters(gatherer prometheus.Gatherer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		saveCounters(gatherer)
+	}
+}
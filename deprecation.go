@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// deprecation -- a flag which was renamed or replaced by an option of the configuration file, the old name
+// is still accepted for two releases before it is removed
+type deprecation struct {
+	// -- the old name of the flag
+	name string
+	// -- the flag replacing it, the old name is registered as alias of it
+	replacement string
+	// -- the key of the configuration file replacing it, the old flag keeps its own definition until removed
+	configKey string
+}
+
+// -- the deprecated flags, oldest first
+var deprecations = []deprecation{
+	{name: "shard", replacement: "shard.spec"},
+}
+
+var deprecatedFlagsGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deprecated_flags_used",
+		Help:      "Whether a deprecated flag was used on startup, by flag and its replacement",
+	},
+	[]string{"flag", "replacement"})
+
+// replacedBy -- the replacement of the deprecation as it is shown to the operator
+func (d deprecation) replacedBy() string {
+	if d.configKey != "" {
+		return "config:" + d.configKey
+	}
+	return d.replacement
+}
+
+// registerDeprecatedFlags -- registers the old names as aliases of the flags replacing them, must be called
+// before the flags are parsed
+func registerDeprecatedFlags() {
+	for _, d := range deprecations {
+		if d.replacement == "" {
+			continue
+		}
+		replacement := flag.Lookup(d.replacement)
+		if replacement == nil {
+			panic(fmt.Sprintf("replacement %v of the deprecated flag %v is not defined", d.replacement, d.name))
+		}
+		flag.Var(replacement.Value, d.name, fmt.Sprintf("Deprecated, use --%v instead", d.replacement))
+	}
+}
+
+// warnDeprecatedFlags -- logs a warning for every deprecated flag set on the command line and exposes it
+// in the telemetry, fails if both the old and the new name are given
+func warnDeprecatedFlags() {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, d := range deprecations {
+		if !set[d.name] {
+			continue
+		}
+		if d.replacement != "" && set[d.replacement] {
+			log.Fatalf("The flags --%v and --%v exclude each other, --%v is deprecated", d.name, d.replacement, d.name)
+		}
+		log.With("flag", d.name).With("replacement", d.replacedBy()).
+			Warn("deprecated flag used, it will be removed in one of the next releases")
+		deprecatedFlagsGauge.WithLabelValues(d.name, d.replacedBy()).Set(1)
+	}
+}
+
+// isDeprecatedAlias -- whether the flag is only an alias of the flag replacing it
+func isDeprecatedAlias(name string) bool {
+	for _, d := range deprecations {
+		if d.name == name && d.replacement != "" {
+			return true
+		}
+	}
+	return false
+}
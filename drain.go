@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/poller"
+	"mystrom-exporter/pkg/sink"
+)
+
+// drain -- stops accepting requests and waits for the requests and polls in flight, bounded by the timeout
+func drain(servers []*http.Server, timeout time.Duration) {
+	log.Infof("draining, waiting up to %v for scrapes and polls in flight", timeout)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// -- stopping the poller releases the long-polls waiting for relay changes
+	polls := make(chan error, 1)
+	go func() {
+		polls <- poller.Stop(ctx)
+	}()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Warnf("drain timeout of %v exceeded, aborting requests in flight on %v: %v", timeout, server.Addr, err)
+		}
+	}
+	if err := <-polls; err != nil {
+		log.Warnf("drain timeout of %v exceeded, abandoning polls in flight: %v", timeout, err)
+	}
+	// -- the polls queued for the sinks are written in the remaining time
+	sink.Close(ctx)
+
+	log.Infof("drained in %v", time.Since(start))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/web"
+)
+
+// healthHandler -- answers 200 while the exporter runs, with deep=true only if at least the minimum
+// number of configured devices responded within the maximum age, so a monitor notices when the
+// devices became unreachable as a whole
+func healthHandler(cfg *config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("deep") != "true" {
+			w.Write([]byte("Healthy\n"))
+			return
+		}
+
+		targets := cfg.Targets()
+		required := *healthMinDevices
+		if required > len(targets) {
+			required = len(targets)
+		}
+
+		responded := 0
+		for _, target := range targets {
+			if contact := mystrom.LastContact(target); !contact.IsZero() && time.Since(contact) <= *healthMaxAge {
+				responded++
+			}
+		}
+
+		message := fmt.Sprintf("%d of %d configured devices responded within %v, %d required",
+			responded, len(targets), *healthMaxAge, required)
+		if responded < required {
+			web.Error(w, r, "", "Unhealthy: "+message, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("Healthy: " + message + "\n"))
+	})
+}
@@ -2,23 +2,42 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
 
+	"mystrom-exporter/pkg/budget"
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/control"
 	"mystrom-exporter/pkg/discover"
+	"mystrom-exporter/pkg/exposition"
+	"mystrom-exporter/pkg/firmware"
+	"mystrom-exporter/pkg/inventory"
+	"mystrom-exporter/pkg/leader"
+	"mystrom-exporter/pkg/lru"
+	"mystrom-exporter/pkg/mdns"
 	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/poller"
+	"mystrom-exporter/pkg/provider"
+	"mystrom-exporter/pkg/schedule"
+	"mystrom-exporter/pkg/shard"
+	"mystrom-exporter/pkg/sink"
+	"mystrom-exporter/pkg/storage"
 	"mystrom-exporter/pkg/version"
+	"mystrom-exporter/pkg/web"
+	"mystrom-exporter/pkg/webhook"
 )
 
 // MystromReqStatus -- represents the request to MyStrom device status
@@ -30,6 +49,9 @@ const (
 	ErrorSocket
 	ErrorTimeout
 	ErrorParsingValue
+	ErrorUnsupported
+	ErrorBudget
+	ErrorCanceled
 )
 
 const namespace = "mystrom_exporter"
@@ -45,11 +67,153 @@ var (
 		"Show version information.")
 	enableDiscovery = flag.Bool("discovery.enabled", false,
 		"Enable the mystrom autodiscovery")
+	discoveryReusePort = flag.Bool("discovery.reuse-port", true,
+		"Share udp port 7979 with other listeners for the announcements (SO_REUSEADDR/SO_REUSEPORT) where supported")
+	discoveryBindRetry = flag.Duration("discovery.bind-retry-interval", time.Minute,
+		"Interval to retry binding udp port 7979 when it's in use, the exporter runs without discovered devices meanwhile")
+	discoveryRawBuffer = flag.Int("discovery.raw-buffer", 256,
+		"Number of recent discovery announcements served on /api/v1/discovery/raw, 0 disables the feed")
+	discoveryForward = flag.String("discovery.forward-addresses", "",
+		"Comma separated udp addresses every discovery announcement is forwarded to unchanged, e.g. 127.0.0.1:7980")
+	discoveryOUIPolicy = flag.String("discovery.oui-policy", discover.OUIPolicyLabel,
+		"Handling of announced mac addresses without a vendor prefix of myStrom, label or drop")
+	discoveryExtraOUIs = flag.String("discovery.extra-ouis", "",
+		"Comma separated vendor prefixes accepted as myStrom devices in addition to 64:00:2D")
+	discoveryHealthLabels = flag.Bool("discovery.health-labels", false,
+		"Label the targets of /discover with __meta_mystrom_health (ok, degraded, down or unknown) by their recent scrapes")
+	discoveryDeviceNameLabel = flag.Bool("discovery.device-name-label", false,
+		"Label the targets of /discover with device_name, the name assigned to the device in the myStrom app")
+	discoveryPeers = flag.String("discovery.peers", "",
+		"Comma separated peer exporters whose discovered devices are merged, e.g. http://exporter-2:9452")
+	discoveryPeerInterval = flag.Duration("discovery.peer-interval", 30*time.Second,
+		"Interval to sync the discovered devices of the peer exporters")
+	configFile = flag.String("config.file", "",
+		"Path to the optional configuration file")
+	enableControl = flag.Bool("control.enabled", false,
+		"Enable the API to switch the relays of the devices")
+	controlDryRun = flag.Bool("control.dry-run", false,
+		"Validate, log and count relay control requests without sending them to the devices")
+	storagePath = flag.String("storage.path", "/var/lib/mystrom-exporter",
+		"Directory to keep the state of the exporter in, e.g. the archived device settings")
+	enableMdns = flag.Bool("mdns.enabled", false,
+		"Advertise the exporter via mDNS as _prometheus-http._tcp")
+	mdnsInstance = flag.String("mdns.instance", "",
+		"Instance name used in the mDNS advertisement, defaults to the hostname")
+	pollInterval = flag.Duration("poll.interval", 0,
+		"Interval to poll the metrics of the configured devices, 0 disables polling")
+	pollMaxAge = flag.Duration("poll.max-age", 5*time.Minute,
+		"Maximum age of polled metrics served on the device path, older ones are scraped again")
+	pollTimestamps = flag.Bool("poll.timestamps", false,
+		"Expose polled metrics with the time they were read from the device")
+	pollPowerBuckets = flag.String("poll.power-buckets", "",
+		"Comma separated bucket bounds in watts of the histogram of polled power readings, empty disables the histogram")
+	pollAdaptiveMin = flag.Duration("poll.adaptive-min-interval", 0,
+		"Shortest interval of polling devices with changing power or relay, 0 disables the adaption of poll.interval")
+	pollAdaptiveMax = flag.Duration("poll.adaptive-max-interval", 0,
+		"Longest interval of polling devices with stable readings, 0 disables the adaption of poll.interval")
+	pollMaxConcurrency = flag.Int("poll.max-concurrency", 0,
+		"Maximum number of polls in flight, further polls wait by the priority of their device or are skipped; 0 doesn't limit them")
+	pollSmoothingAlpha = flag.Float64("poll.smoothing-alpha", 0,
+		"Weight of the latest poll in the exponential moving average of the power exposed as mystrom_power_smoothed, 0 disables it")
+	traceExemplars = flag.Bool("tracing.exemplars", false,
+		"Attach the trace id propagated with scrape requests as exemplar to the scrape duration histogram")
+	checkpointInterval = flag.Duration("storage.checkpoint-interval", 0,
+		"Interval to checkpoint the exporter counters to the storage path, 0 disables checkpoints")
+	relayPollInterval = flag.Duration("poll.relay-interval", 0,
+		"Interval to poll the relay state of the configured devices, 0 disables polling")
+	allowedTargetPorts = flag.String("web.allowed-target-ports", "80,443",
+		"Comma separated list of ports allowed in the target parameter")
+	allowedLocalTargets = flag.String("web.allowed-local-targets", "",
+		"Comma separated list of loopback or link-local networks allowed in the target parameter, e.g. 127.0.0.0/8")
+	allowedCIDRs = flag.String("web.allowed-cidrs", "",
+		"Comma separated networks in CIDR notation allowed to reach any endpoint, other sources are answered with 403; any if empty")
+	adminListenAddress = flag.String("web.admin-listen-address", "",
+		"Separate address to serve the api and admin endpoints on, e.g. localhost or a management network; empty serves them with the metrics")
+	requestTimeout = flag.Duration("web.request-timeout", 0,
+		"Maximum time to serve a request of an endpoint, slower ones are answered with 503; 0 disables the limit")
+	maxInFlight = flag.Int("web.max-requests-in-flight", 0,
+		"Maximum number of concurrent requests per endpoint, further ones are answered with 503 and Retry-After; 0 disables the limit")
+	readHeaderTimeout = flag.Duration("web.read-header-timeout", 10*time.Second,
+		"Maximum time to read the headers of a request")
+	idleTimeout = flag.Duration("web.idle-timeout", 2*time.Minute,
+		"Maximum time to keep an idle connection open")
+	maxHeaderBytes = flag.Int("web.max-header-bytes", 16<<10,
+		"Maximum size of the headers of a request in bytes")
+	maxBodyBytes = flag.Int64("web.max-body-bytes", 64<<10,
+		"Maximum size of the body of a request in bytes, 0 disables the limit")
+	scrapeOnly = flag.Bool("web.scrape-only", false,
+		"Serve only the metrics and device paths, everything else including the landing page responds with 404")
+	exporterInstance = flag.String("web.exporter-instance", "",
+		"Value of the exporter_instance label added to all metrics and the discovery, e.g. the hostname or a site name; empty disables the label")
+	shardSpec = flag.String("shard.spec", "1/1",
+		"Shard N/M of this instance, the devices are split by the hash of their mac address across M instances")
+	leaderLeaseFile = flag.String("leader.lease-file", "",
+		"Lease file on storage shared by redundant instances to elect the one pushing to the outputs, empty disables leader election")
+	leaderLeaseDuration = flag.Duration("leader.lease-duration", 15*time.Second,
+		"Duration of the leader lease, the other instances take over once it expired")
+	leaderID = flag.String("leader.id", "",
+		"Identity of the instance in the leader election, defaults to the hostname and process id")
+	maxTargets = flag.Int("limits.max-targets", 10000,
+		"Maximum number of targets and devices remembered, the least recently seen ones are dropped first; 0 disables the limit")
+	stableAfter = flag.Duration("inventory.stable-after", 72*time.Hour,
+		"Time a device must have been seen for to be reported as missing once it disappears")
+	missingAfter = flag.Duration("inventory.missing-after", 15*time.Minute,
+		"Time without announcement or successful scrape after which a stable device is reported as missing")
+	drainTimeout = flag.Duration("shutdown.drain-timeout", 15*time.Second,
+		"Maximum time to wait for scrapes and polls in flight on shutdown")
+	warmupEnabled = flag.Bool("scrape.warmup", false,
+		"Scrape all configured devices once at startup")
+	warmupConcurrency = flag.Int("scrape.warmup-concurrency", 4,
+		"Maximum number of devices scraped in parallel by the warm-up scrape")
+	unsupportedTTL = flag.Duration("scrape.unsupported-ttl", 15*time.Minute,
+		"Period to fail fast for targets which turned out not to be myStrom devices, 0 disables it")
+	enableWebhooks = flag.Bool("webhook.enabled", false,
+		"Receive the action urls of the devices on /api/v1/webhook/...")
+	webhookNetworks = flag.String("webhook.allowed-networks", "",
+		"Comma separated networks in CIDR notation the devices may call the webhooks from, any if empty")
+	healthMinDevices = flag.Int("health.min-devices", 1,
+		"Minimum number of configured devices which must have responded for /-/healthy?deep=true, at most all configured ones")
+	healthMaxAge = flag.Duration("health.max-age", 5*time.Minute,
+		"Maximum age of the last response of a configured device counted by /-/healthy?deep=true")
+	scrapeBudget = flag.Duration("scrape.budget-per-hour", 0,
+		"Time the exporter may spend requesting a device per hour, further scrapes and polls are skipped; 0 only accounts the time")
+	connectionAttemptDelay = flag.Duration("scrape.connection-attempt-delay", 250*time.Millisecond,
+		"Delay between the connection attempts to the IPv6 and IPv4 addresses of a device name, 0 uses the default dialing of Go")
+	scrapeTimeout = flag.Duration("scrape.timeout", 0,
+		"Time a scrape of a device may take as a whole, outstanding requests are canceled after it; 0 limits only the single requests to 5s")
+	scrapeTLSCAFile = flag.String("scrape.tls-ca-file", "",
+		"CA bundle to verify the certificates of devices reached by https, the system roots if empty")
+	scrapeTLSSkipVerify = flag.Bool("scrape.tls-insecure-skip-verify", false,
+		"Don't verify the certificates of devices reached by https")
+	scrapeTokenFile = flag.String("scrape.token-file", "",
+		"File with the token sent in the Token header to devices protecting their api, devices can override it in the configuration file")
+	temperatureFahrenheit = flag.Bool("metrics.temperature-fahrenheit", false,
+		"Additionally export the temperature in degrees Fahrenheit as mystrom_temperature_fahrenheit")
+	temperatureRaw = flag.Bool("metrics.temperature-raw", false,
+		"Additionally export the raw and compensated temperature of /temp, one more request to the switch per scrape")
+	metricNames = flag.String("metrics.names", exposition.NamesLegacy,
+		"Names of the device metrics: legacy, v2, or both to migrate; a scrape can choose with the parameter names")
+	logPayloads = flag.Bool("debug.log-payloads", false,
+		"Log the raw device response of failed parses, at most once a minute per target; requires a build with '-tags payloadlog'")
+	payloadMaxBytes = flag.Int("debug.payload-max-bytes", 512,
+		"Maximum number of bytes of a logged payload, 0 logs it completely")
+	payloadRedact = flag.Bool("debug.payload-redact", true,
+		"Replace addresses, names and credentials in logged payloads")
 )
 var (
 	mystromDurationCounterVec *prometheus.CounterVec
 	mystromRequestsCounterVec *prometheus.CounterVec
+	mystromAdminCounterVec    *prometheus.CounterVec
+	mystromDurationHistogram  *prometheus.HistogramVec
+	mystromUpGaugeVec         *prometheus.GaugeVec
 )
+var targetPolicy *web.TargetPolicy
+
+// -- the source networks allowed to reach any endpoint, all if empty
+var allowedSources []*net.IPNet
+
+// -- the labels added to the exporters own metrics and to the device metrics
+var exporterLabels, deviceLabels map[string]string
 var landingPage = []byte(`<html>
 <head>
 	<title>myStrom switch report Exporter</title>
@@ -73,10 +237,20 @@ var landingPage = []byte(`<html>
 	<input type="submit" value="Submit">
 </form>
 <p><a href='` + *metricsPath + `'>Metrics</a></p>
+<p><a href='/ui'>Devices</a></p>
 </body>
 </html>`)
 
 func main() {
+	// -- subcommands are handled before the flags of the exporter
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(runSchema(os.Args[2:], os.Stdout))
+	}
+
+	registerDeprecatedFlags()
 	flag.Parse()
 
 	// log.Base().SetLevel("debug")
@@ -91,46 +265,271 @@ func main() {
 		fmt.Fprintln(os.Stdout, v)
 		os.Exit(0)
 	}
+	warnDeprecatedFlags()
+
+	// -- load the optional configuration file
+	var err error
+	cfg := config.New()
+	if *configFile != "" {
+		if cfg, err = config.Load(*configFile); err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+	}
+	if targetPolicy, err = web.NewTargetPolicy(*allowedTargetPorts, *allowedLocalTargets); err != nil {
+		log.Fatalf("Failed to parse the allowed targets: %v", err)
+	}
+	if allowedSources, err = web.ParseNetworks(*allowedCIDRs); err != nil {
+		log.Fatalf("Failed to parse the allowed cidrs: %v", err)
+	}
+	logConfig(cfg)
+	targetPolicy.Allow(cfg.Targets()...)
+	mystrom.SetAddressGuard(targetPolicy.CheckAddress)
+	exporterLabels, deviceLabels = constLabels(cfg)
+	if err := shard.Initialize(*shardSpec); err != nil {
+		log.Fatalf("Failed to parse the shard: %v", err)
+	}
+	if err := exposition.ValidateNames(*metricNames); err != nil {
+		log.Fatalf("Failed to parse the metric names: %v", err)
+	}
+	mystrom.SetConfig(cfg)
+	mystrom.SetUnsupportedTTL(*unsupportedTTL)
+	mystrom.SetFahrenheit(*temperatureFahrenheit)
+	mystrom.SetRawTemperature(*temperatureRaw)
+	mystrom.SetConnectionAttemptDelay(*connectionAttemptDelay)
+	mystrom.SetTokenFile(*scrapeTokenFile)
+	mystrom.SetScrapeTimeout(*scrapeTimeout)
+	if err := mystrom.SetTLS(*scrapeTLSCAFile, *scrapeTLSSkipVerify); err != nil {
+		log.Fatalf("Invalid scrape.tls-ca-file: %v", err)
+	}
+	// -- the relays must not be switchable by anyone on the network
+	if *enableControl && !cfg.Web.AuthEnabled() {
+		log.Fatal("control.enabled requires authentication, configure basic_auth_users, client_cert_roles or oidc")
+	}
+	budget.Initialize(*scrapeBudget, cfg.ScrapeBudgets())
+	powerBuckets, err := poller.ParseBuckets(*pollPowerBuckets)
+	if err != nil {
+		log.Fatalf("Failed to parse the power buckets: %v", err)
+	}
+	poller.SetPowerBuckets(powerBuckets)
+	if err := poller.SetSmoothing(*pollSmoothingAlpha); err != nil {
+		log.Fatalf("Invalid poll.smoothing-alpha: %v", err)
+	}
+	poller.SetRelayWebhook(cfg.RelayWebhook)
+	if *pollMaxConcurrency < 0 {
+		log.Fatalf("Invalid poll.max-concurrency: %d", *pollMaxConcurrency)
+	}
+	poller.SetMaxConcurrency(*pollMaxConcurrency)
+	if err := poller.SetAdaptive(*pollAdaptiveMin, *pollAdaptiveMax); err != nil {
+		log.Fatalf("Invalid poll.adaptive-min-interval or poll.adaptive-max-interval: %v", err)
+	}
+	poller.SetPriorities(cfg.Priorities())
+	if err := mystrom.SetPayloadLogging(*logPayloads, *payloadMaxBytes, *payloadRedact); err != nil {
+		log.Fatalf("Failed to enable payload logging: %v", err)
+	}
+	storage.Initialize(*storagePath)
+	mystrom.LoadCapabilities()
+	if err := storage.LoadAnnotations(); err != nil {
+		log.Errorf("Failed to load device annotations: %v", err)
+	}
+	inventory.SetMissing(*stableAfter, *missingAfter)
+	inventory.SetMaxDevices(*maxTargets)
+	lru.Targets.OnEvict(mystrom.Forget, budget.Forget, poller.Forget, forgetTarget)
+	lru.Targets.SetMax(*maxTargets)
+	discover.SetMaxDevices(*maxTargets)
+	if err := webhook.Initialize(*webhookNetworks, *maxTargets); err != nil {
+		log.Fatalf("Failed to parse the webhook networks: %v", err)
+	}
+	inventory.Initialize(time.Minute)
+	firmware.Initialize(cfg.Firmware)
 
 	// -- create a new registry for the exporter telemetry
 	telemetryRegistry := setupMetrics()
+	if *checkpointInterval > 0 {
+		restoreCounters()
+		go checkpointCounters(telemetryRegistry, *checkpointInterval)
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
+	// -- elect the instance pushing to the outputs among redundant instances
+	if *leaderLeaseFile != "" {
+		id := *leaderID
+		if id == "" {
+			hostname, _ := os.Hostname()
+			id = fmt.Sprintf("%v-%d", hostname, os.Getpid())
+		}
+		leader.Initialize(*leaderLeaseFile, *leaderLeaseDuration, id)
+	}
+
+	if err := mystrom.AddOUIs(*discoveryExtraOUIs); err != nil {
+		log.Fatalf("Invalid discovery.extra-ouis: %v", err)
+	}
+
+	provider.SetHealthLabels(*discoveryHealthLabels)
+	provider.SetDeviceNameLabels(*discoveryDeviceNameLabel)
+
 	// -- startup the discover engine
 	if *enableDiscovery {
-		discover.Initialize(*listenAddress)
+		if err := discover.SetRawFeed(*discoveryRawBuffer, *discoveryForward); err != nil {
+			log.Fatalf("Failed to setup the raw discovery feed: %v", err)
+		}
+		if err := discover.SetOUIPolicy(*discoveryOUIPolicy); err != nil {
+			log.Fatalf("Invalid discovery.oui-policy: %v", err)
+		}
+		discover.Initialize(*discoveryReusePort, *discoveryBindRetry)
+		if err := discover.InitializePeers(*discoveryPeers, *discoveryPeerInterval); err != nil {
+			log.Fatalf("Invalid discovery.peers: %v", err)
+		}
+	}
+
+	// -- the outputs of the polls
+	if err := setupSinks(cfg); err != nil {
+		log.Fatalf("Failed to setup the sinks: %v", err)
+	}
+
+	// -- collect the targets of the configuration, the discovery and the other providers
+	if err := setupProviders(cfg); err != nil {
+		log.Fatalf("Failed to setup the target providers: %v", err)
+	}
+	providersCtx, stopProviders := context.WithCancel(context.Background())
+	defer stopProviders()
+	provider.OnChange(targetsChanged)
+	provider.Run(providersCtx)
+	offerTargets(provider.Targets())
+
+	// -- startup the polling of the targets of this shard
+	var targets []string
+	if *pollInterval > 0 || *relayPollInterval > 0 || *warmupEnabled {
+		targets = pollTargets(provider.Targets())
+	}
+	startPolling(targets)
+
+	// -- scrape the targets once, so the first scrape by Prometheus finds populated caches
+	if *warmupEnabled {
+		go warmUp(targets, *warmupConcurrency)
+	}
+
+	// -- startup the interlocks and the schedules of the configuration
+	control.SetDryRun(*controlDryRun)
+	control.Initialize(cfg)
+	schedule.Initialize(cfg)
+	controlCtx, stopControl := context.WithCancel(context.Background())
+	defer stopControl()
+	if *enableControl {
+		control.Watch(controlCtx)
 	}
 
-	// -- create the mux router config
+	// -- advertise the exporter on the local network
+	if *enableMdns {
+		if err := advertise(); err != nil {
+			log.Errorf("Failed to advertise via mdns: %v", err)
+		}
+	}
+
+	// -- create the mux router config, the routes require the roles of the web configuration
+	auth := web.NewAuthorizer(cfg.Web)
+	limiter := web.NewLimiter(*requestTimeout, *maxInFlight, cfg.Web.Endpoints, "/api/v1/relay/wait")
 	router := mux.NewRouter()
-	router.Handle(*metricsPath, promhttp.HandlerFor(telemetryRegistry, promhttp.HandlerOpts{}))
-	router.HandleFunc(*devicePath, scrapeHandler)
-	if *enableDiscovery {
-		router.HandleFunc("/device_by_mac/{macaddr}", scrapeHandlerByMac)
-		router.HandleFunc("/discover", discoverHandler)
+	router.Use(limiter.Middleware)
+	scrapeRoutes(router, auth, telemetryRegistry)
+	servers := []*http.Server{newServer(*listenAddress, router)}
+	if *adminListenAddress != "" {
+		// -- the api and admin endpoints are only reachable through their own listener
+		adminRouter := mux.NewRouter()
+		adminRouter.Use(limiter.Middleware)
+		apiRoutes(adminRouter, auth, cfg)
+		servers = append(servers, newServer(*adminListenAddress, adminRouter))
+	}
+	if *scrapeOnly {
+		log.Info("serving only the metrics and device paths")
+	} else {
+		discoveryRoutes(router, auth, cfg)
+		webhookRoutes(router)
+		if *adminListenAddress == "" {
+			apiRoutes(router, auth, cfg)
+		}
+		router.Handle("/-/healthy", healthHandler(cfg)).Methods(http.MethodGet, http.MethodHead)
+		router.Handle("/ui", auth.Require(web.RoleReadMetrics, http.HandlerFunc(uiHandler))).Methods(http.MethodGet)
+		router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(landingPage)
+		})
 	}
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write(landingPage)
-	})
 
 	defer os.Exit(0)
 	defer func() {
 		log.Info("exiting.")
 	}()
+	if *checkpointInterval > 0 {
+		defer saveCounters(telemetryRegistry)
+	}
+	defer inventory.Save()
 	if *enableDiscovery {
 		defer discover.ConnClose()
 	}
 
-	go func() {
-		log.Infoln("Listening on address " + *listenAddress)
-		if err := http.ListenAndServe(*listenAddress, router); err != nil {
-			log.Fatal(err)
+	for _, server := range servers {
+		if err := web.ConfigureTLS(server, cfg.Web); err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
 		}
-	}()
+		go serve(server, cfg.Web)
+	}
 
 	<-c
+	// -- no relays are switched automatically while draining
+	stopControl()
+	drain(servers, *drainTimeout)
+}
+
+// newServer -- creates a server with the limits of the flags
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           web.Recover(web.AllowSources(allowedSources, web.LimitBody(*maxBodyBytes, handler))),
+		ReadHeaderTimeout: *readHeaderTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    *maxHeaderBytes,
+	}
+}
+
+// serve -- listens on the address of the server until it is shut down
+func serve(server *http.Server, cfg config.Web) {
+	log.Infoln("Listening on address " + server.Addr)
+	var err error
+	if cfg.TLSCertFile != "" {
+		err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// advertise -- announces the exporter via mdns, the address is taken from the listen address
+func advertise() error {
+	host, portValue, err := net.SplitHostPort(*listenAddress)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return fmt.Errorf("invalid port '%v'", portValue)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		ip = discover.OutboundIP()
+	}
+
+	instance := *mdnsInstance
+	if instance == "" {
+		if instance, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	return mdns.Advertise(instance, ip, port, *metricsPath)
 }
 
 // scrapeHandlerByMac --
@@ -146,40 +545,148 @@ func scrapeHandlerByMac(w http.ResponseWriter, r *http.Request) {
 	scrapeHandler(w, r)
 }
 
-// scrapeHandler --
-func scrapeHandler(w http.ResponseWriter, r *http.Request) {
+// targetParam -- returns the validated target parameter of the request, responds with 400 if it is
+// missing or invalid
+func targetParam(w http.ResponseWriter, r *http.Request) (string, bool) {
 	target := r.URL.Query().Get("target")
 	if target == "" {
-		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		web.Error(w, r, "", "'target' parameter must be specified", http.StatusBadRequest)
+		return "", false
+	}
+	if err := targetPolicy.Validate(target); err != nil {
+		web.Error(w, r, target, fmt.Sprintf("invalid 'target' parameter '%v': %v", target, err), http.StatusBadRequest)
+		return "", false
+	}
+	return target, true
+}
+
+// scrapeHandler --
+func scrapeHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := targetParam(w, r)
+	if !ok {
+		return
+	}
+
+	module := r.URL.Query().Get("module")
+	if err := mystrom.ValidateModule(module); err != nil {
+		web.Error(w, r, target, fmt.Sprintf("invalid 'module' parameter: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	log.Infof("got scrape request for target '%v'", target)
-	exporter := mystrom.NewExporter(target)
+	// -- the polls select the module automatically, an explicit one is always scraped
+	if gatherer := poller.Gatherer(target, *pollMaxAge, *pollTimestamps); gatherer != nil && module == "" {
+		serveDevice(w, r, target, gatherer)
+		return
+	}
+
+	gatherer, duration, err := scrapeTarget(r.Context(), target, module)
+	if _, canceled := err.(*mystrom.CanceledError); canceled {
+		// -- the client disconnected, nobody is left to answer
+		return
+	}
+	if exceeded, ok := err.(*budget.ExceededError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(exceeded.Until).Seconds())+1))
+		web.Error(w, r, target, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	observeDuration(r, target, duration)
+	if err != nil {
+		web.Error(
+			w, r, target,
+			fmt.Sprintf("failed to scrape target '%v': %v", target, err),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	serveDevice(w, r, target, gatherer)
+}
+
+// serveDevice -- serves the metrics of the device, labeled with the exporter instance and the static
+// labels if configured
+func serveDevice(w http.ResponseWriter, r *http.Request, target string, gatherer prometheus.Gatherer) {
+	gatherer = withNames(exposition.WithLabels(gatherer, deviceLabels), r)
+	exposition.Handler(gatherer, mystrom.CountersCreated(target)).ServeHTTP(w, r)
+}
+
+// constLabels -- returns the labels of the exporters own metrics and of the device metrics, the
+// static labels of the configuration are only added to the device metrics if enabled for them and
+// exporter_instance only if set
+func constLabels(cfg *config.Config) (map[string]string, map[string]string) {
+	exporter := make(map[string]string)
+	device := make(map[string]string)
+	if *exporterInstance != "" {
+		exporter["exporter_instance"] = *exporterInstance
+		device["exporter_instance"] = *exporterInstance
+	}
+	for name, value := range cfg.StaticLabels.Labels {
+		exporter[name] = value
+		if cfg.StaticLabels.Devices {
+			device[name] = value
+		}
+	}
+	return exporter, device
+}
+
+// scrapeTarget -- scrapes the device with the module, selected by the device type if empty, and counts
+// the request by its outcome; the requests to the device are canceled once the context is done
+func scrapeTarget(ctx context.Context, target, module string) (prometheus.Gatherer, float64, error) {
+	if err := budget.Check(target); err != nil {
+		mystromRequestsCounterVec.WithLabelValues(target, ErrorBudget.String()).Inc()
+		log.Warn(err)
+		return nil, 0, err
+	}
+	exporter := mystrom.NewExporter(target).WithModule(module).WithContext(ctx)
 
 	start := time.Now()
 	gatherer, err := exporter.Scrape()
+	budget.Account(target, time.Since(start))
 	duration := time.Since(start).Seconds()
 	if err != nil {
-		if strings.Contains(fmt.Sprintf("%v", err), "unable to connect with target") {
+		if _, ok := err.(*mystrom.CanceledError); ok {
+			mystromRequestsCounterVec.WithLabelValues(target, ErrorCanceled.String()).Inc()
+			log.Debug(err)
+			return nil, duration, err
+		} else if _, ok := err.(*mystrom.UnsupportedError); ok {
+			mystromRequestsCounterVec.WithLabelValues(target, ErrorUnsupported.String()).Inc()
+		} else if strings.Contains(fmt.Sprintf("%v", err), "unable to connect with target") {
 			mystromRequestsCounterVec.WithLabelValues(target, ErrorSocket.String()).Inc()
 		} else if strings.Contains(fmt.Sprintf("%v", err), "i/o timeout") {
 			mystromRequestsCounterVec.WithLabelValues(target, ErrorTimeout.String()).Inc()
 		} else {
 			mystromRequestsCounterVec.WithLabelValues(target, ErrorParsingValue.String()).Inc()
 		}
-		http.Error(
-			w,
-			fmt.Sprintf("failed to scrape target '%v': %v", target, err),
-			http.StatusInternalServerError,
-		)
+		mystromUpGaugeVec.WithLabelValues(target).Set(0)
 		log.Error(err)
-		return
+		return nil, duration, err
 	}
 	mystromDurationCounterVec.WithLabelValues(target).Add(duration)
 	mystromRequestsCounterVec.WithLabelValues(target, OK.String()).Inc()
+	mystromUpGaugeVec.WithLabelValues(target).Set(1)
+
+	return gatherer, duration, nil
+}
 
-	promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+// forgetTarget -- drops the metric series of the target, called once it's dropped from lru.Targets
+func forgetTarget(target string) {
+	for status := OK; status <= ErrorCanceled; status++ {
+		mystromRequestsCounterVec.DeleteLabelValues(target, status.String())
+	}
+	mystromDurationCounterVec.DeleteLabelValues(target)
+	mystromDurationHistogram.DeleteLabelValues(target)
+	mystromUpGaugeVec.DeleteLabelValues(target)
+}
+
+// observeDuration -- records the duration of a scrape, with the trace id of the request as exemplar
+// if enabled, so a slow scrape can be followed to its trace
+func observeDuration(r *http.Request, target string, duration float64) {
+	observer := mystromDurationHistogram.WithLabelValues(target)
+	if traceID := web.TraceID(r); *traceExemplars && traceID != "" {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration, prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	observer.Observe(duration)
 }
 
 // -- setupMetrics creates a new registry for the exporter telemetry
@@ -206,6 +713,53 @@ func setupMetrics() *prometheus.Registry {
 		[]string{"target", "status"})
 	registry.MustRegister(mystromRequestsCounterVec)
 
+	mystromDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "scrape_duration_seconds",
+			Help:      "Duration of mystrom requests by target in seconds, including failed ones",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"target"})
+	registry.MustRegister(mystromDurationHistogram)
+
+	mystromAdminCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "admin_requests_total",
+			Help:      "Number of admin requests proxied to the devices by target, operation and result",
+		},
+		[]string{"target", "operation", "result"})
+	registry.MustRegister(mystromAdminCounterVec)
+
+	// -- set by the scrapes and the warm-up, a canceled scrape or an exceeded budget keeps the last value
+	mystromUpGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mystrom_up",
+			Help: "Whether the last scrape of the target was successful",
+		},
+		[]string{"target"})
+	registry.MustRegister(mystromUpGaugeVec)
+
+	// -- aggregated readings of the device groups from the configuration file
+	registry.MustRegister(mystrom.NewGroupCollector(namespace))
+	registry.MustRegister(mystrom.Collectors()...)
+
+	// -- relay control and the schedules using it
+	registry.MustRegister(control.Collectors()...)
+	registry.MustRegister(schedule.Collectors()...)
+	registry.MustRegister(poller.Collectors()...)
+	registry.MustRegister(leader.Collectors()...)
+	registry.MustRegister(inventory.Collectors()...)
+	registry.MustRegister(web.Collectors()...)
+	registry.MustRegister(discover.Collectors()...)
+	registry.MustRegister(provider.Collectors()...)
+	registry.MustRegister(budget.Collectors()...)
+	registry.MustRegister(webhook.Collectors()...)
+	registry.MustRegister(sink.Collectors()...)
+	registry.MustRegister(legacyScrapesCounterVec)
+	registry.MustRegister(deprecatedFlagsGauge)
+
 	// -- make the build information is available through a metric
 	buildInfo := prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -224,12 +778,12 @@ func setupMetrics() *prometheus.Registry {
 // discoerHandler
 func discoverHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("got discover request from '%v' for %v", r.Host, r.URL.String())
-	if data, e := discover.Discover(); e == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+	data, e := provider.Discover(discoveryAddress(), *devicePath, *exporterInstance)
+	if e != nil {
+		web.Error(w, r, "", fmt.Sprintf("failed to list the discovered targets: %v", e), http.StatusInternalServerError)
 		return
 	}
-	w.WriteHeader(http.StatusInternalServerError)
-
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"mystrom-exporter/pkg/exposition"
+	"mystrom-exporter/pkg/lru"
+	"mystrom-exporter/pkg/web"
+)
+
+// -- the default period of the legacy names report, older uses are forgotten; the uses remembered at most,
+// the least recently scraped are dropped first, and the length of the user agents kept
+const (
+	legacyReportWindow = 24 * time.Hour
+	maxLegacyUses      = 1000
+	maxUserAgentLength = 256
+)
+
+// legacyUse -- the scrapes of a scraper which got a metric by its legacy name
+type legacyUse struct {
+	Metric      string    `json:"metric"`
+	Replacement string    `json:"replacement"`
+	Scraper     string    `json:"scraper"`
+	UserAgent   string    `json:"user_agent"`
+	LastScraped time.Time `json:"last_scraped"`
+	Scrapes     int       `json:"scrapes"`
+}
+
+var (
+	// -- by metric, scraper address and user agent, ordered by their last scrape in legacyOrder
+	legacyUses      = make(map[string]*legacyUse)
+	legacyOrder     = lru.New(maxLegacyUses)
+	legacyPruned    time.Time
+	legacyUsesMutex sync.Mutex
+
+	legacyScrapesCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "legacy_metric_scrapes_total",
+			Help:      "Number of device scrapes which got a metric by its legacy name, by metric",
+		},
+		[]string{"metric"})
+)
+
+// namingScheme -- the naming scheme of the device metrics requested by the scrape with the parameter
+// names, the one of the flags otherwise
+func namingScheme(r *http.Request) string {
+	if scheme := r.URL.Query().Get("names"); exposition.ValidateNames(scheme) == nil {
+		return scheme
+	}
+	return *metricNames
+}
+
+// withNames -- exposes the device metrics with the names of the scheme of the scrape and records the
+// legacy names served
+func withNames(gatherer prometheus.Gatherer, r *http.Request) prometheus.Gatherer {
+	scraper, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		scraper = r.RemoteAddr
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return exposition.WithNames(gatherer, namingScheme(r), func(legacy []string) {
+		now := time.Now()
+
+		legacyUsesMutex.Lock()
+		defer legacyUsesMutex.Unlock()
+
+		// -- at most once a minute, scrapes are frequent
+		if now.Sub(legacyPruned) >= time.Minute {
+			forgetLegacyUses(now)
+			legacyPruned = now
+		}
+		for _, metric := range legacy {
+			legacyScrapesCounterVec.WithLabelValues(metric).Inc()
+			key := metric + "\x00" + scraper + "\x00" + userAgent
+			use, ok := legacyUses[key]
+			if !ok {
+				use = &legacyUse{Metric: metric, Replacement: exposition.Renames[metric], Scraper: scraper, UserAgent: userAgent}
+				legacyUses[key] = use
+			}
+			use.LastScraped = now
+			use.Scrapes++
+			for _, evicted := range legacyOrder.Touch(key) {
+				delete(legacyUses, evicted)
+			}
+		}
+	})
+}
+
+// forgetLegacyUses -- drops the uses older than the default window, must be called with the uses locked
+func forgetLegacyUses(now time.Time) {
+	for key, use := range legacyUses {
+		if use.LastScraped.Before(now.Add(-legacyReportWindow)) {
+			delete(legacyUses, key)
+			legacyOrder.Remove(key)
+		}
+	}
+}
+
+// legacyNamesHandler -- lists the scrapers which got metrics by their legacy names within the window
+// given by the parameter window, the last 24 hours by default
+func legacyNamesHandler(w http.ResponseWriter, r *http.Request) {
+	window := legacyReportWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			web.Error(w, r, "", "invalid 'window' parameter", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+	since := time.Now().Add(-window)
+
+	legacyUsesMutex.Lock()
+	forgetLegacyUses(time.Now())
+	uses := []legacyUse{}
+	for _, use := range legacyUses {
+		if !use.LastScraped.Before(since) {
+			uses = append(uses, *use)
+		}
+	}
+	legacyUsesMutex.Unlock()
+
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].Metric != uses[j].Metric {
+			return uses[i].Metric < uses[j].Metric
+		}
+		return uses[i].Scraper < uses[j].Scraper
+	})
+	writeJSON(w, http.StatusOK, uses)
+}
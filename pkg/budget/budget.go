@@ -0,0 +1,174 @@
+package budget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "mystrom_exporter"
+
+// ExceededError -- the time the exporter may spend on the device in the current hour is used up
+type ExceededError struct {
+	Target string
+	Budget time.Duration
+	Until  time.Time
+}
+
+// Error --
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("scrape budget of %v per hour of target '%v' is used up until %v",
+		e.Budget, e.Target, e.Until.Format(time.RFC3339))
+}
+
+// usage -- the time spent on a target in the hour starting with window
+type usage struct {
+	window  time.Time
+	seconds float64
+}
+
+var (
+	defaultBudget time.Duration
+	budgets       = make(map[string]time.Duration)
+	usages        = make(map[string]*usage)
+	// -- the hour the usages of the previous hours were forgotten in
+	pruned time.Time
+	mutex  sync.Mutex
+
+	usedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "scrape_budget_used_seconds"),
+		"Time spent requesting the device in the current hour by scrapes and polls",
+		[]string{"target"}, nil)
+	budgetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "scrape_budget_seconds"),
+		"Time the exporter may spend requesting the device per hour, only for devices with a budget",
+		[]string{"target"}, nil)
+
+	spentCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "device_request_seconds_total",
+			Help:      "Accumulated time spent requesting the device by scrapes and polls, including the failed ones",
+		},
+		[]string{"target"})
+	exceededCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scrape_budget_exceeded_total",
+			Help:      "Number of scrapes and polls skipped as the budget of the device was used up",
+		},
+		[]string{"target"})
+)
+
+// Initialize -- sets the budget per hour of all targets and the budgets of single targets, 0 only
+// accounts the time without enforcing a budget
+func Initialize(perHour time.Duration, overrides map[string]time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defaultBudget = perHour
+	budgets = overrides
+}
+
+// Collectors -- returns the metrics of the budgets to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{collector{}, spentCounterVec, exceededCounterVec}
+}
+
+// Check -- returns an ExceededError if the budget of the target is used up for the current hour
+func Check(target string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	limit := budgetOf(target)
+	u := current(target, now)
+	if limit <= 0 || u == nil || u.seconds < limit.Seconds() {
+		return nil
+	}
+
+	exceededCounterVec.WithLabelValues(target).Inc()
+	return &ExceededError{Target: target, Budget: limit, Until: u.window.Add(time.Hour)}
+}
+
+// Account -- adds the time spent requesting the target
+func Account(target string, duration time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	spentCounterVec.WithLabelValues(target).Add(duration.Seconds())
+
+	u := current(target, now)
+	if u == nil {
+		u = &usage{window: now.Truncate(time.Hour)}
+		usages[target] = u
+	}
+	u.seconds += duration.Seconds()
+
+	// -- forget the targets not requested in the current hour, once per hour
+	if pruned.Before(u.window) {
+		for t, other := range usages {
+			if other.window.Before(u.window) {
+				delete(usages, t)
+			}
+		}
+		pruned = u.window
+	}
+}
+
+// Forget -- drops the usage and the metric series of the target, called once it's dropped from lru.Targets
+func Forget(target string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(usages, target)
+	spentCounterVec.DeleteLabelValues(target)
+	exceededCounterVec.DeleteLabelValues(target)
+}
+
+// budgetOf --
+func budgetOf(target string) time.Duration {
+	if limit, ok := budgets[target]; ok {
+		return limit
+	}
+	return defaultBudget
+}
+
+// current -- the usage of the target in the current hour, nil if there is none
+func current(target string, now time.Time) *usage {
+	u, ok := usages[target]
+	if !ok || !u.window.Equal(now.Truncate(time.Hour)) {
+		return nil
+	}
+	return u
+}
+
+// collector -- exports the usage of the current hour, so it resets with the hour
+type collector struct{}
+
+// Describe --
+func (collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usedDesc
+	ch <- budgetDesc
+}
+
+// Collect --
+func (collector) Collect(ch chan<- prometheus.Metric) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	for target := range usages {
+		if u := current(target, now); u != nil {
+			ch <- prometheus.MustNewConstMetric(usedDesc, prometheus.GaugeValue, u.seconds, target)
+		}
+	}
+	for target, limit := range budgets {
+		if limit > 0 {
+			ch <- prometheus.MustNewConstMetric(budgetDesc, prometheus.GaugeValue, limit.Seconds(), target)
+		}
+	}
+}
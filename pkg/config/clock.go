@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string][]time.Weekday{
+	"mon":     {time.Monday},
+	"tue":     {time.Tuesday},
+	"wed":     {time.Wednesday},
+	"thu":     {time.Thursday},
+	"fri":     {time.Friday},
+	"sat":     {time.Saturday},
+	"sun":     {time.Sunday},
+	"weekday": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend": {time.Saturday, time.Sunday},
+}
+
+// parseDays -- converts day names into a set of weekdays, no days means every day
+func parseDays(names []string) (map[time.Weekday]bool, error) {
+	if len(names) == 0 {
+		names = []string{"weekday", "weekend"}
+	}
+
+	days := make(map[time.Weekday]bool)
+	for _, name := range names {
+		list, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown day '%v'", name)
+		}
+		for _, day := range list {
+			days[day] = true
+		}
+	}
+	return days, nil
+}
+
+// parseClock -- converts a HH:MM value into the minutes of the day
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%v', expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
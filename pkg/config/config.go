@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config -- represents the content of the exporter configuration file
+type Config struct {
+	Tariff    *Tariff    `yaml:"tariff,omitempty"`
+	Devices   []Device   `yaml:"devices,omitempty"`
+	Schedules []Schedule `yaml:"schedules,omitempty"`
+	Firmware  *Firmware  `yaml:"firmware,omitempty"`
+	Web       Web        `yaml:"web,omitempty"`
+	Zones     []Zone     `yaml:"zones,omitempty"`
+	Providers Providers  `yaml:"providers,omitempty"`
+
+	RelayWebhook *RelayWebhook `yaml:"relay_webhook,omitempty"`
+	Sinks        Sinks         `yaml:"sinks,omitempty"`
+
+	StaticLabels StaticLabels `yaml:"static_labels,omitempty"`
+
+	Plausibility map[string]Bounds `yaml:"plausibility,omitempty"`
+}
+
+// New -- returns the configuration used without a configuration file
+func New() *Config {
+	cfg := &Config{}
+	cfg.validate()
+	return cfg
+}
+
+// Load -- reads and validates the configuration file with the given name
+func Load(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file %v: %v", filename, err.Error())
+	}
+
+	cfg := New()
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %v", explainParseError(filename, content, err).Error())
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %v", filename, err.Error())
+	}
+
+	return cfg, nil
+}
+
+// validate -- checks the values of the configuration and prepares derived fields
+func (c *Config) validate() error {
+	if c.Tariff != nil {
+		if err := c.Tariff.validate(); err != nil {
+			return fmt.Errorf("tariff: %v", err.Error())
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range c.Devices {
+		if err := c.Devices[i].validate(); err != nil {
+			return fmt.Errorf("devices[%d]: %v", i, err.Error())
+		}
+		if seen[c.Devices[i].Target] {
+			return fmt.Errorf("devices[%d]: duplicate target %v", i, c.Devices[i].Target)
+		}
+		seen[c.Devices[i].Target] = true
+	}
+
+	if c.Firmware != nil {
+		if err := c.Firmware.validate(); err != nil {
+			return fmt.Errorf("firmware: %v", err.Error())
+		}
+	}
+
+	if err := c.Web.validate(); err != nil {
+		return fmt.Errorf("web: %v", err.Error())
+	}
+
+	for i := range c.Schedules {
+		if err := c.Schedules[i].validate(); err != nil {
+			return fmt.Errorf("schedules[%d]: %v", i, err.Error())
+		}
+	}
+
+	for i := range c.Zones {
+		if err := c.Zones[i].validate(); err != nil {
+			return fmt.Errorf("zones[%d]: %v", i, err.Error())
+		}
+	}
+
+	if err := c.Providers.validate(); err != nil {
+		return fmt.Errorf("providers: %v", err.Error())
+	}
+
+	if c.RelayWebhook != nil {
+		if err := c.RelayWebhook.validate(); err != nil {
+			return fmt.Errorf("relay_webhook: %v", err.Error())
+		}
+	}
+
+	if err := c.Sinks.validate(); err != nil {
+		return fmt.Errorf("sinks: %v", err.Error())
+	}
+
+	if err := c.StaticLabels.validate(); err != nil {
+		return fmt.Errorf("static_labels: %v", err.Error())
+	}
+
+	if err := validatePlausibility(c.Plausibility); err != nil {
+		return fmt.Errorf("plausibility: %v", err.Error())
+	}
+
+	return nil
+}
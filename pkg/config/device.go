@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Device -- settings of a single device, matched by the target used to scrape it
+type Device struct {
+	Target           string            `yaml:"target"`
+	Addresses        []string          `yaml:"addresses"`
+	Mac              string            `yaml:"mac"`
+	StandbyThreshold float64           `yaml:"standby_threshold"`
+	Groups           map[string]string `yaml:"groups"`
+	NeverOff         bool              `yaml:"never_off"`
+	MaxOnDuration    time.Duration     `yaml:"max_on_duration"`
+	Scheme           string            `yaml:"scheme"`
+	Port             int               `yaml:"port"`
+	BasicAuth        *BasicAuth        `yaml:"basic_auth"`
+	ScrapeBudget     *time.Duration    `yaml:"scrape_budget"`
+	Priority         string            `yaml:"priority"`
+	// -- the token of devices protecting their api, sent in the Token header
+	Token     string `yaml:"token" redact:"true"`
+	TokenFile string `yaml:"token_file"`
+	TLS       *TLS   `yaml:"tls"`
+}
+
+// TLS -- the verification of the certificate of a device reached by https, e.g. behind a reverse proxy
+type TLS struct {
+	CAFile             string `yaml:"ca_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ServerName         string `yaml:"server_name"`
+}
+
+// BasicAuth -- credentials sent with the requests to a device, e.g. for an authenticating reverse proxy
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password" redact:"true"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// Credentials -- returns the username and password, a password file is read on every call to pick up
+// rotated secrets
+func (b *BasicAuth) Credentials() (string, string, error) {
+	if b.PasswordFile == "" {
+		return b.Username, b.Password, nil
+	}
+	content, err := ioutil.ReadFile(b.PasswordFile)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read password file: %v", err.Error())
+	}
+	return b.Username, strings.TrimSpace(string(content)), nil
+}
+
+// APIToken -- returns the token of the device, empty without one; a token file is read on every call to
+// pick up rotated tokens
+func (d *Device) APIToken() (string, error) {
+	if d.TokenFile == "" {
+		return d.Token, nil
+	}
+	return ReadToken(d.TokenFile)
+}
+
+// ReadToken -- returns the token in the file, without surrounding whitespace
+func ReadToken(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file: %v", err.Error())
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// validate --
+func (b *BasicAuth) validate() error {
+	if b.Username == "" {
+		return fmt.Errorf("basic_auth needs a username")
+	}
+	if b.Password != "" && b.PasswordFile != "" {
+		return fmt.Errorf("basic_auth password and password_file exclude each other")
+	}
+	return nil
+}
+
+// Address -- returns the scheme and the host with port to reach the device, the port of the
+// target takes precedence over the configured one
+func (d *Device) Address() (string, string) {
+	scheme := d.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme, d.hostPort(d.Target)
+}
+
+// Hosts -- returns the scheme and the hosts with port to reach the device, the target first followed
+// by the further addresses in the order they are tried
+func (d *Device) Hosts() (string, []string) {
+	scheme, host := d.Address()
+	hosts := []string{host}
+	for _, address := range d.Addresses {
+		hosts = append(hosts, d.hostPort(address))
+	}
+	return scheme, hosts
+}
+
+// hostPort -- adds the configured port to the address unless it has one
+func (d *Device) hostPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil && d.Port != 0 {
+		return net.JoinHostPort(strings.Trim(address, "[]"), strconv.Itoa(d.Port))
+	}
+	return address
+}
+
+// Device -- returns the settings of the given target, nil if it isn't configured
+func (c *Config) Device(target string) *Device {
+	for i := range c.Devices {
+		if c.Devices[i].Target == target {
+			return &c.Devices[i]
+		}
+	}
+	return nil
+}
+
+// validate --
+func (d *Device) validate() error {
+	if d.Target == "" {
+		return fmt.Errorf("target must be specified")
+	}
+	for _, address := range d.Addresses {
+		if address == "" || strings.ContainsAny(address, "/@?#") {
+			return fmt.Errorf("invalid address '%v', must be a host with an optional port", address)
+		}
+		if address == d.Target {
+			return fmt.Errorf("address '%v' is the target itself", address)
+		}
+	}
+	if d.StandbyThreshold < 0 {
+		return fmt.Errorf("standby_threshold must not be negative")
+	}
+	if d.ScrapeBudget != nil && *d.ScrapeBudget < 0 {
+		return fmt.Errorf("scrape_budget must not be negative")
+	}
+	if d.MaxOnDuration < 0 {
+		return fmt.Errorf("max_on_duration must not be negative")
+	}
+	if d.Mac != "" {
+		if _, err := net.ParseMAC(d.Mac); err != nil {
+			return fmt.Errorf("invalid mac '%v'", d.Mac)
+		}
+	}
+	if d.Priority != "" && d.Priority != "high" && d.Priority != "normal" && d.Priority != "low" {
+		return fmt.Errorf("priority must be high, normal or low")
+	}
+	if d.Scheme != "" && d.Scheme != "http" && d.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if d.TLS != nil && d.Scheme != "https" {
+		return fmt.Errorf("tls requires the scheme https")
+	}
+	if d.Port < 0 || d.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", d.Port)
+	}
+	if d.BasicAuth != nil {
+		if err := d.BasicAuth.validate(); err != nil {
+			return err
+		}
+	}
+	if d.Token != "" && d.TokenFile != "" {
+		return fmt.Errorf("token and token_file exclude each other")
+	}
+	if d.NeverOff && d.MaxOnDuration > 0 {
+		return fmt.Errorf("never_off and max_on_duration exclude each other")
+	}
+	for label, group := range d.Groups {
+		if !model.LabelName(label).IsValid() {
+			return fmt.Errorf("invalid group label '%v'", label)
+		}
+		if group == "" {
+			return fmt.Errorf("group label '%v' has no value", label)
+		}
+	}
+	return nil
+}
+
+// Targets -- returns the targets of all configured devices
+func (c *Config) Targets() []string {
+	targets := make([]string, 0, len(c.Devices))
+	for _, d := range c.Devices {
+		targets = append(targets, d.Target)
+	}
+	return targets
+}
+
+// ScrapeBudgets -- returns the scrape budgets per hour of the devices which configure one
+func (c *Config) ScrapeBudgets() map[string]time.Duration {
+	budgets := make(map[string]time.Duration)
+	for _, d := range c.Devices {
+		if d.ScrapeBudget != nil {
+			budgets[d.Target] = *d.ScrapeBudget
+		}
+	}
+	return budgets
+}
+
+// Priorities -- the priority classes of the polls by target, for the devices having one
+func (c *Config) Priorities() map[string]string {
+	priorities := make(map[string]string)
+	for _, d := range c.Devices {
+		if d.Priority != "" {
+			priorities[d.Target] = d.Priority
+		}
+	}
+	return priorities
+}
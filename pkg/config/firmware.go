@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Firmware -- where to find the latest firmware versions by device type
+type Firmware struct {
+	Latest          map[string]string `yaml:"latest"`
+	URL             string            `yaml:"url" redact:"url"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval"`
+}
+
+// validate --
+func (f *Firmware) validate() error {
+	if len(f.Latest) == 0 && f.URL == "" {
+		return fmt.Errorf("either latest or url must be specified")
+	}
+	if f.RefreshInterval == 0 {
+		f.RefreshInterval = 6 * time.Hour
+	}
+	if f.RefreshInterval < time.Minute {
+		return fmt.Errorf("refresh_interval must be at least 1m")
+	}
+	return nil
+}
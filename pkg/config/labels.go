@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// -- labels set by the exporter or Prometheus itself, which can't be used as static labels
+var reservedLabels = map[string]bool{
+	"instance":          true,
+	"job":               true,
+	"target":            true,
+	"exporter_instance": true,
+	"le":                true,
+	"quantile":          true,
+}
+
+// StaticLabels -- constant labels, e.g. the site or environment, added to the metrics of the exporter
+// and optionally to the metrics of the devices
+type StaticLabels struct {
+	Labels  map[string]string `yaml:"labels"`
+	Devices bool              `yaml:"devices"`
+}
+
+// validate --
+func (s *StaticLabels) validate() error {
+	for name, value := range s.Labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name '%v'", name)
+		}
+		if reservedLabels[name] {
+			return fmt.Errorf("label '%v' is set by the exporter or Prometheus", name)
+		}
+		if value == "" {
+			return fmt.Errorf("label '%v' has no value", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RelayWebhook -- the url called with the relay changes seen by the relay poller, e.g. for automations
+// reacting to the button of a switch
+type RelayWebhook struct {
+	URL     string            `yaml:"url" redact:"url"`
+	Headers map[string]string `yaml:"headers,omitempty" redact:"true"`
+	Timeout time.Duration     `yaml:"timeout,omitempty"`
+	// -- also call it for the changes made through the exporter
+	All bool `yaml:"all,omitempty"`
+}
+
+// validate --
+func (r *RelayWebhook) validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url '%v'", r.URL)
+	}
+	if r.Timeout == 0 {
+		r.Timeout = 5 * time.Second
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// OIDC -- accepts the bearer tokens of an OpenID Connect provider, the roles are taken from a claim of
+// the token
+type OIDC struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// -- the keys are read from the discovery document of the issuer if not set
+	JWKSURL string `yaml:"jwks_url,omitempty"`
+	// -- the claim holding the roles or groups of the principal, roles by default
+	RolesClaim string `yaml:"roles_claim,omitempty"`
+	// -- the roles granted by values of the claim, values being roles themselves are granted directly
+	ClaimRoles map[string][]string `yaml:"claim_roles,omitempty"`
+}
+
+// validate --
+func (o *OIDC) validate() error {
+	if o.Issuer == "" {
+		return fmt.Errorf("issuer must be specified")
+	}
+	if u, err := url.Parse(o.Issuer); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return fmt.Errorf("invalid issuer '%v', must be an http(s) url", o.Issuer)
+	}
+	if o.Audience == "" {
+		return fmt.Errorf("audience must be specified")
+	}
+	if o.JWKSURL != "" {
+		if u, err := url.Parse(o.JWKSURL); err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return fmt.Errorf("invalid jwks_url '%v', must be an http(s) url", o.JWKSURL)
+		}
+	}
+	if o.RolesClaim == "" {
+		o.RolesClaim = "roles"
+	}
+	for value, roles := range o.ClaimRoles {
+		if err := validateRoles(roles); err != nil {
+			return fmt.Errorf("claim_roles: %v: %v", value, err.Error())
+		}
+	}
+	return nil
+}
+
+// Roles -- returns the roles granted by the values of the roles claim
+func (o *OIDC) Roles(values []string) []string {
+	var roles []string
+	for _, value := range values {
+		if contains(KnownRoles, value) {
+			roles = append(roles, value)
+		}
+		roles = append(roles, o.ClaimRoles[value]...)
+	}
+	return roles
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/prometheus/common/model"
+)
+
+// Bounds -- the plausible range of the values of a device metric, samples outside of it are dropped
+type Bounds struct {
+	Min *float64 `yaml:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty"`
+}
+
+// DefaultBounds -- the bounds of the metrics not in the plausibility section, e.g. the -3276.8°C
+// reported by some devices during brownouts
+var DefaultBounds = map[string]Bounds{
+	"mystrom_temperature":             {Min: float(-40), Max: float(100)},
+	"mystrom_temperature_fahrenheit":  {Min: float(-40), Max: float(212)},
+	"mystrom_temperature_raw":         {Min: float(-40), Max: float(125)},
+	"mystrom_temperature_compensated": {Min: float(-40), Max: float(100)},
+	"mystrom_power":                   {Min: float(0), Max: float(4000)},
+	"mystrom_wifi_rssi_dbm":           {Min: float(-120), Max: float(0)},
+}
+
+// Contains -- whether the value is within the bounds, NaN and infinite values never are
+func (b Bounds) Contains(value float64) bool {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return false
+	}
+	return (b.Min == nil || value >= *b.Min) && (b.Max == nil || value <= *b.Max)
+}
+
+// Bounds -- returns the bounds of the device metric, the configured ones replace the default ones
+func (c *Config) Bounds(metric string) (Bounds, bool) {
+	if bounds, ok := c.Plausibility[metric]; ok {
+		return bounds, true
+	}
+	bounds, ok := DefaultBounds[metric]
+	return bounds, ok
+}
+
+// validatePlausibility --
+func validatePlausibility(plausibility map[string]Bounds) error {
+	for metric, bounds := range plausibility {
+		if !model.IsValidMetricName(model.LabelValue(metric)) {
+			return fmt.Errorf("invalid metric name '%v'", metric)
+		}
+		if bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max {
+			return fmt.Errorf("%v: min must not be greater than max", metric)
+		}
+	}
+	return nil
+}
+
+// float --
+func float(value float64) *float64 {
+	return &value
+}
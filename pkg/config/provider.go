@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Providers -- the additional sources of targets besides the devices of the configuration and the discovery
+type Providers struct {
+	Kubernetes *KubernetesProvider `yaml:"kubernetes,omitempty"`
+	HTTP       *HTTPProvider       `yaml:"http,omitempty"`
+	File       *FileProvider       `yaml:"file,omitempty"`
+	Netbox     *IPAMProvider       `yaml:"netbox,omitempty"`
+	PhpIPAM    *IPAMProvider       `yaml:"phpipam,omitempty"`
+	DHCPLeases *LeasesProvider     `yaml:"dhcp_leases,omitempty"`
+}
+
+// KubernetesProvider -- offers the addresses of the endpoints matching the label selector, e.g. of
+// services without selector whose endpoints are maintained as the devices
+type KubernetesProvider struct {
+	Namespace       string        `yaml:"namespace,omitempty"`
+	LabelSelector   string        `yaml:"label_selector,omitempty"`
+	Port            string        `yaml:"port,omitempty"`
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+}
+
+// HTTPProvider -- fetches the targets in the format of Prometheus' http_sd from an url, e.g. exported by a
+// home automation system keeping the inventory of the devices
+type HTTPProvider struct {
+	URL             string        `yaml:"url" redact:"url"`
+	BasicAuth       *BasicAuth    `yaml:"basic_auth,omitempty"`
+	BearerToken     string        `yaml:"bearer_token,omitempty" redact:"true"`
+	BearerTokenFile string        `yaml:"bearer_token_file,omitempty"`
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+}
+
+// FileProvider -- reads the targets from a file in the format of Prometheus' file_sd, which is watched for changes
+type FileProvider struct {
+	Path string `yaml:"path"`
+}
+
+// LeasesProvider -- offers the hosts of a dhcp lease file with a mac address of myStrom, after a probe
+// confirmed they are myStrom devices
+type LeasesProvider struct {
+	Path   string `yaml:"path"`
+	Format string `yaml:"format,omitempty"`
+}
+
+// IPAMProvider -- pulls the ip addresses with the tag from an inventory like Netbox or phpIPAM, which
+// stays the single source of truth for the targets
+type IPAMProvider struct {
+	URL             string        `yaml:"url" redact:"url"`
+	Token           string        `yaml:"token,omitempty" redact:"true"`
+	TokenFile       string        `yaml:"token_file,omitempty"`
+	Tag             string        `yaml:"tag,omitempty"`
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+	// -- the id of the api application, only used by phpIPAM
+	AppID string `yaml:"app_id,omitempty"`
+}
+
+// validate --
+func (p *Providers) validate() error {
+	if p.Kubernetes != nil {
+		if err := p.Kubernetes.validate(); err != nil {
+			return fmt.Errorf("kubernetes: %v", err.Error())
+		}
+	}
+	if p.HTTP != nil {
+		if err := p.HTTP.validate(); err != nil {
+			return fmt.Errorf("http: %v", err.Error())
+		}
+	}
+	if p.File != nil && p.File.Path == "" {
+		return fmt.Errorf("file: path is missing")
+	}
+	if p.Netbox != nil {
+		if err := p.Netbox.validate(); err != nil {
+			return fmt.Errorf("netbox: %v", err.Error())
+		}
+	}
+	if p.PhpIPAM != nil {
+		if err := p.PhpIPAM.validate(); err != nil {
+			return fmt.Errorf("phpipam: %v", err.Error())
+		}
+		if p.PhpIPAM.AppID == "" {
+			return fmt.Errorf("phpipam: app_id is missing")
+		}
+	}
+	if p.DHCPLeases != nil {
+		if p.DHCPLeases.Path == "" {
+			return fmt.Errorf("dhcp_leases: path is missing")
+		}
+		switch p.DHCPLeases.Format {
+		case "", "dnsmasq", "kea":
+		default:
+			return fmt.Errorf("dhcp_leases: unknown format '%v', must be dnsmasq or kea", p.DHCPLeases.Format)
+		}
+	}
+	return nil
+}
+
+// Enabled -- whether any provider is configured
+func (p *Providers) Enabled() bool {
+	return p.Kubernetes != nil || p.HTTP != nil || p.File != nil || p.Netbox != nil || p.PhpIPAM != nil ||
+		p.DHCPLeases != nil
+}
+
+// validate --
+func (k *KubernetesProvider) validate() error {
+	if k.RefreshInterval == 0 {
+		k.RefreshInterval = time.Minute
+	}
+	if k.RefreshInterval < time.Second {
+		return fmt.Errorf("refresh_interval must be at least 1s")
+	}
+	return nil
+}
+
+// validate --
+func (h *HTTPProvider) validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url '%v'", h.URL)
+	}
+	if h.BasicAuth != nil {
+		if err := h.BasicAuth.validate(); err != nil {
+			return err
+		}
+		if h.BearerToken != "" || h.BearerTokenFile != "" {
+			return fmt.Errorf("basic_auth and bearer_token exclude each other")
+		}
+	}
+	if h.BearerToken != "" && h.BearerTokenFile != "" {
+		return fmt.Errorf("bearer_token and bearer_token_file exclude each other")
+	}
+	if h.RefreshInterval == 0 {
+		h.RefreshInterval = 5 * time.Minute
+	}
+	if h.RefreshInterval < time.Second {
+		return fmt.Errorf("refresh_interval must be at least 1s")
+	}
+	return nil
+}
+
+// Token -- returns the bearer token, a token file is read on every call to pick up rotated secrets
+func (h *HTTPProvider) Token() (string, error) {
+	return secret(h.BearerToken, h.BearerTokenFile)
+}
+
+// validate --
+func (i *IPAMProvider) validate() error {
+	u, err := url.Parse(i.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url '%v'", i.URL)
+	}
+	if i.Token == "" && i.TokenFile == "" {
+		return fmt.Errorf("token or token_file is required")
+	}
+	if i.Token != "" && i.TokenFile != "" {
+		return fmt.Errorf("token and token_file exclude each other")
+	}
+	if i.Tag == "" {
+		i.Tag = "mystrom"
+	}
+	if i.RefreshInterval == 0 {
+		i.RefreshInterval = 10 * time.Minute
+	}
+	if i.RefreshInterval < time.Second {
+		return fmt.Errorf("refresh_interval must be at least 1s")
+	}
+	return nil
+}
+
+// APIToken -- returns the api token, a token file is read on every call to pick up rotated secrets
+func (i *IPAMProvider) APIToken() (string, error) {
+	return secret(i.Token, i.TokenFile)
+}
+
+// secret -- returns the value or the content of the file if one is given
+func secret(value, filename string) (string, error) {
+	if filename == "" {
+		return value, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %v: %v", filename, err.Error())
+	}
+	return strings.TrimSpace(string(content)), nil
+}
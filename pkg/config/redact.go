@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redacted -- replaces secrets in the summary of the configuration
+const redacted = "REDACTED"
+
+// Redacted -- returns the configuration as generic document with the keys of the configuration file,
+// fields tagged with redact:"true" are replaced and the credentials of fields tagged with redact:"url"
+func (c *Config) Redacted() map[string]interface{} {
+	document, _ := summarize(reflect.ValueOf(c), "").(map[string]interface{})
+	return document
+}
+
+// summarize --
+func summarize(v reflect.Value, redact string) interface{} {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		return summarize(v.Elem(), redact)
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		document := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag := strings.Split(field.Tag.Get("yaml"), ",")
+			if len(tag) > 1 && tag[1] == "inline" {
+				if inline, ok := summarize(v.Field(i), "").(map[string]interface{}); ok {
+					for key, value := range inline {
+						document[key] = value
+					}
+				}
+				continue
+			}
+			name := tag[0]
+			if field.PkgPath != "" || name == "" || name == "-" {
+				continue
+			}
+			if value := v.Field(i); !value.IsZero() {
+				document[name] = summarize(value, field.Tag.Get("redact"))
+			}
+		}
+		return document
+	case reflect.Map:
+		document := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			document[fmt.Sprintf("%v", key.Interface())] = summarize(v.MapIndex(key), redact)
+		}
+		return document
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, summarize(v.Index(i), redact))
+		}
+		return items
+	case reflect.String:
+		switch redact {
+		case "true":
+			return redacted
+		case "url":
+			return redactURL(v.String())
+		}
+	}
+	return v.Interface()
+}
+
+// redactURL -- replaces the password of the url
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule -- switches the relays of the selected devices on and/or off at fixed times
+type Schedule struct {
+	Name    string            `yaml:"name"`
+	Targets []string          `yaml:"targets"`
+	Groups  map[string]string `yaml:"groups"`
+	Days    []string          `yaml:"days"`
+	On      string            `yaml:"on"`
+	Off     string            `yaml:"off"`
+
+	days map[time.Weekday]bool
+	on   int
+	off  int
+}
+
+// Due -- returns whether the relays should be turned on and/or off in the minute of the given time
+func (s *Schedule) Due(now time.Time) (on bool, off bool) {
+	if !s.days[now.Weekday()] {
+		return false, false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	return s.On != "" && s.on == minute, s.Off != "" && s.off == minute
+}
+
+// ScheduleTargets -- returns the targets selected by the schedule, either listed explicitly or
+// configured devices having all the group labels of the schedule
+func (c *Config) ScheduleTargets(s *Schedule) []string {
+	targets := append([]string{}, s.Targets...)
+	if len(s.Groups) == 0 {
+		return targets
+	}
+
+	for _, device := range c.Devices {
+		matches := true
+		for label, group := range s.Groups {
+			if device.Groups[label] != group {
+				matches = false
+				break
+			}
+		}
+		if matches && !contains(targets, device.Target) {
+			targets = append(targets, device.Target)
+		}
+	}
+	return targets
+}
+
+// validate --
+func (s *Schedule) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name must be specified")
+	}
+	if len(s.Targets) == 0 && len(s.Groups) == 0 {
+		return fmt.Errorf("schedule %v selects no devices, targets or groups must be specified", s.Name)
+	}
+	if s.On == "" && s.Off == "" {
+		return fmt.Errorf("schedule %v has neither on nor off time", s.Name)
+	}
+
+	var err error
+	if s.On != "" {
+		if s.on, err = parseClock(s.On); err != nil {
+			return fmt.Errorf("schedule %v: on: %v", s.Name, err.Error())
+		}
+	}
+	if s.Off != "" {
+		if s.off, err = parseClock(s.Off); err != nil {
+			return fmt.Errorf("schedule %v: off: %v", s.Name, err.Error())
+		}
+	}
+
+	if s.days, err = parseDays(s.Days); err != nil {
+		return fmt.Errorf("schedule %v: %v", s.Name, err.Error())
+	}
+	return nil
+}
+
+// contains --
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
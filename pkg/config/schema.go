@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -- the messages of yaml.v2 for keys without a field and values of the wrong type
+var (
+	unknownKeyPattern = regexp.MustCompile(`^line (\d+): field (.+) not found in type (\S+)$`)
+	linePattern       = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+)
+
+// Schema -- returns the JSON schema of the configuration file, derived from the types of the
+// configuration; keys not in the schema are rejected when loading it
+func Schema() map[string]interface{} {
+	schema := schemaOf(reflect.TypeOf(Config{}), make(map[reflect.Type]bool))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "mystrom-exporter configuration"
+	return schema
+}
+
+// schemaOf -- the schema of a value of the type, types containing themselves accept any value below
+func schemaOf(t reflect.Type, parents map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]interface{}{"type": "string", "pattern": `^([0-9.]+(ns|us|µs|ms|s|m|h))+$`}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if parents[t] {
+			return map[string]interface{}{}
+		}
+		parents[t] = true
+		defer delete(parents, t)
+
+		properties := make(map[string]interface{})
+		for _, field := range yamlFields(t) {
+			properties[field.name] = schemaOf(field.Type, parents)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), parents)}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), parents)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// yamlField -- a key of a struct in the configuration file
+type yamlField struct {
+	reflect.StructField
+	name string
+}
+
+// yamlFields -- the keys of the struct, including those of inlined structs
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			fields = append(fields, yamlFields(field.Type)...)
+			continue
+		}
+		if field.PkgPath != "" || tag[0] == "-" {
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{StructField: field, name: name})
+	}
+	return fields
+}
+
+// structTypes -- the struct types of the configuration by their name in the messages of yaml.v2
+func structTypes(t reflect.Type, types map[string]reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || types[t.String()] != nil {
+		return types
+	}
+	types[t.String()] = t
+	for _, field := range yamlFields(t) {
+		structTypes(field.Type, types)
+	}
+	return types
+}
+
+// explainParseError -- turns the errors of yaml.v2 into one message per problem with the position in
+// the file, unknown keys with the closest known key as suggestion
+func explainParseError(filename string, content []byte, err error) error {
+	var messages []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	lines := strings.Split(string(content), "\n")
+	types := structTypes(reflect.TypeOf(Config{}), make(map[string]reflect.Type))
+	explained := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if match := unknownKeyPattern.FindStringSubmatch(message); match != nil {
+			var line int
+			fmt.Sscanf(match[1], "%d", &line)
+			explained = append(explained, fmt.Sprintf("%v:%d:%d: %v", filename, line, keyColumn(lines, line, match[2]),
+				unknownKey(match[2], types[match[3]])))
+			continue
+		}
+		if match := linePattern.FindStringSubmatch(message); match != nil {
+			explained = append(explained, fmt.Sprintf("%v:%v: %v", filename, match[1], match[2]))
+			continue
+		}
+		explained = append(explained, fmt.Sprintf("%v: %v", filename, message))
+	}
+	return fmt.Errorf("%v", strings.Join(explained, "; "))
+}
+
+// unknownKey -- describes the unknown key with the closest key of the struct, or all of its keys if
+// none is close
+func unknownKey(key string, t reflect.Type) string {
+	if t == nil {
+		return fmt.Sprintf("unknown key '%v'", key)
+	}
+	var names []string
+	for _, field := range yamlFields(t) {
+		names = append(names, field.name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", len(key)/2+1
+	for _, name := range names {
+		if distance := editDistance(key, name); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown key '%v', did you mean '%v'?", key, best)
+	}
+	return fmt.Sprintf("unknown key '%v', expected one of %v", key, strings.Join(names, ", "))
+}
+
+// keyColumn -- the column of the key in the line, 1 if it isn't found
+func keyColumn(lines []string, line int, key string) int {
+	if line < 1 || line > len(lines) {
+		return 1
+	}
+	text := lines[line-1]
+	for _, quoted := range []string{`"` + key + `"`, "'" + key + "'", key} {
+		if index := strings.Index(text, quoted); index >= 0 {
+			return len([]rune(text[:index])) + 1
+		}
+	}
+	return 1
+}
+
+// editDistance -- the Levenshtein distance of the strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// min3 --
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Sinks -- the outputs the polled readings are pushed to, by the leader if leader election is enabled
+type Sinks struct {
+	JSONFile    *JSONFileSink    `yaml:"json_file,omitempty"`
+	InfluxDB    *InfluxDBSink    `yaml:"influxdb,omitempty"`
+	MQTT        *MQTTSink        `yaml:"mqtt,omitempty"`
+	RemoteWrite *RemoteWriteSink `yaml:"remote_write,omitempty"`
+}
+
+// SinkOptions -- the settings common to all sinks
+type SinkOptions struct {
+	// -- the number of polls buffered while the sink is unavailable, the oldest are dropped first
+	QueueSize int `yaml:"queue_size,omitempty"`
+	// -- the polls failed to be written are buffered in this directory and replayed on recovery
+	BufferPath string `yaml:"buffer_path,omitempty"`
+	// -- the oldest buffered polls are dropped above this size
+	BufferMaxBytes int64 `yaml:"buffer_max_bytes,omitempty"`
+	// -- regular expressions matching the whole metric name, the excluded metrics are removed from the included
+	IncludeMetrics []string `yaml:"include_metrics,omitempty"`
+	ExcludeMetrics []string `yaml:"exclude_metrics,omitempty"`
+
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// JSONFileSink -- appends the readings as JSON lines to a file
+type JSONFileSink struct {
+	SinkOptions `yaml:",inline"`
+	Path        string `yaml:"path"`
+}
+
+// InfluxDBSink -- writes the readings in the line protocol, to the /write endpoint of InfluxDB 1.x or the
+// /api/v2/write endpoint of InfluxDB 2.x including the query parameters selecting the database or bucket
+type InfluxDBSink struct {
+	SinkOptions `yaml:",inline"`
+	URL         string     `yaml:"url" redact:"url"`
+	BasicAuth   *BasicAuth `yaml:"basic_auth,omitempty"`
+	Token       string     `yaml:"token,omitempty" redact:"true"`
+	TokenFile   string     `yaml:"token_file,omitempty"`
+}
+
+// MQTTSink -- publishes every reading to the topic <topic_prefix>/<instance>/<metric>
+type MQTTSink struct {
+	SinkOptions  `yaml:",inline"`
+	Broker       string `yaml:"broker"`
+	ClientID     string `yaml:"client_id,omitempty"`
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty" redact:"true"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+	TopicPrefix  string `yaml:"topic_prefix,omitempty"`
+	QoS          byte   `yaml:"qos,omitempty"`
+	Retain       bool   `yaml:"retain,omitempty"`
+}
+
+// RemoteWriteSink -- sends the readings with the remote write protocol of Prometheus
+type RemoteWriteSink struct {
+	SinkOptions     `yaml:",inline"`
+	URL             string        `yaml:"url" redact:"url"`
+	BasicAuth       *BasicAuth    `yaml:"basic_auth,omitempty"`
+	BearerToken     string        `yaml:"bearer_token,omitempty" redact:"true"`
+	BearerTokenFile string        `yaml:"bearer_token_file,omitempty"`
+	Timeout         time.Duration `yaml:"timeout,omitempty"`
+	// -- the polls of this interval are sent together, 0 sends every poll on its own
+	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
+	// -- the maximum size of a request before compression
+	MaxBatchBytes int `yaml:"max_batch_bytes,omitempty"`
+}
+
+// validate --
+func (s *Sinks) validate() error {
+	if s.JSONFile != nil {
+		if s.JSONFile.Path == "" {
+			return fmt.Errorf("json_file: path is missing")
+		}
+		if err := s.JSONFile.SinkOptions.validate(); err != nil {
+			return fmt.Errorf("json_file: %v", err.Error())
+		}
+	}
+	if s.InfluxDB != nil {
+		if err := validateURL(s.InfluxDB.URL); err != nil {
+			return fmt.Errorf("influxdb: %v", err.Error())
+		}
+		if s.InfluxDB.Token != "" && s.InfluxDB.TokenFile != "" {
+			return fmt.Errorf("influxdb: token and token_file exclude each other")
+		}
+		if s.InfluxDB.BasicAuth != nil {
+			if err := s.InfluxDB.BasicAuth.validate(); err != nil {
+				return fmt.Errorf("influxdb: %v", err.Error())
+			}
+		}
+		if err := s.InfluxDB.SinkOptions.validate(); err != nil {
+			return fmt.Errorf("influxdb: %v", err.Error())
+		}
+	}
+	if s.MQTT != nil {
+		if s.MQTT.Broker == "" {
+			return fmt.Errorf("mqtt: broker is missing")
+		}
+		if s.MQTT.Password != "" && s.MQTT.PasswordFile != "" {
+			return fmt.Errorf("mqtt: password and password_file exclude each other")
+		}
+		if s.MQTT.QoS > 2 {
+			return fmt.Errorf("mqtt: qos must be 0, 1 or 2")
+		}
+		if s.MQTT.TopicPrefix == "" {
+			s.MQTT.TopicPrefix = "mystrom"
+		}
+		if err := s.MQTT.SinkOptions.validate(); err != nil {
+			return fmt.Errorf("mqtt: %v", err.Error())
+		}
+	}
+	if s.RemoteWrite != nil {
+		if err := validateURL(s.RemoteWrite.URL); err != nil {
+			return fmt.Errorf("remote_write: %v", err.Error())
+		}
+		if s.RemoteWrite.BearerToken != "" && s.RemoteWrite.BearerTokenFile != "" {
+			return fmt.Errorf("remote_write: bearer_token and bearer_token_file exclude each other")
+		}
+		if s.RemoteWrite.BasicAuth != nil {
+			if err := s.RemoteWrite.BasicAuth.validate(); err != nil {
+				return fmt.Errorf("remote_write: %v", err.Error())
+			}
+		}
+		if s.RemoteWrite.Timeout == 0 {
+			s.RemoteWrite.Timeout = 30 * time.Second
+		}
+		if s.RemoteWrite.FlushInterval < 0 {
+			return fmt.Errorf("remote_write: flush_interval must not be negative")
+		}
+		if s.RemoteWrite.MaxBatchBytes == 0 {
+			s.RemoteWrite.MaxBatchBytes = 1 << 20
+		}
+		if err := s.RemoteWrite.SinkOptions.validate(); err != nil {
+			return fmt.Errorf("remote_write: %v", err.Error())
+		}
+	}
+
+	// -- every sink needs its own buffer
+	paths := make(map[string]bool)
+	for _, options := range s.options() {
+		if options.BufferPath == "" {
+			continue
+		}
+		path := filepath.Clean(options.BufferPath)
+		if paths[path] {
+			return fmt.Errorf("buffer_path %v is used by several sinks", options.BufferPath)
+		}
+		paths[path] = true
+	}
+	return nil
+}
+
+// options -- returns the options of the configured sinks
+func (s *Sinks) options() []SinkOptions {
+	var options []SinkOptions
+	if s.JSONFile != nil {
+		options = append(options, s.JSONFile.SinkOptions)
+	}
+	if s.InfluxDB != nil {
+		options = append(options, s.InfluxDB.SinkOptions)
+	}
+	if s.MQTT != nil {
+		options = append(options, s.MQTT.SinkOptions)
+	}
+	if s.RemoteWrite != nil {
+		options = append(options, s.RemoteWrite.SinkOptions)
+	}
+	return options
+}
+
+// validate -- compiles the metric patterns and sets the defaults
+func (o *SinkOptions) validate() error {
+	if o.QueueSize == 0 {
+		o.QueueSize = 100
+	}
+	if o.BufferPath != "" && o.BufferMaxBytes == 0 {
+		o.BufferMaxBytes = 100 << 20
+	}
+
+	var err error
+	if o.include, err = compilePatterns(o.IncludeMetrics); err != nil {
+		return fmt.Errorf("include_metrics: %v", err.Error())
+	}
+	if o.exclude, err = compilePatterns(o.ExcludeMetrics); err != nil {
+		return fmt.Errorf("exclude_metrics: %v", err.Error())
+	}
+	return nil
+}
+
+// Matches -- whether the metric is written to the sink, all metrics are included without include patterns
+func (o SinkOptions) Matches(name string) bool {
+	if len(o.include) > 0 && !matchesAny(o.include, name) {
+		return false
+	}
+	return !matchesAny(o.exclude, name)
+}
+
+// compilePatterns -- compiles the regular expressions anchored at both ends
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%v': %v", pattern, err.Error())
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchesAny --
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateURL -- checks for an absolute http or https url
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url '%v'", value)
+	}
+	return nil
+}
+
+// InfluxToken -- returns the token, a token file is read on every call to pick up rotated secrets
+func (i *InfluxDBSink) InfluxToken() (string, error) {
+	return secret(i.Token, i.TokenFile)
+}
+
+// MQTTPassword -- returns the password, a password file is read on every call to pick up rotated secrets
+func (m *MQTTSink) MQTTPassword() (string, error) {
+	return secret(m.Password, m.PasswordFile)
+}
+
+// Token -- returns the bearer token, a token file is read on every call to pick up rotated secrets
+func (r *RemoteWriteSink) Token() (string, error) {
+	return secret(r.BearerToken, r.BearerTokenFile)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"time"
+	// -- the zone database for the tariff timezone, the alpine image comes without one
+	_ "time/tzdata"
+)
+
+// DefaultTariffWindow -- name of the window used when no configured window matches
+const DefaultTariffWindow = "default"
+
+// Tariff -- the electricity contract used to calculate the energy cost
+type Tariff struct {
+	Currency string         `yaml:"currency"`
+	Price    float64        `yaml:"price"`
+	Timezone string         `yaml:"timezone"`
+	Windows  []TariffWindow `yaml:"windows"`
+
+	location *time.Location
+}
+
+// TariffWindow -- a time range with its own price per kWh, e.g. the night tariff
+type TariffWindow struct {
+	Name  string   `yaml:"name"`
+	Days  []string `yaml:"days"`
+	From  string   `yaml:"from"`
+	To    string   `yaml:"to"`
+	Price float64  `yaml:"price"`
+
+	days map[time.Weekday]bool
+	from int
+	to   int
+}
+
+// PriceAt -- returns the name and the price per kWh of the window active at the given time
+func (t *Tariff) PriceAt(now time.Time) (string, float64) {
+	if t.location != nil {
+		now = now.In(t.location)
+	}
+	minute := now.Hour()*60 + now.Minute()
+
+	for _, w := range t.Windows {
+		if w.matches(now.Weekday(), minute) {
+			return w.Name, w.Price
+		}
+	}
+
+	return DefaultTariffWindow, t.Price
+}
+
+// validate --
+func (t *Tariff) validate() error {
+	if t.Currency == "" {
+		t.Currency = "CHF"
+	}
+	// -- the windows are in the local time of the contract, not of the exporter
+	if t.Timezone != "" {
+		location, err := time.LoadLocation(t.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %v", err.Error())
+		}
+		t.location = location
+	}
+
+	for i := range t.Windows {
+		w := &t.Windows[i]
+		if w.Name == "" {
+			return fmt.Errorf("window %d has no name", i)
+		}
+
+		var err error
+		if w.from, err = parseClock(w.From); err != nil {
+			return fmt.Errorf("window %v: from: %v", w.Name, err.Error())
+		}
+		if w.to, err = parseClock(w.To); err != nil {
+			return fmt.Errorf("window %v: to: %v", w.Name, err.Error())
+		}
+
+		if w.days, err = parseDays(w.Days); err != nil {
+			return fmt.Errorf("window %v: %v", w.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// matches -- a window with from > to spans midnight and belongs to the day it starts on, so after
+// midnight it applies if it started the previous day
+func (w *TariffWindow) matches(day time.Weekday, minute int) bool {
+	if w.from <= w.to {
+		return w.days[day] && minute >= w.from && minute < w.to
+	}
+	if minute >= w.from {
+		return w.days[day]
+	}
+	return minute < w.to && w.days[(day+6)%7]
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTariffPriceAt(t *testing.T) {
+	tariff := &Tariff{
+		Price: 0.2,
+		Windows: []TariffWindow{
+			{Name: "weekend-night", Days: []string{"fri"}, From: "22:00", To: "06:00", Price: 0.1},
+			{Name: "high", Days: []string{"weekday"}, From: "07:00", To: "20:00", Price: 0.3},
+			{Name: "low", Days: []string{"weekday"}, From: "20:00", To: "07:00", Price: 0.15},
+		},
+	}
+	if err := tariff.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// -- 2021-03-05 is a friday
+	tests := []struct {
+		time   string
+		window string
+	}{
+		{"2021-03-05 21:59", "low"},
+		{"2021-03-05 22:00", "weekend-night"},
+		{"2021-03-05 23:59", "weekend-night"},
+		{"2021-03-06 00:00", "weekend-night"},
+		{"2021-03-06 03:00", "weekend-night"},
+		{"2021-03-06 06:00", "low"},
+		{"2021-03-06 07:00", DefaultTariffWindow},
+		{"2021-03-06 22:30", DefaultTariffWindow},
+		{"2021-03-07 03:00", DefaultTariffWindow},
+		{"2021-03-08 03:00", DefaultTariffWindow},
+		{"2021-03-08 07:00", "high"},
+		{"2021-03-08 20:00", "low"},
+		{"2021-03-09 06:59", "low"},
+		{"2021-03-05 06:59", "low"},
+	}
+	for _, test := range tests {
+		now, err := time.Parse("2006-01-02 15:04", test.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if window, _ := tariff.PriceAt(now); window != test.window {
+			t.Errorf("PriceAt(%v) = %v, want %v", test.time, window, test.window)
+		}
+	}
+}
+
+func TestTariffTimezone(t *testing.T) {
+	tariff := &Tariff{
+		Price:    0.2,
+		Timezone: "Europe/Zurich",
+		Windows: []TariffWindow{
+			{Name: "high", Days: []string{"weekday"}, From: "07:00", To: "20:00", Price: 0.3},
+		},
+	}
+	if err := tariff.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// -- 2021-03-08 is a monday, 06:30 UTC is 07:30 in Zurich
+	tests := []struct {
+		time   string
+		window string
+	}{
+		{"2021-03-08 05:59", DefaultTariffWindow},
+		{"2021-03-08 06:00", "high"},
+		{"2021-03-08 18:59", "high"},
+		{"2021-03-08 19:00", DefaultTariffWindow},
+	}
+	for _, test := range tests {
+		now, err := time.Parse("2006-01-02 15:04", test.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if window, _ := tariff.PriceAt(now); window != test.window {
+			t.Errorf("PriceAt(%v UTC) = %v, want %v", test.time, window, test.window)
+		}
+	}
+
+	tariff.Timezone = "Europe/Nowhere"
+	if err := tariff.validate(); err == nil {
+		t.Error("validate() accepted an unknown timezone")
+	}
+}
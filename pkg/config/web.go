@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// KnownRoles -- the roles which can be bound to users and client certificates, admin includes all others
+var KnownRoles = []string{"read-metrics", "read-devices", "control", "admin"}
+
+// DefaultProxyPaths -- the device endpoints readable through the proxy if none are configured
+var DefaultProxyPaths = []string{"/report", "/temp", "/api/v1/info"}
+
+// Web -- settings of the exporters own http endpoints
+type Web struct {
+	BasicAuthUsers  map[string]string   `yaml:"basic_auth_users" redact:"true"`
+	ProxyPaths      []string            `yaml:"proxy_paths"`
+	UserRoles       map[string][]string `yaml:"user_roles"`
+	ClientCertRoles map[string][]string `yaml:"client_cert_roles"`
+	TLSCertFile     string              `yaml:"tls_cert_file"`
+	TLSKeyFile      string              `yaml:"tls_key_file"`
+	ClientCAFile    string              `yaml:"client_ca_file"`
+	OIDC            *OIDC               `yaml:"oidc,omitempty"`
+	// -- the limits of the endpoints by the path of their route, e.g. /device
+	Endpoints map[string]EndpointLimits `yaml:"endpoints,omitempty"`
+}
+
+// EndpointLimits -- the limits of an endpoint, unset ones are taken from the flags
+type EndpointLimits struct {
+	Timeout     time.Duration `yaml:"timeout,omitempty"`
+	MaxInFlight int           `yaml:"max_in_flight,omitempty"`
+}
+
+// validate --
+func (w *Web) validate() error {
+	if w.ProxyPaths == nil {
+		w.ProxyPaths = DefaultProxyPaths
+	}
+	for _, path := range w.ProxyPaths {
+		if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?#") {
+			return fmt.Errorf("proxy path '%v' must start with / and must not contain a query", path)
+		}
+	}
+
+	for user, hash := range w.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("password of user %v must be a bcrypt hash: %v", user, err.Error())
+		}
+	}
+
+	if (w.TLSCertFile == "") != (w.TLSKeyFile == "") {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be specified together")
+	}
+	if w.ClientCAFile != "" && w.TLSCertFile == "" {
+		return fmt.Errorf("client_ca_file requires tls_cert_file")
+	}
+	if len(w.ClientCertRoles) > 0 && w.ClientCAFile == "" {
+		return fmt.Errorf("client_cert_roles require client_ca_file")
+	}
+
+	for user, roles := range w.UserRoles {
+		if _, ok := w.BasicAuthUsers[user]; !ok {
+			return fmt.Errorf("user_roles: unknown user %v", user)
+		}
+		if err := validateRoles(roles); err != nil {
+			return fmt.Errorf("user_roles: %v: %v", user, err.Error())
+		}
+	}
+	for cn, roles := range w.ClientCertRoles {
+		if err := validateRoles(roles); err != nil {
+			return fmt.Errorf("client_cert_roles: %v: %v", cn, err.Error())
+		}
+	}
+
+	if w.OIDC != nil {
+		if err := w.OIDC.validate(); err != nil {
+			return fmt.Errorf("oidc: %v", err.Error())
+		}
+	}
+
+	for path, limits := range w.Endpoints {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("endpoints: path '%v' must start with /", path)
+		}
+		if limits.Timeout < 0 || limits.MaxInFlight < 0 {
+			return fmt.Errorf("endpoints: %v: limits must not be negative", path)
+		}
+	}
+	return nil
+}
+
+// AuthEnabled -- whether any backend authenticating the requests is configured
+func (w *Web) AuthEnabled() bool {
+	return len(w.BasicAuthUsers) > 0 || len(w.ClientCertRoles) > 0 || w.OIDC != nil
+}
+
+// RolesEnabled -- whether the routes are protected by roles instead of only by the basic auth users
+func (w *Web) RolesEnabled() bool {
+	return len(w.UserRoles) > 0 || len(w.ClientCertRoles) > 0 || w.OIDC != nil
+}
+
+// validateRoles --
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !contains(KnownRoles, role) {
+			return fmt.Errorf("unknown role '%v', must be one of %v", role, strings.Join(KnownRoles, ", "))
+		}
+	}
+	return nil
+}
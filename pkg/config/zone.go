@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// Zone -- the source of the requests to the selected devices, for hosts with interfaces in several
+// networks where routing alone doesn't pick the right one
+type Zone struct {
+	Name          string            `yaml:"name"`
+	Targets       []string          `yaml:"targets"`
+	Groups        map[string]string `yaml:"groups"`
+	SourceAddress string            `yaml:"source_address"`
+	Interface     string            `yaml:"interface"`
+}
+
+// Zone -- returns the first zone selecting the target, either listed explicitly or by the group
+// labels of the device; nil if there is none
+func (c *Config) Zone(target string) *Zone {
+	device := c.Device(target)
+	for i := range c.Zones {
+		z := &c.Zones[i]
+		if contains(z.Targets, target) {
+			return z
+		}
+		if device == nil || len(z.Groups) == 0 {
+			continue
+		}
+		matches := true
+		for label, group := range z.Groups {
+			if device.Groups[label] != group {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return z
+		}
+	}
+	return nil
+}
+
+// validate --
+func (z *Zone) validate() error {
+	if z.Name == "" {
+		return fmt.Errorf("name must be specified")
+	}
+	if len(z.Targets) == 0 && len(z.Groups) == 0 {
+		return fmt.Errorf("zone %v selects no devices, targets or groups must be specified", z.Name)
+	}
+	if (z.SourceAddress == "") == (z.Interface == "") {
+		return fmt.Errorf("zone %v needs either source_address or interface", z.Name)
+	}
+	if z.SourceAddress != "" && net.ParseIP(z.SourceAddress) == nil {
+		return fmt.Errorf("zone %v has an invalid source_address '%v'", z.Name, z.SourceAddress)
+	}
+	return nil
+}
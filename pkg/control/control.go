@@ -0,0 +1,126 @@
+package control
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+const namespace = "mystrom_exporter"
+
+// Action -- an operation on the relay of a device
+type Action string
+
+// the supported actions
+const (
+	ActionOn     Action = "on"
+	ActionOff    Action = "off"
+	ActionToggle Action = "toggle"
+)
+
+var requestsCounterVec = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "control_requests_total",
+		Help:      "Number of relay control requests by target, action, source and result",
+	},
+	[]string{"target", "action", "source", "result"})
+
+// dryRun -- validate, log and count actions without sending them to the devices
+var dryRun bool
+
+// SetDryRun -- enables or disables the dry-run mode
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+}
+
+// DryRun -- whether actions are only validated, logged and counted
+func DryRun() bool {
+	return dryRun
+}
+
+// Collectors -- returns the metrics of the control layer to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{requestsCounterVec, blockedCounterVec, autoOffCounterVec}
+}
+
+// ParseAction --
+func ParseAction(value string) (Action, error) {
+	switch action := Action(strings.ToLower(value)); action {
+	case ActionOn, ActionOff, ActionToggle:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown action '%v', must be one of on, off or toggle", value)
+}
+
+// NoRelayError -- the target is a device without relay, e.g. a bulb or a button
+type NoRelayError struct {
+	Target string
+	Module string
+}
+
+// Error --
+func (e *NoRelayError) Error() string {
+	return fmt.Sprintf("target '%v' is a %v without relay", e.Target, e.Module)
+}
+
+// Execute -- runs the action against the relay of the target, source names the initiator
+// of the request (e.g. api or schedule) and is used in logs and metrics
+func Execute(target string, action Action, source string) error {
+	// -- an unreachable device fails below, with the error of the relay
+	if module, err := mystrom.DetectModule(target); err == nil && !mystrom.HasRelay(module) {
+		requestsCounterVec.WithLabelValues(target, string(action), source, "error").Inc()
+		log.Warnf("refused to %v relay of target '%v' (%v): it's a %v without relay", action, target, source, module)
+		return &NoRelayError{Target: target, Module: module}
+	}
+	if err := checkInterlocks(target, action); err != nil {
+		result := "error"
+		if _, ok := err.(*BlockedError); ok {
+			result = "blocked"
+		}
+		requestsCounterVec.WithLabelValues(target, string(action), source, result).Inc()
+		log.Warnf("refused to %v relay of target '%v' (%v): %v", action, target, source, err)
+		return err
+	}
+
+	if dryRun {
+		requestsCounterVec.WithLabelValues(target, string(action), source, "dry_run").Inc()
+		log.Infof("dry-run: would turn relay of target '%v' %v (%v)", target, action, source)
+		return nil
+	}
+
+	exporter := mystrom.NewExporter(target)
+
+	var err error
+	switch action {
+	case ActionOn:
+		if err = exporter.SetRelay(true); err == nil {
+			recordAction(target, true)
+		}
+	case ActionOff:
+		if err = exporter.SetRelay(false); err == nil {
+			recordAction(target, false)
+		}
+	case ActionToggle:
+		var relay bool
+		if relay, err = exporter.ToggleRelay(); err == nil {
+			recordAction(target, relay)
+		}
+	default:
+		err = fmt.Errorf("unknown action '%v'", action)
+	}
+
+	if err != nil {
+		requestsCounterVec.WithLabelValues(target, string(action), source, "error").Inc()
+		log.Errorf("failed to %v relay of target '%v' (%v): %v", action, target, source, err)
+		return err
+	}
+
+	requestsCounterVec.WithLabelValues(target, string(action), source, "ok").Inc()
+	log.Infof("turned relay of target '%v' %v (%v)", target, action, source)
+	return nil
+}
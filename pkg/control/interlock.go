@@ -0,0 +1,150 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/leader"
+	"mystrom-exporter/pkg/mystrom"
+)
+
+// interlockCheckInterval -- how often the relays of devices with a max on-duration are checked
+const interlockCheckInterval = 30 * time.Second
+
+// BlockedError -- returned when an interlock prevents an action
+type BlockedError struct {
+	Target string
+	Reason string
+}
+
+// Error --
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("relay of target '%v' is protected: %v", e.Target, e.Reason)
+}
+
+var (
+	blockedCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "control_blocked_total",
+			Help:      "Number of relay control requests blocked by an interlock by target, action and reason",
+		},
+		[]string{"target", "action", "reason"})
+	autoOffCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "control_auto_off_total",
+			Help:      "Number of relays turned off automatically after exceeding the maximum on-duration by target",
+		},
+		[]string{"target"})
+)
+
+var (
+	settings      = &config.Config{}
+	onSince       = make(map[string]time.Time)
+	interlockLock sync.Mutex
+)
+
+// Initialize -- configures the interlocks
+func Initialize(cfg *config.Config) {
+	interlockLock.Lock()
+	settings = cfg
+	interlockLock.Unlock()
+}
+
+// Watch -- starts watching the devices with a max on-duration until the context is done, only
+// with the relay control enabled
+func Watch(ctx context.Context) {
+	interlockLock.Lock()
+	cfg := settings
+	interlockLock.Unlock()
+
+	for _, device := range cfg.Devices {
+		if device.MaxOnDuration > 0 {
+			go watchOnDuration(ctx, device.Target, device.MaxOnDuration)
+		}
+	}
+}
+
+// checkInterlocks -- returns a BlockedError if the action isn't allowed for the target
+func checkInterlocks(target string, action Action) error {
+	interlockLock.Lock()
+	device := settings.Device(target)
+	interlockLock.Unlock()
+
+	if device == nil || !device.NeverOff || action == ActionOn {
+		return nil
+	}
+
+	if action == ActionToggle {
+		// -- a toggle is only harmless when the relay is currently off
+		relay, err := mystrom.NewExporter(target).FetchRelay()
+		if err != nil {
+			return fmt.Errorf("unable to check relay state: %v", err.Error())
+		}
+		if !relay {
+			return nil
+		}
+	}
+
+	blockedCounterVec.WithLabelValues(target, string(action), "never_off").Inc()
+	return &BlockedError{Target: target, Reason: "never_off"}
+}
+
+// recordAction -- remembers when a relay was turned on by the exporter
+func recordAction(target string, on bool) {
+	interlockLock.Lock()
+	defer interlockLock.Unlock()
+
+	if on {
+		onSince[target] = time.Now()
+	} else {
+		delete(onSince, target)
+	}
+}
+
+// watchOnDuration -- turns the relay off once it was seen on for longer than the maximum
+func watchOnDuration(ctx context.Context, target string, maxOn time.Duration) {
+	ticker := time.NewTicker(interlockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		relay, err := mystrom.NewExporter(target).WithContext(ctx).FetchRelay()
+		if err != nil {
+			log.Debugf("failed to check on-duration of target '%v': %v", target, err)
+			continue
+		}
+
+		interlockLock.Lock()
+		since, known := onSince[target]
+		if !relay {
+			delete(onSince, target)
+		} else if !known {
+			onSince[target] = time.Now()
+		}
+		interlockLock.Unlock()
+
+		// -- of redundant instances only the leader switches the relays, the others keep track of them
+		// to take over
+		if !relay || !known || time.Since(since) < maxOn || !leader.IsLeader() {
+			continue
+		}
+
+		log.Warnf("relay of target '%v' was on for more than %v, turning it off", target, maxOn)
+		if err := Execute(target, ActionOff, "interlock"); err == nil {
+			autoOffCounterVec.WithLabelValues(target).Inc()
+		}
+	}
+}
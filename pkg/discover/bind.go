@@ -0,0 +1,82 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	connectionUDP *net.UDPConn
+	bindMutex     sync.Mutex
+
+	// -- closed on shutdown to stop retrying
+	closing   = make(chan struct{})
+	closeOnce sync.Once
+
+	enabledGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "mystrom_discovery_enabled",
+			Help: "Whether the discovery listens for announcements, 0 while the udp port can't be bound",
+		})
+)
+
+// Collectors -- returns the metrics of the discovery to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{enabledGauge, conflictsCounter, conflictGauge, foreignCounter, foreignGauge,
+		peerSyncsCounterVec, peerDevicesGauge}
+}
+
+// bind -- opens the discovery port and starts listening on it
+func bind(channel chan Packet, reuse bool) error {
+	listenConfig := net.ListenConfig{}
+	if reuse {
+		listenConfig.Control = reusePort
+	}
+	conn, err := listenConfig.ListenPacket(context.Background(), "udp", port)
+	if err != nil {
+		return fmt.Errorf("unable to listen for announcements on udp port %v: %v", strings.TrimPrefix(port, ":"), err.Error())
+	}
+
+	bindMutex.Lock()
+	defer bindMutex.Unlock()
+
+	select {
+	case <-closing:
+		conn.Close()
+		return nil
+	default:
+	}
+	connectionUDP = conn.(*net.UDPConn)
+	enabledGauge.Set(1)
+
+	go listen(channel, port, connectionUDP)
+	return nil
+}
+
+// retryBind -- retries to open the discovery port until it succeeds or the exporter stops
+func retryBind(channel chan Packet, reuse bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closing:
+			return
+		case <-ticker.C:
+		}
+
+		if err := bind(channel, reuse); err != nil {
+			log.Debugf("discovery is still not available: %v", err)
+			continue
+		}
+		log.Info("discovery is available, listening for announcements")
+		return
+	}
+}
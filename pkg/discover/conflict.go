@@ -0,0 +1,84 @@
+package discover
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/inventory"
+)
+
+// -- announcements of a type not repeated within this window are no longer considered conflicting
+const conflictWindow = time.Hour
+
+// observation -- the last announcement of a mac address with one device type
+type observation struct {
+	packet Packet
+	seen   time.Time
+}
+
+var (
+	// -- the announcements by mac address and device type, only accessed by the update goroutine
+	observations = make(map[string]map[int]observation)
+
+	conflictsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mystrom_exporter_discovery_mac_conflicts_total",
+			Help: "Number of announcements claiming a mac address already announced with another device type",
+		})
+	conflictGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mystrom_discovery_mac_conflict",
+			Help: "Number of device types announced for the mac address within the last hour, only present while above 1",
+		},
+		[]string{"mac"})
+)
+
+// resolve -- records the announcement and returns the one to offer for its mac address, of several
+// device types the one verified by the last successful scrape is preferred, else the most recent
+func resolve(msg Packet, now time.Time) Packet {
+	mac := msg.MacAddress.String()
+	types, ok := observations[mac]
+	if !ok {
+		types = make(map[int]observation)
+		observations[mac] = types
+	}
+	_, known := types[msg.DeviceType]
+	types[msg.DeviceType] = observation{packet: msg, seen: now}
+
+	for deviceType, o := range types {
+		if now.Sub(o.seen) > conflictWindow {
+			delete(types, deviceType)
+		}
+	}
+	if len(types) < 2 {
+		conflictGauge.DeleteLabelValues(mac)
+		return msg
+	}
+
+	conflictGauge.WithLabelValues(mac).Set(float64(len(types)))
+	if !known {
+		conflictsCounter.Inc()
+		seen := make([]string, 0, len(types))
+		for deviceType, o := range types {
+			seen = append(seen, fmt.Sprintf("type %d from %v", deviceType, o.packet.SourceIP))
+		}
+		sort.Strings(seen)
+		log.With("mac", mac).With("announcements", strings.Join(seen, ", ")).
+			Warn("mac address announced with different device types")
+	}
+
+	if verified, ok := inventory.VerifiedType(strings.ToUpper(hex.EncodeToString(msg.MacAddress))); ok {
+		for deviceType, o := range types {
+			if fmt.Sprintf("%d", deviceType) == verified {
+				return o.packet
+			}
+		}
+	}
+	return msg
+}
@@ -2,24 +2,21 @@ package discover
 
 import (
 	"bytes"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/inventory"
+	"mystrom-exporter/pkg/lru"
 )
 
 const port = ":7979"
 
-type LabelsList map[string]string
-
-type TargetsEntry struct {
-	Targets []string   `json:"targets"`
-	Labels  LabelsList `json:"labels"`
-}
-type TargetsList []TargetsEntry
-
 type Packet struct {
 	SourceIP   string           `json:"source_ip"`
 	Port       int              `json:"port"`
@@ -28,35 +25,46 @@ type Packet struct {
 }
 type Packetlist map[string]Packet
 
-var LocalAddress string
-var discoverlist Packetlist
-var connectionUDP *net.UDPConn
+var (
+	discoverlist  = make(Packetlist)
+	discoverMutex sync.Mutex
+	// -- when the devices of the discoverlist were announced last, exchanged with the peers
+	discoverSeen = make(map[string]time.Time)
+	// -- the announced mac addresses, the least recently announced ones are dropped above the maximum
+	announced = lru.New(0)
+)
 
-// Initialize -- starts the updater and listener goroutines on startup
-func Initialize(localaddr string) {
-	discoverlist = make(Packetlist)
-	channel := make(chan Packet, 10)
+// SetMaxDevices -- limits the number of announced devices remembered, 0 doesn't limit the number; must be
+// called before Initialize
+func SetMaxDevices(max int) {
+	announced.SetMax(max)
+}
 
-	if strings.HasPrefix(localaddr, ":") {
-		LocalAddress = getOutboundIP().String() + localaddr
-	} else {
-		LocalAddress = localaddr
-	}
-	localAddress, err := net.ResolveUDPAddr("udp", port)
-	if err != nil {
-		log.Fatalf("error: %v", err)
-	}
-	connectionUDP, err = net.ListenUDP("udp", localAddress)
-	if err != nil {
-		log.Fatalf("error: %v", err)
-	}
+// Initialize -- starts the updater and listener goroutines on startup, with reuse the port is shared
+// with other listeners for the announcements where the platform supports it; when the port can't be
+// bound, binding is retried in the given interval while the exporter runs without discovered devices
+func Initialize(reuse bool, retryInterval time.Duration) {
+	channel := make(chan Packet, 10)
 
-	go listen(channel, port, connectionUDP)
 	go update(channel)
+
+	if err := bind(channel, reuse); err != nil {
+		log.Errorf("discovery is not available, retrying every %v: %v", retryInterval, err)
+		go retryBind(channel, reuse, retryInterval)
+	}
 }
 
 // ConnClose --
 func ConnClose() {
+	bindMutex.Lock()
+	defer bindMutex.Unlock()
+
+	closeOnce.Do(func() {
+		close(closing)
+	})
+	if connectionUDP == nil {
+		return
+	}
 	if err := connectionUDP.Close(); err != nil {
 		log.Errorf("error: %v", err)
 		return
@@ -64,30 +72,15 @@ func ConnClose() {
 	log.Info("stopping discovery listener")
 }
 
-// Discover --
-func Discover() ([]byte, error) {
-	var targetlist TargetsList
-
-	for macaddr, data := range discoverlist {
-		targetlist = append(targetlist, TargetsEntry{
-			Targets: []string{
-				LocalAddress,
-			},
-			Labels: LabelsList{
-				"instance":         data.SourceIP,
-				"__metrics_path__": fmt.Sprintf("/device_by_mac/%s", data.MacAddress),
-				"__mac_address":    macaddr,
-				"__device_type":    fmt.Sprintf("%d", data.DeviceType),
-			},
-		})
-	}
-
-	return json.Marshal(targetlist)
-}
-
 // TargetByMacaddr --
 func TargetByMacaddr(macaddr string) string {
-	return discoverlist[macaddr].SourceIP
+	discoverMutex.Lock()
+	defer discoverMutex.Unlock()
+
+	if data, known := discoverlist[macaddr]; known {
+		return data.SourceIP
+	}
+	return gossipedTarget(macaddr)
 }
 
 // update -- updates the
@@ -95,7 +88,46 @@ func update(channel <-chan Packet) {
 	for {
 		msg := <-channel
 		log.Debugf("msg: %s | %s\n", msg.SourceIP, msg.MacAddress.String())
-		discoverlist[msg.MacAddress.String()] = msg
+		for _, mac := range announced.Touch(msg.MacAddress.String()) {
+			forget(mac)
+		}
+		if !checkOUI(msg) {
+			continue
+		}
+		now := time.Now()
+		offered := resolve(msg, now)
+		discoverMutex.Lock()
+		previous, known := discoverlist[msg.MacAddress.String()]
+		discoverlist[msg.MacAddress.String()] = offered
+		discoverSeen[msg.MacAddress.String()] = now
+		discoverMutex.Unlock()
+		if !known || previous.SourceIP != offered.SourceIP || previous.DeviceType != offered.DeviceType {
+			changed()
+		}
+		inventory.Observe(inventory.Device{
+			Mac:  strings.ToUpper(hex.EncodeToString(msg.MacAddress)),
+			IP:   msg.SourceIP,
+			Type: fmt.Sprintf("%d", msg.DeviceType),
+		})
+	}
+}
+
+// forget -- drops everything remembered about the announcements of the mac address, called by the
+// update goroutine only
+func forget(mac string) {
+	log.Infof("dropping least recently announced device '%v'", mac)
+	discoverMutex.Lock()
+	_, offered := discoverlist[mac]
+	delete(discoverlist, mac)
+	delete(discoverSeen, mac)
+	discoverMutex.Unlock()
+
+	delete(observations, mac)
+	delete(foreignMacs, mac)
+	conflictGauge.DeleteLabelValues(mac)
+	foreignGauge.DeleteLabelValues(mac)
+	if offered {
+		changed()
 	}
 }
 
@@ -116,7 +148,8 @@ func listen(receive chan Packet, port string, connection *net.UDPConn) {
 			return
 		}
 		buffer := bytes.NewBuffer(inputBytes[:length])
-		if len(buffer.String()) < 6 {
+		// -- 6 bytes mac address followed by the device type
+		if len(buffer.String()) < 7 {
 			continue
 		}
 		macString := net.HardwareAddr(buffer.String()[0:6])
@@ -130,13 +163,14 @@ func listen(receive chan Packet, port string, connection *net.UDPConn) {
 			MacAddress: macString,
 			DeviceType: deviceType,
 		}
+		recordRaw(message, inputBytes[:length])
 
 		receive <- message
 	}
 }
 
-// getOutboundIP -- Get preferred outbound ip of this machine, connectivy not needed
-func getOutboundIP() net.IP {
+// OutboundIP -- Get preferred outbound ip of this machine, connectivy not needed
+func OutboundIP() net.IP {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,58 @@
+package discover
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"mystrom-exporter/pkg/testutil"
+)
+
+func TestDiscoverFakeAnnouncer(t *testing.T) {
+	connection, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := make(chan Packet, 10)
+	go update(channel)
+	go listen(channel, "", connection)
+	defer connection.Close()
+
+	announcer, err := testutil.NewAnnouncer(connection.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer announcer.Close()
+	announcer.Start("64:00:2D:00:00:21", 106, 50*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for TargetByMacaddr("64:00:2d:00:00:21") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("announced device not discovered")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if target := TargetByMacaddr("64:00:2d:00:00:21"); target != "127.0.0.1" {
+		t.Errorf("got target %v, want 127.0.0.1", target)
+	}
+
+	discoverMutex.Lock()
+	packet := discoverlist["64:00:2d:00:00:21"]
+	discoverMutex.Unlock()
+	if packet.DeviceType != 106 {
+		t.Errorf("got device type %v, want 106", packet.DeviceType)
+	}
+}
+
+func TestAnnounceInvalidMac(t *testing.T) {
+	announcer, err := testutil.NewAnnouncer("127.0.0.1:7979")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer announcer.Close()
+	for _, mac := range []string{"", "64002D", "zz:00:2D:00:00:21"} {
+		if err := announcer.Announce(mac, 106); err == nil {
+			t.Errorf("announcing mac %q: want an error", mac)
+		}
+	}
+}
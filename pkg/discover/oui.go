@@ -0,0 +1,61 @@
+package discover
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+// -- the handling of announcements of mac addresses without a vendor prefix of myStrom
+const (
+	OUIPolicyLabel = "label"
+	OUIPolicyDrop  = "drop"
+)
+
+var (
+	ouiPolicy = OUIPolicyLabel
+	// -- the foreign mac addresses already logged, only accessed by the update goroutine
+	foreignMacs = make(map[string]bool)
+
+	foreignCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "mystrom_exporter_discovery_foreign_mac_announcements_total",
+			Help: "Number of announcements of mac addresses without a vendor prefix of myStrom",
+		})
+	foreignGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mystrom_discovery_foreign_mac",
+			Help: "Always 1, for every announced mac address without a vendor prefix of myStrom",
+		},
+		[]string{"mac"})
+)
+
+// SetOUIPolicy -- label offers the devices with foreign mac addresses with the label __oui_mismatch,
+// drop ignores their announcements
+func SetOUIPolicy(policy string) error {
+	if policy != OUIPolicyLabel && policy != OUIPolicyDrop {
+		return fmt.Errorf("invalid policy '%v', must be label or drop", policy)
+	}
+	ouiPolicy = policy
+	return nil
+}
+
+// checkOUI -- counts and logs announcements of foreign mac addresses, false if they are dropped
+func checkOUI(msg Packet) bool {
+	mac := msg.MacAddress.String()
+	if mystrom.IsMystromMac(mac) {
+		return true
+	}
+
+	foreignCounter.Inc()
+	foreignGauge.WithLabelValues(mac).Set(1)
+	if !foreignMacs[mac] {
+		foreignMacs[mac] = true
+		log.With("mac", mac).With("source", msg.SourceIP).With("policy", ouiPolicy).
+			Warn("announced mac address has no vendor prefix of myStrom")
+	}
+	return ouiPolicy != OUIPolicyDrop
+}
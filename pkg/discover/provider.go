@@ -0,0 +1,73 @@
+package discover
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/provider"
+)
+
+// -- signalled when a device was discovered or changed its address or type
+var changes = make(chan struct{}, 1)
+
+// Provider -- offers the discovered devices, they are scraped by their mac address
+type Provider struct{}
+
+// Name --
+func (Provider) Name() string {
+	return "discovery"
+}
+
+// List -- the devices announced to a peer are labeled with it, the ones announced here win
+func (Provider) List() ([]provider.Target, error) {
+	discoverMutex.Lock()
+	defer discoverMutex.Unlock()
+
+	targets := make([]provider.Target, 0, len(discoverlist))
+	for macaddr, data := range discoverlist {
+		targets = append(targets, target(macaddr, data))
+	}
+	for macaddr, entry := range gossipedPackets() {
+		if _, known := discoverlist[macaddr]; known {
+			continue
+		}
+		t := target(macaddr, entry.packet)
+		t.Labels["__discovery_peer"] = entry.peer
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// target --
+func target(macaddr string, data Packet) provider.Target {
+	labels := map[string]string{
+		"instance":         data.SourceIP,
+		"__metrics_path__": fmt.Sprintf("/device_by_mac/%s", data.MacAddress),
+		"__mac_address":    macaddr,
+	}
+	if !mystrom.IsMystromMac(macaddr) {
+		labels["__oui_mismatch"] = "true"
+	}
+	return provider.Target{
+		Target: data.SourceIP,
+		Mac:    strings.ToUpper(hex.EncodeToString(data.MacAddress)),
+		Type:   fmt.Sprintf("%d", data.DeviceType),
+		Labels: labels,
+	}
+}
+
+// Watch --
+func (Provider) Watch(ctx context.Context) <-chan struct{} {
+	return changes
+}
+
+// changed -- signals the change of the discovered devices without blocking, a pending signal covers it
+func changed() {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
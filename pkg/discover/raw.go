@@ -0,0 +1,102 @@
+package discover
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// RawPacket -- a discovery announcement as received, normalized for other tools
+type RawPacket struct {
+	Time       time.Time `json:"time"`
+	SourceIP   string    `json:"source_ip"`
+	Port       int       `json:"port"`
+	Mac        string    `json:"mac"`
+	DeviceType int       `json:"device_type"`
+	Payload    string    `json:"payload"`
+}
+
+var (
+	// -- the most recent announcements, at most rawBufferSize
+	rawPackets    []RawPacket
+	rawBufferSize int
+	forwardConns  []*net.UDPConn
+	rawMutex      sync.Mutex
+)
+
+// SetRawFeed -- keeps the given number of recent announcements for the raw feed and forwards every
+// announcement unchanged to the comma separated udp addresses, so other tools don't need port 7979
+func SetRawFeed(bufferSize int, forwardAddresses string) error {
+	if bufferSize < 0 {
+		return fmt.Errorf("invalid buffer size %v", bufferSize)
+	}
+
+	conns := []*net.UDPConn{}
+	for _, address := range strings.Split(forwardAddresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		udpAddr, err := net.ResolveUDPAddr("udp", address)
+		if err != nil {
+			return fmt.Errorf("invalid forward address '%v': %v", address, err.Error())
+		}
+		conn, err := net.DialUDP("udp", nil, udpAddr)
+		if err != nil {
+			return fmt.Errorf("unable to forward to '%v': %v", address, err.Error())
+		}
+		conns = append(conns, conn)
+	}
+
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+
+	rawBufferSize = bufferSize
+	forwardConns = conns
+	return nil
+}
+
+// RawPackets -- returns the remembered announcements received after the given time, oldest first
+func RawPackets(since time.Time) []RawPacket {
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+
+	packets := []RawPacket{}
+	for _, packet := range rawPackets {
+		if packet.Time.After(since) {
+			packets = append(packets, packet)
+		}
+	}
+	return packets
+}
+
+// recordRaw -- remembers the announcement and forwards it
+func recordRaw(packet Packet, payload []byte) {
+	rawMutex.Lock()
+	defer rawMutex.Unlock()
+
+	if rawBufferSize > 0 {
+		rawPackets = append(rawPackets, RawPacket{
+			Time:       time.Now(),
+			SourceIP:   packet.SourceIP,
+			Port:       packet.Port,
+			Mac:        strings.ToUpper(hex.EncodeToString(packet.MacAddress)),
+			DeviceType: packet.DeviceType,
+			Payload:    hex.EncodeToString(payload),
+		})
+		if len(rawPackets) > rawBufferSize {
+			rawPackets = append([]RawPacket{}, rawPackets[len(rawPackets)-rawBufferSize:]...)
+		}
+	}
+
+	for _, conn := range forwardConns {
+		if _, err := conn.Write(payload); err != nil {
+			log.Debugf("failed to forward announcement to '%v': %v", conn.RemoteAddr(), err)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package discover
+
+import (
+	"syscall"
+)
+
+// reusePort -- sharing the discovery port isn't supported on this platform
+func reusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package exposition
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+)
+
+// Handler -- serves the gatherer like promhttp, but when OpenMetrics is negotiated every counter
+// sample is followed by a _created sample with the given time, unless the time is zero
+func Handler(gatherer prometheus.Gatherer, created time.Time) http.Handler {
+	fallback := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if created.IsZero() || expfmt.NegotiateIncludingOpenMetrics(r.Header) != expfmt.FmtOpenMetrics {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		families, err := gatherer.Gather()
+		if err != nil {
+			http.Error(w, "error gathering metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		for _, family := range families {
+			if family.GetType() == dto.MetricType_COUNTER {
+				err = writeCounter(&buf, family, created)
+			} else {
+				_, err = expfmt.MetricFamilyToOpenMetrics(&buf, family)
+			}
+			if err != nil {
+				log.Errorf("failed to encode metric family %v: %v", family.GetName(), err)
+				http.Error(w, "error encoding metrics: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		expfmt.FinalizeOpenMetrics(&buf)
+
+		w.Header().Set("Content-Type", string(expfmt.FmtOpenMetrics))
+		w.Write(buf.Bytes())
+	})
+}
+
+// writeCounter -- encodes the family and inserts a _created sample after the sample of every metric,
+// the created samples are encoded as gauges to get the same label formatting
+func writeCounter(buf *bytes.Buffer, family *dto.MetricFamily, created time.Time) error {
+	var encoded bytes.Buffer
+	if _, err := expfmt.MetricFamilyToOpenMetrics(&encoded, family); err != nil {
+		return err
+	}
+
+	var headers, samples []string
+	scanner := bufio.NewScanner(&encoded)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "#") {
+			headers = append(headers, scanner.Text())
+		} else {
+			samples = append(samples, scanner.Text())
+		}
+	}
+
+	createdName := strings.TrimSuffix(family.GetName(), "_total") + "_created"
+	createdValue := float64(created.UnixNano()) / 1e9
+
+	for _, header := range headers {
+		buf.WriteString(header + "\n")
+	}
+	for i, sample := range samples {
+		buf.WriteString(sample + "\n")
+		if i >= len(family.Metric) {
+			continue
+		}
+
+		createdFamily := &dto.MetricFamily{
+			Name: proto.String(createdName),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: family.Metric[i].Label,
+				Gauge: &dto.Gauge{Value: proto.Float64(createdValue)},
+			}},
+		}
+		var line bytes.Buffer
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&line, createdFamily); err != nil {
+			return err
+		}
+		for _, l := range strings.Split(strings.TrimSpace(line.String()), "\n") {
+			if !strings.HasPrefix(l, "#") {
+				buf.WriteString(l + "\n")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package exposition
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// WithLabel -- adds the label to every metric of the gatherer which doesn't have it yet, the families
+// are copied as they may be shared, e.g. by the poller; an empty value returns the gatherer unchanged
+func WithLabel(gatherer prometheus.Gatherer, name string, value string) prometheus.Gatherer {
+	return WithLabels(gatherer, map[string]string{name: value})
+}
+
+// WithLabels -- adds the labels like WithLabel, labels with an empty value are skipped
+func WithLabels(gatherer prometheus.Gatherer, labels map[string]string) prometheus.Gatherer {
+	names := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return gatherer
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+		if err != nil {
+			return nil, err
+		}
+
+		labeled := make([]*dto.MetricFamily, 0, len(families))
+		for _, family := range families {
+			family = proto.Clone(family).(*dto.MetricFamily)
+			for _, metric := range family.Metric {
+				for _, name := range names {
+					if hasLabel(metric, name) {
+						continue
+					}
+					metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(labels[name])})
+				}
+				sort.Slice(metric.Label, func(i, j int) bool {
+					return metric.Label[i].GetName() < metric.Label[j].GetName()
+				})
+			}
+			labeled = append(labeled, family)
+		}
+		return labeled, nil
+	})
+}
+
+// hasLabel --
+func hasLabel(metric *dto.Metric, name string) bool {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
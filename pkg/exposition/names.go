@@ -0,0 +1,82 @@
+package exposition
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// -- the naming schemes of the device metrics
+const (
+	NamesLegacy = "legacy"
+	NamesBoth   = "both"
+	NamesV2     = "v2"
+)
+
+// Renames -- the names of the device metrics in the naming scheme v2 by their legacy name, following the
+// conventions of Prometheus for units and states
+var Renames = map[string]string{
+	"mystrom_power":       "mystrom_power_watts",
+	"mystrom_temperature": "mystrom_temperature_celsius",
+	"mystrom_relay":       "mystrom_relay_state",
+
+	"mystrom_power_smoothed": "mystrom_power_smoothed_watts",
+
+	"mystrom_temperature_raw":          "mystrom_temperature_raw_celsius",
+	"mystrom_temperature_compensation": "mystrom_temperature_compensation_celsius",
+	"mystrom_temperature_compensated":  "mystrom_temperature_compensated_celsius",
+	// -- a watt second is a joule
+	"mystrom_energy_wattseconds_total": "mystrom_energy_joules_total",
+}
+
+// Unconditional -- the v2 names exposed with the legacy naming scheme as well, so they are available
+// without opting in to the scheme v2
+var Unconditional = map[string]bool{
+	"mystrom_relay_state": true,
+}
+
+// ValidateNames -- checks the name of a naming scheme
+func ValidateNames(scheme string) error {
+	switch scheme {
+	case NamesLegacy, NamesBoth, NamesV2:
+		return nil
+	}
+	return fmt.Errorf("invalid naming scheme '%v', must be legacy, both or v2", scheme)
+}
+
+// WithNames -- exposes the renamed metrics of the gatherer with the names of the scheme, both exposes
+// them twice; served is called with the legacy names of the renamed metrics exposed by a gather
+func WithNames(gatherer prometheus.Gatherer, scheme string, served func(legacy []string)) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+		if err != nil {
+			return nil, err
+		}
+
+		var legacy []string
+		named := make([]*dto.MetricFamily, 0, len(families))
+		for _, family := range families {
+			v2, renamed := Renames[family.GetName()]
+			if !renamed {
+				named = append(named, family)
+				continue
+			}
+			if scheme != NamesV2 {
+				named = append(named, family)
+				legacy = append(legacy, family.GetName())
+			}
+			if scheme != NamesLegacy || Unconditional[v2] {
+				// -- the families may be shared, e.g. by the poller
+				family = proto.Clone(family).(*dto.MetricFamily)
+				family.Name = proto.String(v2)
+				named = append(named, family)
+			}
+		}
+		if len(legacy) > 0 && served != nil {
+			served(legacy)
+		}
+		return named, nil
+	})
+}
@@ -0,0 +1,41 @@
+package exposition
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWithNames(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"mystrom_power", "mystrom_relay", "mystrom_device_info"} {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: name})
+		registry.MustRegister(gauge)
+	}
+
+	tests := []struct {
+		scheme string
+		names  []string
+	}{
+		{NamesLegacy, []string{"mystrom_device_info", "mystrom_power", "mystrom_relay", "mystrom_relay_state"}},
+		{NamesBoth, []string{"mystrom_device_info", "mystrom_power", "mystrom_power_watts", "mystrom_relay",
+			"mystrom_relay_state"}},
+		{NamesV2, []string{"mystrom_device_info", "mystrom_power_watts", "mystrom_relay_state"}},
+	}
+	for _, test := range tests {
+		families, err := WithNames(registry, test.scheme, nil).Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, family := range families {
+			names = append(names, family.GetName())
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%v: got %v, want %v", test.scheme, names, test.names)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+const reqTimeout = time.Second * 10
+
+var (
+	latest      = make(map[string]string)
+	latestMutex sync.Mutex
+)
+
+// Initialize -- loads the configured versions and starts refreshing them from the url
+func Initialize(cfg *config.Firmware) {
+	if cfg == nil {
+		return
+	}
+
+	setLatest(cfg.Latest, nil)
+	if cfg.URL != "" {
+		go refresh(cfg)
+	}
+}
+
+// UpdateAvailable -- compares the version of a device with the latest known version for its type,
+// known is false if there is no latest version for the type
+func UpdateAvailable(deviceType string, version string) (available bool, known bool) {
+	latestMutex.Lock()
+	newest, ok := latest[deviceType]
+	latestMutex.Unlock()
+
+	if !ok {
+		return false, false
+	}
+	return compareVersions(version, newest) < 0, true
+}
+
+// setLatest -- the static versions of the configuration win over the fetched ones
+func setLatest(static map[string]string, fetched map[string]string) {
+	latestMutex.Lock()
+	defer latestMutex.Unlock()
+
+	latest = make(map[string]string, len(static)+len(fetched))
+	for deviceType, version := range fetched {
+		latest[deviceType] = version
+	}
+	for deviceType, version := range static {
+		latest[deviceType] = version
+	}
+}
+
+// refresh -- fetches the latest versions from the url in the configured interval
+func refresh(cfg *config.Firmware) {
+	ticker := time.NewTicker(cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		fetched, err := fetchLatest(cfg.URL)
+		if err != nil {
+			log.Errorf("failed to refresh latest firmware versions: %v", err)
+			continue
+		}
+		log.Debugf("latest firmware versions: %v", fetched)
+		setLatest(cfg.Latest, fetched)
+	}
+}
+
+// fetchLatest -- the url must return a JSON object with the device type as key and the version as value
+func fetchLatest(url string) (map[string]string, error) {
+	client := http.Client{Timeout: reqTimeout}
+
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", res.Status)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body: %v", err.Error())
+	}
+
+	versions := make(map[string]string)
+	if err := json.Unmarshal(body, &versions); err != nil {
+		return nil, fmt.Errorf("unable to decode versions: %v", err.Error())
+	}
+	return versions, nil
+}
+
+// compareVersions -- compares dotted versions numerically, returns -1, 0 or 1
+func compareVersions(a string, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
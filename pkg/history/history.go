@@ -0,0 +1,165 @@
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"mystrom-exporter/pkg/sink"
+)
+
+// -- the time covered and the number of points kept per series, polls closer than window/capacity to
+// the previous point are skipped
+const (
+	Window   = time.Hour
+	capacity = 720
+)
+
+// Metrics -- the metrics kept in the history
+var Metrics = []string{"mystrom_power", "mystrom_temperature"}
+
+// Point -- a polled value
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// ring -- the latest points of a series in a fixed size buffer
+type ring struct {
+	points [capacity]Point
+	start  int
+	count  int
+}
+
+var (
+	// -- the series by target and metric
+	series      = make(map[string]map[string]*ring)
+	seriesMutex sync.Mutex
+)
+
+// add -- adds the point, overwriting the oldest one once the buffer is full
+func (r *ring) add(p Point) {
+	if r.count > 0 && p.Time.Sub(r.points[(r.start+r.count-1)%capacity].Time) < Window/capacity {
+		return
+	}
+	if r.count < capacity {
+		r.points[(r.start+r.count)%capacity] = p
+		r.count++
+		return
+	}
+	r.points[r.start] = p
+	r.start = (r.start + 1) % capacity
+}
+
+// since -- returns the points after the time, oldest first
+func (r *ring) since(t time.Time) []Point {
+	var points []Point
+	for i := 0; i < r.count; i++ {
+		p := r.points[(r.start+i)%capacity]
+		if p.Time.After(t) {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// Sink -- returns the sink keeping the history of the polls, for the quick look of the web ui
+func Sink() sink.Sink {
+	return historySink{}
+}
+
+// historySink --
+type historySink struct{}
+
+// Name --
+func (historySink) Name() string {
+	return "history"
+}
+
+// Write --
+func (historySink) Write(batch sink.Batch) error {
+	seriesMutex.Lock()
+	defer seriesMutex.Unlock()
+
+	for _, family := range batch.Families {
+		if !kept(family.GetName()) || len(family.Metric) == 0 {
+			continue
+		}
+		var value float64
+		switch family.GetType() {
+		case dto.MetricType_GAUGE:
+			value = family.Metric[0].GetGauge().GetValue()
+		case dto.MetricType_UNTYPED:
+			value = family.Metric[0].GetUntyped().GetValue()
+		default:
+			continue
+		}
+
+		target, ok := series[batch.Target]
+		if !ok {
+			target = make(map[string]*ring)
+			series[batch.Target] = target
+		}
+		r, ok := target[family.GetName()]
+		if !ok {
+			r = &ring{}
+			target[family.GetName()] = r
+		}
+		r.add(Point{Time: batch.Time, Value: value})
+	}
+	return nil
+}
+
+// Close --
+func (historySink) Close() error {
+	return nil
+}
+
+// Targets -- returns the targets with points within the window, sorted
+func Targets() []string {
+	seriesMutex.Lock()
+	defer seriesMutex.Unlock()
+
+	since := time.Now().Add(-Window)
+	var targets []string
+	for target, metrics := range series {
+		recent := false
+		for _, r := range metrics {
+			if r.count > 0 && r.points[(r.start+r.count-1)%capacity].Time.After(since) {
+				recent = true
+			}
+		}
+		if !recent {
+			// -- targets no longer polled are forgotten once their points aged out
+			delete(series, target)
+			continue
+		}
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Series -- returns the points of the metric of the target within the window, oldest first
+func Series(target, metric string) []Point {
+	seriesMutex.Lock()
+	defer seriesMutex.Unlock()
+
+	r, ok := series[target][metric]
+	if !ok {
+		return nil
+	}
+	return r.since(time.Now().Add(-Window))
+}
+
+// kept --
+func kept(name string) bool {
+	for _, metric := range Metrics {
+		if metric == name {
+			return true
+		}
+	}
+	return false
+}
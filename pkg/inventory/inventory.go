@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/storage"
+)
+
+// Device -- everything known about a device seen through a scrape or the discovery
+type Device struct {
+	Mac         string            `json:"mac"`
+	IP          string            `json:"ip"`
+	Target      string            `json:"target,omitempty"`
+	Type        string            `json:"type"`
+	Name        string            `json:"name,omitempty"`
+	Firmware    string            `json:"firmware,omitempty"`
+	FirstSeen   time.Time         `json:"first_seen"`
+	LastSeen    time.Time         `json:"last_seen"`
+	Verified    *time.Time        `json:"verified,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+var (
+	devices      = make(map[string]*Device)
+	devicesMutex sync.Mutex
+	maxDevices   int
+)
+
+// Observe -- records that the device with the normalized mac address was seen now, the non-empty
+// fields of the observation replace the known ones; an observation with a target comes from a scrape
+// and verifies the device, announcements of another type don't replace a verified device
+func Observe(observed Device) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	now := time.Now()
+	d, ok := devices[observed.Mac]
+	if !ok {
+		d = &Device{Mac: observed.Mac, FirstSeen: now}
+		devices[observed.Mac] = d
+	}
+	d.LastSeen = now
+	dirty = true
+	if !ok {
+		evictDevices()
+	}
+	if observed.Target != "" {
+		d.Verified = &now
+	} else if d.Verified != nil && observed.Type != "" && observed.Type != d.Type {
+		return
+	}
+
+	for _, field := range []struct{ known, observed *string }{
+		{&d.IP, &observed.IP},
+		{&d.Target, &observed.Target},
+		{&d.Type, &observed.Type},
+		{&d.Name, &observed.Name},
+		{&d.Firmware, &observed.Firmware},
+	} {
+		if *field.observed != "" {
+			*field.known = *field.observed
+		}
+	}
+}
+
+// VerifiedType -- returns the type of the device reported by its last successful scrape
+func VerifiedType(mac string) (string, bool) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	d, ok := devices[mac]
+	if !ok || d.Verified == nil {
+		return "", false
+	}
+	return d.Type, true
+}
+
+// DeviceName -- returns the name assigned to the device in the myStrom app, by its normalized mac address
+// or, without one, by the target it was scraped with; empty if it's unknown
+func DeviceName(mac, target string) string {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	if d, ok := devices[mac]; ok && mac != "" {
+		return d.Name
+	}
+	if target == "" {
+		return ""
+	}
+	for _, d := range devices {
+		if d.Target == target {
+			return d.Name
+		}
+	}
+	return ""
+}
+
+// SetMaxDevices -- limits the number of devices in the inventory, the least recently seen ones are
+// dropped first; 0 doesn't limit the number
+func SetMaxDevices(max int) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	maxDevices = max
+}
+
+// evictDevices -- drops the least recently seen devices above the maximum, must be called with the devices locked
+func evictDevices() {
+	for maxDevices > 0 && len(devices) > maxDevices {
+		var oldest *Device
+		for _, d := range devices {
+			if oldest == nil || d.LastSeen.Before(oldest.LastSeen) {
+				oldest = d
+			}
+		}
+		log.Infof("dropping least recently seen device '%v' from the inventory", oldest.Mac)
+		evictionsCounter.Inc()
+		delete(devices, oldest.Mac)
+	}
+}
+
+// Devices -- returns all known devices with their annotations, sorted by mac address
+func Devices() []Device {
+	devicesMutex.Lock()
+	list := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		list = append(list, *d)
+	}
+	devicesMutex.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Mac < list[j].Mac
+	})
+	for i := range list {
+		if annotations := storage.Annotations(list[i].Mac); len(annotations) > 0 {
+			list[i].Annotations = annotations
+		}
+	}
+	return list
+}
+
+// WriteCSV -- writes the devices as CSV with a header line, the annotations are joined as key=value pairs
+func WriteCSV(w io.Writer, list []Device) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"mac", "ip", "target", "type", "name", "firmware", "first_seen", "last_seen", "annotations"})
+
+	for _, d := range list {
+		keys := make([]string, 0, len(d.Annotations))
+		for key := range d.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+d.Annotations[key])
+		}
+
+		out.Write([]string{
+			d.Mac, d.IP, d.Target, d.Type, d.Name, d.Firmware,
+			d.FirstSeen.UTC().Format(time.RFC3339), d.LastSeen.UTC().Format(time.RFC3339),
+			strings.Join(pairs, ";"),
+		})
+	}
+
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,78 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "mystrom_exporter"
+
+var (
+	firstSeenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "device_first_seen_timestamp_seconds"),
+		"When the device was first seen through a scrape or the discovery, by mac address",
+		[]string{"mac", "target"}, nil)
+	lastSeenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "device_last_seen_timestamp_seconds"),
+		"When the device was last seen through a scrape or the discovery, by mac address",
+		[]string{"mac", "target"}, nil)
+	missingDesc = prometheus.NewDesc(
+		"mystrom_device_missing",
+		"Whether a device which was seen for a long time stopped announcing itself and being scraped",
+		[]string{"mac", "target"}, nil)
+)
+
+var evictionsCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_evictions_total",
+		Help:      "Number of devices dropped from the inventory to stay below the maximum number of devices",
+	})
+
+var (
+	stableAfter  = 72 * time.Hour
+	missingAfter = 15 * time.Minute
+)
+
+// SetMissing -- a device seen for longer than stableAfter is missing once it wasn't seen for missingAfter
+func SetMissing(stable time.Duration, missing time.Duration) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	stableAfter, missingAfter = stable, missing
+}
+
+// collector -- exposes the sightings of the devices in the inventory
+type collector struct{}
+
+// Collectors -- returns the metrics of the inventory to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{collector{}, evictionsCounter}
+}
+
+// Describe --
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- firstSeenDesc
+	ch <- lastSeenDesc
+	ch <- missingDesc
+}
+
+// Collect --
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	now := time.Now()
+	for _, d := range devices {
+		missing := 0.0
+		if d.LastSeen.Sub(d.FirstSeen) >= stableAfter && now.Sub(d.LastSeen) >= missingAfter {
+			missing = 1
+		}
+		ch <- prometheus.MustNewConstMetric(missingDesc, prometheus.GaugeValue, missing, d.Mac, d.Target)
+		ch <- prometheus.MustNewConstMetric(firstSeenDesc, prometheus.GaugeValue,
+			float64(d.FirstSeen.UnixNano())/1e9, d.Mac, d.Target)
+		ch <- prometheus.MustNewConstMetric(lastSeenDesc, prometheus.GaugeValue,
+			float64(d.LastSeen.UnixNano())/1e9, d.Mac, d.Target)
+	}
+}
@@ -0,0 +1,57 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/storage"
+)
+
+const inventoryFile = "inventory.json"
+
+var dirty bool
+
+// Initialize -- restores the devices seen before the last restart and starts saving them in the given interval
+func Initialize(interval time.Duration) {
+	loaded := make(map[string]*Device)
+	if _, err := storage.LoadJSON(inventoryFile, &loaded); err != nil {
+		log.Errorf("failed to load inventory: %v", err)
+	}
+
+	devicesMutex.Lock()
+	for mac, d := range loaded {
+		d.Annotations = nil
+		if known, ok := devices[mac]; ok {
+			// -- seen since the start, keep the first sighting of the previous run
+			known.FirstSeen = d.FirstSeen
+			continue
+		}
+		devices[mac] = d
+	}
+	devicesMutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			Save()
+		}
+	}()
+}
+
+// Save -- writes the inventory to the storage path if it changed since the last save
+func Save() {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	if !dirty {
+		return
+	}
+	if err := storage.SaveJSON(inventoryFile, devices); err != nil {
+		log.Errorf("failed to save inventory: %v", err)
+		return
+	}
+	dirty = false
+}
@@ -0,0 +1,204 @@
+package leader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const namespace = "mystrom_exporter"
+
+// lease -- the content of the lease file shared by the exporter instances
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+var (
+	enabled    bool
+	leading    bool
+	stateMutex sync.Mutex
+
+	leaderGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "leader",
+			Help:      "Whether this instance is the leader pushing to the outputs, always 1 without leader election",
+		})
+)
+
+// Collectors -- returns the metrics of the leader election to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	leaderGauge.Set(boolValue(IsLeader()))
+	return []prometheus.Collector{leaderGauge}
+}
+
+// Initialize -- starts competing for the lease in the given file, which must be on storage shared by all
+// instances; the leader renews the lease every third of its duration, the others take over once it expired
+func Initialize(filename string, duration time.Duration, id string) {
+	stateMutex.Lock()
+	enabled = true
+	stateMutex.Unlock()
+	leaderGauge.Set(0)
+
+	log.Infof("leader election enabled as '%v' with lease file %v", id, filename)
+	go func() {
+		ticker := time.NewTicker(duration / 3)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			acquired, err := acquire(filename, duration, id)
+			if err != nil {
+				log.Errorf("failed to acquire leader lease: %v", err)
+			}
+			setLeading(acquired)
+		}
+	}()
+}
+
+// IsLeader -- whether this instance should push to the outputs, true if leader election isn't enabled
+func IsLeader() bool {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	return !enabled || leading
+}
+
+// setLeading --
+func setLeading(acquired bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if acquired != leading {
+		if acquired {
+			log.Info("became leader")
+		} else {
+			log.Info("lost leadership")
+		}
+	}
+	leading = acquired
+	leaderGauge.Set(boolValue(acquired))
+}
+
+// -- how often and in which interval the lock of the lease is tried before giving up for this round
+const (
+	lockAttempts = 20
+	lockInterval = 50 * time.Millisecond
+)
+
+// acquire -- takes or renews the lease if it is expired or held by this instance. The lease is only read
+// and written while holding its lock file, which is created exclusively, so two instances can't both
+// find the lease expired and take it over
+func acquire(filename string, duration time.Duration, id string) (bool, error) {
+	unlock, err := lockLease(filename, id, duration)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	current, err := readLease(filename)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if current != nil && current.Holder != id && now.Before(current.Expires) {
+		return false, nil
+	}
+
+	if err := writeLease(filename, lease{Holder: id, Expires: now.Add(duration)}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lockLease -- creates the lock file of the lease exclusively, waiting a moment for another instance
+// holding it. A lock older than the lease duration was left by an instance stopped while holding it and
+// is removed, it's only taken in the next round so an instance that just took it isn't disturbed
+func lockLease(filename, id string, stale time.Duration) (func(), error) {
+	lockfile := filename + ".lock"
+	for attempt := 0; attempt < lockAttempts; attempt++ {
+		f, err := os.OpenFile(lockfile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(id)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockfile)
+				return nil, fmt.Errorf("unable to lock lease: %v", err.Error())
+			}
+			return func() { os.Remove(lockfile) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("unable to lock lease: %v", err.Error())
+		}
+		if info, err := os.Stat(lockfile); err == nil && time.Since(info.ModTime()) > stale {
+			log.Warnf("removing stale lock %v", lockfile)
+			os.Remove(lockfile)
+			break
+		}
+		time.Sleep(lockInterval)
+	}
+	return nil, fmt.Errorf("lease is locked by another instance")
+}
+
+// readLease -- returns nil if there is no lease yet
+func readLease(filename string) (*lease, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lease: %v", err.Error())
+	}
+
+	current := &lease{}
+	if err := json.Unmarshal(data, current); err != nil {
+		// -- a broken lease is taken over
+		log.Warnf("ignoring broken lease in %v: %v", filename, err)
+		return nil, nil
+	}
+	return current, nil
+}
+
+// writeLease -- writes the lease atomically by renaming a temporary file
+func writeLease(filename string, l lease) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("unable to encode lease: %v", err.Error())
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".lease-")
+	if err != nil {
+		return fmt.Errorf("unable to create lease: %v", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write lease: %v", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write lease: %v", err.Error())
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("unable to write lease: %v", err.Error())
+	}
+	return nil
+}
+
+// boolValue --
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package leader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireSingleLeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "lease.json")
+
+	for round := 0; round < 20; round++ {
+		// -- the lease of the previous round expired
+		if err := writeLease(filename, lease{Holder: "gone", Expires: time.Now().Add(-time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		var leaders []string
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				acquired, err := acquire(filename, time.Minute, id)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if acquired {
+					mutex.Lock()
+					leaders = append(leaders, id)
+					mutex.Unlock()
+				}
+			}(fmt.Sprintf("instance-%d", i))
+		}
+		wg.Wait()
+		if len(leaders) != 1 {
+			t.Fatalf("round %d: got leaders %v, want exactly one", round, leaders)
+		}
+	}
+}
+
+func TestAcquireRemovesStaleLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "lease.json")
+
+	if err := ioutil.WriteFile(filename+".lock", []byte("crashed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filename+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if acquired, err := acquire(filename, time.Minute, "instance-1"); acquired || err == nil {
+		t.Errorf("got %v, %v while removing the stale lock, want an error", acquired, err)
+	}
+	if acquired, err := acquire(filename, time.Minute, "instance-1"); !acquired || err != nil {
+		t.Errorf("got %v, %v after removing the stale lock, want the lease", acquired, err)
+	}
+	if acquired, err := acquire(filename, time.Minute, "instance-2"); acquired || err != nil {
+		t.Errorf("got %v, %v for a lease held by another instance, want none", acquired, err)
+	}
+}
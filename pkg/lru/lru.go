@@ -0,0 +1,131 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Targets -- the targets the exporter keeps state for, every package remembering something per target
+// forgets it once the target is dropped from here
+var Targets = New(0)
+
+// entry --
+type entry struct {
+	key  string
+	used time.Time
+}
+
+// LRU -- keys ordered by their last use, the least recently used ones are dropped above the maximum;
+// touching and dropping a key take constant time
+type LRU struct {
+	mutex    sync.Mutex
+	max      int
+	order    *list.List
+	elements map[string]*list.Element
+	forget   []func(key string)
+}
+
+// New -- returns an LRU keeping at most max keys, 0 doesn't limit the number of keys
+func New(max int) *LRU {
+	return &LRU{max: max, order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+// OnEvict -- adds functions called with every key dropped to stay below the maximum
+func (l *LRU) OnEvict(forget ...func(key string)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.forget = append(l.forget, forget...)
+}
+
+// SetMax -- limits the number of keys, dropping the least recently used ones above the new maximum
+func (l *LRU) SetMax(max int) {
+	l.mutex.Lock()
+	l.max = max
+	evicted, forget := l.shrink()
+	l.mutex.Unlock()
+
+	notify(evicted, forget)
+}
+
+// Touch -- marks the key as used now, the keys dropped for it are returned after the functions of
+// OnEvict were called for them
+func (l *LRU) Touch(key string) []string {
+	l.mutex.Lock()
+	if element, ok := l.elements[key]; ok {
+		element.Value.(*entry).used = time.Now()
+		l.order.MoveToFront(element)
+		l.mutex.Unlock()
+		return nil
+	}
+	l.elements[key] = l.order.PushFront(&entry{key: key, used: time.Now()})
+	evicted, forget := l.shrink()
+	l.mutex.Unlock()
+
+	// -- outside of the lock, the functions may take the locks of their packages which are held
+	// while touching
+	notify(evicted, forget)
+	return evicted
+}
+
+// LastUsed -- returns when the key was touched last, zero if it's unknown
+func (l *LRU) LastUsed(key string) time.Time {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if element, ok := l.elements[key]; ok {
+		return element.Value.(*entry).used
+	}
+	return time.Time{}
+}
+
+// Contains --
+func (l *LRU) Contains(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, ok := l.elements[key]
+	return ok
+}
+
+// Remove -- drops the key without calling the functions of OnEvict
+func (l *LRU) Remove(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if element, ok := l.elements[key]; ok {
+		l.order.Remove(element)
+		delete(l.elements, key)
+	}
+}
+
+// Len --
+func (l *LRU) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.elements)
+}
+
+// shrink -- drops the least recently used keys above the maximum, must be called with the lru locked
+func (l *LRU) shrink() ([]string, []func(key string)) {
+	var evicted []string
+	for l.max > 0 && len(l.elements) > l.max {
+		oldest := l.order.Back()
+		key := oldest.Value.(*entry).key
+		l.order.Remove(oldest)
+		delete(l.elements, key)
+		evicted = append(evicted, key)
+	}
+	return evicted, l.forget
+}
+
+// notify --
+func notify(evicted []string, forget []func(key string)) {
+	for _, key := range evicted {
+		for _, f := range forget {
+			f(key)
+		}
+	}
+}
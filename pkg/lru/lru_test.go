@@ -0,0 +1,58 @@
+package lru
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTouchEvictsLeastRecentlyUsed(t *testing.T) {
+	l := New(3)
+	var forgotten []string
+	l.OnEvict(func(key string) { forgotten = append(forgotten, key) })
+
+	for _, key := range []string{"a", "b", "c", "a", "d", "e"} {
+		l.Touch(key)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(forgotten, want) {
+		t.Errorf("got evicted %v, want %v", forgotten, want)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if got := l.Contains(key); got != want {
+			t.Errorf("contains %v = %v, want %v", key, got, want)
+		}
+	}
+	if l.Len() != 3 {
+		t.Errorf("got %v keys, want 3", l.Len())
+	}
+}
+
+func TestTouchReturnsEvicted(t *testing.T) {
+	l := New(1)
+	if evicted := l.Touch("a"); evicted != nil {
+		t.Errorf("got evicted %v, want none", evicted)
+	}
+	if evicted := l.Touch("b"); !reflect.DeepEqual(evicted, []string{"a"}) {
+		t.Errorf("got evicted %v, want [a]", evicted)
+	}
+	if !l.LastUsed("a").IsZero() || l.LastUsed("b").IsZero() {
+		t.Errorf("got last use %v of a and %v of b", l.LastUsed("a"), l.LastUsed("b"))
+	}
+}
+
+func TestSetMaxShrinks(t *testing.T) {
+	l := New(0)
+	var forgotten []string
+	l.OnEvict(func(key string) { forgotten = append(forgotten, key) })
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		l.Touch(key)
+	}
+	l.Remove("b")
+	l.SetMax(1)
+	if want := []string{"a", "c"}; !reflect.DeepEqual(forgotten, want) {
+		t.Errorf("got evicted %v, want %v", forgotten, want)
+	}
+	if !l.Contains("d") || l.Len() != 1 {
+		t.Errorf("got %v keys, want only d", l.Len())
+	}
+}
@@ -0,0 +1,153 @@
+package mdns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ServiceType -- the DNS-SD service type used for prometheus exporters
+const ServiceType = "_prometheus-http._tcp.local."
+
+// ttl -- time to live of the records in seconds, as recommended by RFC 6762 for host records
+const ttl = 120
+
+var multicastAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// responder -- answers queries for the service and announces it on startup
+type responder struct {
+	conn     *net.UDPConn
+	service  dnsmessage.Name
+	instance dnsmessage.Name
+	host     dnsmessage.Name
+	ip       [4]byte
+	port     uint16
+	txt      []string
+}
+
+// Advertise -- announces the exporter as an instance of _prometheus-http._tcp on the local network
+// and keeps answering queries for it
+func Advertise(instance string, ip net.IP, port int, metricsPath string) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("only IPv4 addresses can be advertised, got %v", ip)
+	}
+
+	host := strings.Split(instance, ".")[0]
+	r := &responder{
+		service: dnsmessage.MustNewName(ServiceType),
+		port:    uint16(port),
+		txt:     []string{"path=" + metricsPath},
+	}
+	copy(r.ip[:], ip4)
+
+	var err error
+	if r.instance, err = dnsmessage.NewName(instance + "." + ServiceType); err != nil {
+		return fmt.Errorf("invalid instance name '%v': %v", instance, err.Error())
+	}
+	if r.host, err = dnsmessage.NewName(host + ".local."); err != nil {
+		return fmt.Errorf("invalid host name '%v': %v", host, err.Error())
+	}
+
+	if r.conn, err = net.ListenMulticastUDP("udp4", nil, multicastAddr); err != nil {
+		return fmt.Errorf("unable to join mdns group: %v", err.Error())
+	}
+
+	log.Infof("advertising %v at %v:%d via mdns", r.instance, ip4, port)
+	go r.announce()
+	go r.listen()
+	return nil
+}
+
+// announce -- sends unsolicited responses on startup, repeated as recommended by RFC 6762
+func (r *responder) announce() {
+	for _, delay := range []time.Duration{0, time.Second, 2 * time.Second} {
+		time.Sleep(delay)
+		r.respond()
+	}
+}
+
+// listen -- answers queries for the service type, the instance or the host
+func (r *responder) listen() {
+	buffer := make([]byte, 9000)
+	for {
+		length, _, err := r.conn.ReadFromUDP(buffer)
+		if err != nil {
+			log.Errorf("mdns: %v", err)
+			return
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buffer[:length])
+		if err != nil || header.Response {
+			continue
+		}
+		questions, err := parser.AllQuestions()
+		if err != nil {
+			continue
+		}
+
+		for _, q := range questions {
+			if r.matches(q) {
+				r.respond()
+				break
+			}
+		}
+	}
+}
+
+// matches --
+func (r *responder) matches(q dnsmessage.Question) bool {
+	name := strings.ToLower(q.Name.String())
+	switch {
+	case name == strings.ToLower(r.service.String()):
+		return q.Type == dnsmessage.TypePTR || q.Type == dnsmessage.TypeALL
+	case name == strings.ToLower(r.instance.String()):
+		return q.Type == dnsmessage.TypeSRV || q.Type == dnsmessage.TypeTXT || q.Type == dnsmessage.TypeALL
+	case name == strings.ToLower(r.host.String()):
+		return q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL
+	}
+	return false
+}
+
+// respond -- multicasts all records of the service at once, the message is small enough
+func (r *responder) respond() {
+	msg, err := r.message()
+	if err != nil {
+		log.Errorf("mdns: unable to build response: %v", err)
+		return
+	}
+	if _, err := r.conn.WriteToUDP(msg, multicastAddr); err != nil {
+		log.Errorf("mdns: unable to send response: %v", err)
+	}
+}
+
+// message --
+func (r *responder) message() ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	header := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+
+	if err := b.PTRResource(header(r.service), dnsmessage.PTRResource{PTR: r.instance}); err != nil {
+		return nil, err
+	}
+	if err := b.SRVResource(header(r.instance), dnsmessage.SRVResource{Target: r.host, Port: r.port}); err != nil {
+		return nil, err
+	}
+	if err := b.TXTResource(header(r.instance), dnsmessage.TXTResource{TXT: r.txt}); err != nil {
+		return nil, err
+	}
+	if err := b.AResource(header(r.host), dnsmessage.AResource{A: r.ip}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
@@ -0,0 +1,48 @@
+package mystrom
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connectError -- the device couldn't be connected at the address, so the next one may be tried
+type connectError struct {
+	err error
+}
+
+// Error --
+func (e *connectError) Error() string {
+	return e.err.Error()
+}
+
+var addressFailoversCounterVec = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "mystrom_exporter",
+		Name:      "address_failovers_total",
+		Help:      "Number of times a device with several addresses couldn't be connected at one and the next was tried",
+	},
+	[]string{"target"})
+
+// registerAddressMetrics -- records the address the device answered at, only for devices with several addresses
+func (e *Exporter) registerAddressMetrics(reg prometheus.Registerer) error {
+	if e.host == "" {
+		return nil
+	}
+
+	collectorAddress := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "address_info",
+			Help:      "The address of a device with several addresses which answered the scrape",
+		},
+		[]string{"instance", "address"})
+
+	if err := reg.Register(collectorAddress); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "address_info", err.Error())
+	}
+
+	collectorAddress.WithLabelValues(e.myStromSwitchIp, e.host).Set(1)
+
+	return nil
+}
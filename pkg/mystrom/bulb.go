@@ -0,0 +1,194 @@
+package mystrom
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// -- the fields of the bulb state some firmware builds report as strings
+var (
+	bulbNumbers = []string{"power", "ramp"}
+	bulbBools   = []string{"on", "reachable"}
+)
+
+// bulbState -- the state of a bulb or led strip from /api/v1/device, keyed by its mac address there
+type bulbState struct {
+	Type      string  `json:"type"`
+	On        bool    `json:"on"`
+	Reachable bool    `json:"reachable"`
+	Color     string  `json:"color"`
+	Mode      string  `json:"mode"`
+	Ramp      float64 `json:"ramp"`
+	Power     float64 `json:"power"`
+}
+
+// UnmarshalJSON -- accepts numbers and booleans as strings
+func (b *bulbState) UnmarshalJSON(data []byte) error {
+	type plain bulbState
+	normalized, err := normalizePayload(data, bulbNumbers, bulbBools)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, (*plain)(b))
+}
+
+// bulbColor -- the color of the bulb decoded by its mode, the values not given by the mode are nil
+type bulbColor struct {
+	brightness       *float64
+	hue              *float64
+	saturation       *float64
+	colorTemperature *float64
+	// -- red, green, blue and white from 0 to 255
+	channels map[string]float64
+}
+
+// scrapeBulb -- capabilities detected before /api/v1/device was known don't rule it out
+func (e *Exporter) scrapeBulb(reg prometheus.Registerer, capabilities map[string]bool) error {
+	if supported, known := capabilities["/api/v1/device"]; known && !supported {
+		return nil
+	}
+	state := bulbState{}
+	if err := e.fetchDeviceState("bulbState", &state); err != nil {
+		return err
+	}
+	if err := registerBulbMetrics(reg, state, e.myStromSwitchIp); err != nil {
+		return fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return nil
+}
+
+// decodeColor -- decodes the color of the mode: hsv as "<hue>;<saturation>;<value>", mono as
+// "<color temperature>;<brightness>" and rgb as "RRGGBB" or "WWRRGGBB"
+func decodeColor(mode, color string) (bulbColor, error) {
+	decoded := bulbColor{}
+	switch mode {
+	case "hsv", "mono":
+		var values []float64
+		for _, part := range strings.Split(color, ";") {
+			value, err := parseNumber(part)
+			if err != nil {
+				return decoded, fmt.Errorf("invalid %v color '%v'", mode, color)
+			}
+			values = append(values, value)
+		}
+		if mode == "hsv" && len(values) == 3 {
+			decoded.hue, decoded.saturation, decoded.brightness = &values[0], &values[1], &values[2]
+			return decoded, nil
+		}
+		if mode == "mono" && len(values) == 2 {
+			decoded.colorTemperature, decoded.brightness = &values[0], &values[1]
+			return decoded, nil
+		}
+		return decoded, fmt.Errorf("invalid %v color '%v'", mode, color)
+	case "rgb":
+		bytes, err := hex.DecodeString(color)
+		if err != nil || (len(bytes) != 3 && len(bytes) != 4) {
+			return decoded, fmt.Errorf("invalid rgb color '%v'", color)
+		}
+		if len(bytes) == 3 {
+			bytes = append([]byte{0}, bytes...)
+		}
+		decoded.channels = map[string]float64{
+			"white": float64(bytes[0]),
+			"red":   float64(bytes[1]),
+			"green": float64(bytes[2]),
+			"blue":  float64(bytes[3]),
+		}
+		return decoded, nil
+	}
+	// -- unknown modes only report the mode
+	return decoded, nil
+}
+
+// registerBulbMetrics --
+func registerBulbMetrics(reg prometheus.Registerer, data bulbState, target string) error {
+	color, err := decodeColor(data.Mode, data.Color)
+	if err != nil {
+		// -- the other values are still exported
+		log.Debugf("bulb of target '%v': %v", target, err)
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value *float64
+	}{
+		{"power", "The current power consumed by the bulb", &data.Power},
+		{"bulb_on", "Whether the bulb is switched on", boolValue(data.On)},
+		{"bulb_reachable", "Whether the bulb is reachable in the mesh of the bulbs", boolValue(data.Reachable)},
+		{"bulb_ramp_milliseconds", "The duration of the transitions between colors", &data.Ramp},
+		{"bulb_brightness_percent", "The brightness of the bulb, only in hsv and mono mode", color.brightness},
+		{"bulb_hue_degrees", "The hue of the color of the bulb, only in hsv mode", color.hue},
+		{"bulb_saturation_percent", "The saturation of the color of the bulb, only in hsv mode", color.saturation},
+		{"bulb_color_temperature", "The color temperature of the bulb from 1 (warm) to 18 (cold), only in mono mode", color.colorTemperature},
+	}
+	for _, gauge := range gauges {
+		if gauge.value == nil {
+			continue
+		}
+		collector := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      gauge.name,
+				Help:      gauge.help,
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", gauge.name, err.Error())
+		}
+
+		collector.WithLabelValues(target).Set(*gauge.value)
+	}
+
+	// --
+	collectorMode := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bulb_color_mode",
+			Help:      "The color mode of the bulb (rgb, hsv or mono), always 1",
+		},
+		[]string{"instance", "mode"})
+
+	if err := reg.Register(collectorMode); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "bulb_color_mode", err.Error())
+	}
+
+	collectorMode.WithLabelValues(target, data.Mode).Set(1)
+
+	if color.channels == nil {
+		return nil
+	}
+
+	// --
+	collectorChannels := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bulb_color_channel",
+			Help:      "The value of the color channel of the bulb from 0 to 255, only in rgb mode",
+		},
+		[]string{"instance", "channel"})
+
+	if err := reg.Register(collectorChannels); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "bulb_color_channel", err.Error())
+	}
+
+	for channel, value := range color.channels {
+		collectorChannels.WithLabelValues(target, channel).Set(value)
+	}
+	return nil
+}
+
+// boolValue --
+func boolValue(b bool) *float64 {
+	value := 0.0
+	if b {
+		value = 1
+	}
+	return &value
+}
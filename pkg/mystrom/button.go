@@ -0,0 +1,220 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// -- the voltages of the battery of a button taken as empty and full
+const (
+	buttonEmptyVoltage = 3.5
+	buttonFullVoltage  = 4.2
+)
+
+// -- the fields of the button state some firmware builds report as strings, and its actions
+var (
+	buttonNumbers = []string{"voltage"}
+	buttonBools   = []string{"charge", "reachable"}
+	buttonActions = []string{"single", "double", "long", "touch", "wheel", "wheel_final"}
+)
+
+// buttonState -- the state of a button from /api/v1/device, keyed by its mac address there
+type buttonState struct {
+	Type      string  `json:"type"`
+	Charge    bool    `json:"charge"`
+	Reachable bool    `json:"reachable"`
+	Voltage   float64 `json:"voltage"`
+	// -- the urls requested by the actions, by action
+	actions map[string]string
+}
+
+// buttonContact -- the last answer of a button, served while it sleeps
+type buttonContact struct {
+	info  Info
+	state buttonState
+	time  time.Time
+}
+
+var (
+	buttons      = make(map[string]*buttonContact)
+	buttonsMutex sync.Mutex
+)
+
+// UnmarshalJSON -- accepts numbers and booleans as strings and collects the configured actions
+func (b *buttonState) UnmarshalJSON(data []byte) error {
+	type plain buttonState
+	normalized, err := normalizePayload(data, buttonNumbers, buttonBools)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(normalized, (*plain)(b)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(normalized, &fields)
+	b.actions = make(map[string]string)
+	for _, action := range buttonActions {
+		var value string
+		if err := json.Unmarshal(fields[action], &value); err == nil && value != "" {
+			b.actions[action] = value
+		}
+	}
+	return nil
+}
+
+// fetchDeviceState -- decodes the state of the device from /api/v1/device, which reports it by the mac
+// address of the device
+func (e *Exporter) fetchDeviceState(name string, state interface{}) error {
+	body, err := e.fetchData("/api/v1/device")
+	if err != nil {
+		return err
+	}
+
+	states := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &states); err != nil {
+		logPayload(e.myStromSwitchIp, "/api/v1/device", body, err)
+		return fmt.Errorf("unable to decode %v: %v", name, err.Error())
+	}
+	// -- the device only reports itself
+	for _, raw := range states {
+		if err := json.Unmarshal(raw, state); err != nil {
+			logPayload(e.myStromSwitchIp, "/api/v1/device", body, err)
+			return fmt.Errorf("unable to decode %v: %v", name, err.Error())
+		}
+		return nil
+	}
+	return fmt.Errorf("unable to decode %v: no device in the response", name)
+}
+
+// scrapeButton -- remembers the answer of the button for the time it sleeps
+func (e *Exporter) scrapeButton(reg prometheus.Registerer, info Info, capabilities map[string]bool) error {
+	if supported, known := capabilities["/api/v1/device"]; known && !supported {
+		return nil
+	}
+	state := buttonState{}
+	if err := e.fetchDeviceState("buttonState", &state); err != nil {
+		return err
+	}
+
+	contact := &buttonContact{info: info, state: state, time: time.Now()}
+	buttonsMutex.Lock()
+	buttons[e.myStromSwitchIp] = contact
+	buttonsMutex.Unlock()
+
+	if err := registerButtonMetrics(reg, contact, false, e.myStromSwitchIp); err != nil {
+		return fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return nil
+}
+
+// asleepButton -- returns the metrics of the last answer if the target is a button which answered
+// before, nil otherwise
+func asleepButton(target string) (*prometheus.Registry, error) {
+	buttonsMutex.Lock()
+	contact, ok := buttons[target]
+	buttonsMutex.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := registerInfoMetrics(reg, contact.info, target, SelectModule(contact.info.SwType, nil)); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	if err := registerButtonMetrics(reg, contact, true, target); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return reg, nil
+}
+
+// forgetButton --
+func forgetButton(target string) {
+	buttonsMutex.Lock()
+	defer buttonsMutex.Unlock()
+
+	delete(buttons, target)
+}
+
+// registerButtonMetrics --
+func registerButtonMetrics(reg prometheus.Registerer, contact *buttonContact, asleep bool, target string) error {
+	state := contact.state
+	percent := (state.Voltage - buttonEmptyVoltage) / (buttonFullVoltage - buttonEmptyVoltage) * 100
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value *float64
+	}{
+		{"button_battery_voltage", "The voltage of the battery of the button", &state.Voltage},
+		{"button_battery_percent", "The charge of the battery of the button estimated from its voltage", &percent},
+		{"button_charging", "Whether the battery of the button is charging", boolValue(state.Charge)},
+		{"button_asleep", "Whether the button sleeps, its metrics are the ones of the last answer then", boolValue(asleep)},
+		{"button_last_contact_timestamp_seconds", "When the button last answered", floatValue(float64(contact.time.UnixNano()) / 1e9)},
+	}
+	for _, gauge := range gauges {
+		collector := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      gauge.name,
+				Help:      gauge.help,
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", gauge.name, err.Error())
+		}
+
+		collector.WithLabelValues(target).Set(*gauge.value)
+	}
+
+	if len(state.actions) == 0 {
+		return nil
+	}
+
+	// --
+	collectorActions := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "button_action_info",
+			Help:      "The url requested by the action of the button, only for configured actions",
+		},
+		[]string{"instance", "action", "url"})
+
+	if err := reg.Register(collectorActions); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "button_action_info", err.Error())
+	}
+
+	for action, actionURL := range state.actions {
+		collectorActions.WithLabelValues(target, action, redactPassword(actionURL)).Set(1)
+	}
+	return nil
+}
+
+// redactPassword -- removes the password of the url
+func redactPassword(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String()
+}
+
+// floatValue --
+func floatValue(value float64) *float64 {
+	return &value
+}
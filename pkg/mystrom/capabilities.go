@@ -0,0 +1,86 @@
+package mystrom
+
+import (
+	"sync"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/storage"
+)
+
+const capabilitiesFile = "capabilities.json"
+
+// capabilitySet -- the endpoints a target supports, detected for a firmware version
+type capabilitySet struct {
+	Version   string          `json:"version"`
+	Endpoints map[string]bool `json:"endpoints"`
+}
+
+var (
+	capabilitySets   = make(map[string]*capabilitySet)
+	capabilityMutex  sync.Mutex
+	capabilityLoaded bool
+)
+
+// LoadCapabilities -- restores the capabilities detected before the last restart
+func LoadCapabilities() {
+	capabilityMutex.Lock()
+	defer capabilityMutex.Unlock()
+
+	sets := make(map[string]*capabilitySet)
+	if _, err := storage.LoadJSON(capabilitiesFile, &sets); err != nil {
+		log.Errorf("failed to load device capabilities: %v", err)
+		return
+	}
+	for target, set := range sets {
+		capabilitySets[target] = set
+	}
+	capabilityLoaded = true
+}
+
+// capabilities -- returns the supported endpoints of the target, probing them on first contact
+// and after firmware changes; endpoints are only marked unsupported when the device answers 404
+func (e *Exporter) capabilities(version string) map[string]bool {
+	capabilityMutex.Lock()
+	set, ok := capabilitySets[e.myStromSwitchIp]
+	capabilityMutex.Unlock()
+
+	if ok && set.Version == version {
+		return set.Endpoints
+	}
+
+	set = &capabilitySet{Version: version, Endpoints: make(map[string]bool)}
+	for _, result := range e.Probe() {
+		if statusErr, ok := result.Err.(*StatusError); ok && statusErr.Code == 404 {
+			set.Endpoints[result.Path] = false
+			continue
+		}
+		if result.Err != nil {
+			// -- the device is unreachable or broken, try again next time
+			log.Debugf("capability detection of target '%v' incomplete: %v", e.myStromSwitchIp, result.Err)
+			return allEndpoints()
+		}
+		set.Endpoints[result.Path] = true
+	}
+	log.Infof("detected capabilities of target '%v' (firmware %v): %v", e.myStromSwitchIp, version, set.Endpoints)
+
+	capabilityMutex.Lock()
+	defer capabilityMutex.Unlock()
+
+	capabilitySets[e.myStromSwitchIp] = set
+	if capabilityLoaded {
+		if err := storage.SaveJSON(capabilitiesFile, capabilitySets); err != nil {
+			log.Errorf("failed to save device capabilities: %v", err)
+		}
+	}
+	return set.Endpoints
+}
+
+// allEndpoints -- assumes every endpoint is supported when detection isn't possible
+func allEndpoints() map[string]bool {
+	endpoints := make(map[string]bool, len(KnownEndpoints))
+	for _, path := range KnownEndpoints {
+		endpoints[path] = true
+	}
+	return endpoints
+}
@@ -0,0 +1,83 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// -- layouts of the device time seen in the firmware versions
+var deviceTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// deviceTime -- the clock of the device as reported by some endpoints, either as string or epoch seconds,
+// values in an unknown format are ignored so they don't fail the whole response
+type deviceTime struct {
+	time.Time
+}
+
+// UnmarshalJSON --
+func (t *deviceTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var epoch float64
+	if err := json.Unmarshal(data, &epoch); err == nil {
+		if epoch > 0 {
+			t.Time = time.Unix(0, int64(epoch*float64(time.Second)))
+		}
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil || value == "" {
+		return nil
+	}
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		t.Time = time.Unix(0, int64(epoch*float64(time.Second)))
+		return nil
+	}
+	for _, layout := range deviceTimeLayouts {
+		// -- the layouts without zone are taken as utc, the devices don't report local time
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return nil
+}
+
+// clockSkew -- the difference between the device clock and the middle of the request, positive when the device is ahead
+func clockSkew(device deviceTime, requested, received time.Time) (float64, bool) {
+	if device.IsZero() {
+		return 0, false
+	}
+	reference := requested.Add(received.Sub(requested) / 2)
+	return device.Sub(reference).Seconds(), true
+}
+
+// registerClockMetrics --
+func registerClockMetrics(reg prometheus.Registerer, skew float64, target string) error {
+	collectorSkew := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "clock_skew_seconds",
+			Help:      "Difference between the clock of the device and the exporter, positive when the device is ahead",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorSkew); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "clock_skew_seconds", err.Error())
+	}
+
+	collectorSkew.WithLabelValues(target).Set(skew)
+
+	return nil
+}
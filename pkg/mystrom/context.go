@@ -0,0 +1,67 @@
+package mystrom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	// -- the time a scrape may take as a whole, 0 limits only the single requests
+	scrapeTimeout      time.Duration
+	scrapeTimeoutMutex sync.Mutex
+)
+
+// CanceledError -- the scrape was canceled before the device answered, e.g. since the client of the
+// scrape disconnected; it tells nothing about the device
+type CanceledError struct {
+	Target string
+}
+
+// Error --
+func (e *CanceledError) Error() string {
+	return "scrape of target '" + e.Target + "' canceled"
+}
+
+// SetScrapeTimeout -- cancels the requests of a scrape still outstanding after the timeout, 0 limits only
+// the single requests
+func SetScrapeTimeout(timeout time.Duration) {
+	scrapeTimeoutMutex.Lock()
+	defer scrapeTimeoutMutex.Unlock()
+
+	scrapeTimeout = timeout
+}
+
+// WithContext -- cancels the outstanding requests to the device once the context is done, e.g. when the
+// client of the scrape disconnects
+func (e *Exporter) WithContext(ctx context.Context) *Exporter {
+	e.ctx = ctx
+	return e
+}
+
+// requestContext -- the context of the requests to the device
+func (e *Exporter) requestContext() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// withScrapeTimeout -- limits the requests of the exporter to the scrape timeout, the returned function
+// restores the previous context
+func (e *Exporter) withScrapeTimeout() func() {
+	scrapeTimeoutMutex.Lock()
+	timeout := scrapeTimeout
+	scrapeTimeoutMutex.Unlock()
+
+	if timeout == 0 {
+		return func() {}
+	}
+	parent := e.ctx
+	ctx, cancel := context.WithTimeout(e.requestContext(), timeout)
+	e.ctx = ctx
+	return func() {
+		cancel()
+		e.ctx = parent
+	}
+}
@@ -0,0 +1,54 @@
+package mystrom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// accountCost -- adds the energy consumed at the average power during the elapsed time up to now to the
+// tariff windows, a minute at a time so longer scrape intervals are split across the windows
+func (s *targetState) accountCost(tariff *config.Tariff, average float64, elapsed time.Duration, now time.Time) {
+	if tariff == nil {
+		return
+	}
+
+	for at := now.Add(-elapsed); at.Before(now); {
+		step := now.Sub(at)
+		if step > time.Minute {
+			step = time.Minute
+		}
+		at = at.Add(step)
+		// -- converted from Ws to kWh, priced at the end of the step
+		window, price := tariff.PriceAt(at)
+		s.cost[window] += average * step.Seconds() / 3.6e6 * price
+	}
+}
+
+// registerCostMetrics --
+func registerCostMetrics(reg prometheus.Registerer, state targetState, tariff *config.Tariff, target string) error {
+	if tariff == nil {
+		return nil
+	}
+
+	collectorCost := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "energy_cost_total",
+			Help:      "Accumulated cost of the energy consumed by devices attached to the switch, by tariff window",
+		},
+		[]string{"instance", "tariff", "currency"})
+
+	if err := reg.Register(collectorCost); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "energy_cost_total", err.Error())
+	}
+
+	for window, value := range state.cost {
+		collectorCost.WithLabelValues(target, window, tariff.Currency).Add(value)
+	}
+
+	return nil
+}
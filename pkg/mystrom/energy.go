@@ -0,0 +1,43 @@
+package mystrom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// averagePower -- the average power since the previous reading of the exporter. Ws is the average power
+// since the device was last read by anyone, so it's weighted by the time since the exporters own reading
+// instead of being summed up, which keeps the energy right with several readers, across reboots of the
+// device and for any scrape interval. Firmware without Ws falls back to the trapezoidal rule of the power,
+// and to the power of the previous reading for readings more than maxAccountingGap apart, which misses
+// the changes in between
+func (s *targetState) averagePower(report switchReport, elapsed time.Duration) float64 {
+	if !report.missing["Ws"] {
+		return report.WattPerSec
+	}
+	if elapsed > maxAccountingGap {
+		return s.lastPower
+	}
+	return (s.lastPower + report.Power) / 2
+}
+
+// registerEnergyMetrics --
+func registerEnergyMetrics(reg prometheus.Registerer, state targetState, target string) error {
+	collectorEnergy := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "energy_wattseconds_total",
+			Help:      "Energy consumed by devices attached to the switch, accumulated by the exporter from the Ws of the reports",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorEnergy); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "energy_wattseconds_total", err.Error())
+	}
+
+	collectorEnergy.WithLabelValues(target).Add(state.energy)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package mystrom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	// -- when the devices last responded to a scrape or poll
+	lastContact = make(map[string]time.Time)
+
+	evictionsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "mystrom_exporter",
+			Name:      "target_evictions_total",
+			Help:      "Number of targets whose remembered state was dropped to stay below the maximum number of targets",
+		})
+)
+
+// Collectors -- returns the metrics of the device layer to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{evictionsCounter, addressFailoversCounterVec}
+}
+
+// LastContact -- returns when the device last responded to a scrape or poll, zero if it never did
+// or was dropped to stay below the maximum number of targets
+func LastContact(target string) time.Time {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	return lastContact[target]
+}
+
+// contacted -- remembers that the device responded
+func contacted(target string, now time.Time) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	lastContact[target] = now
+}
+
+// Forget -- forgets everything about the target, called once it's dropped from lru.Targets
+func Forget(target string) {
+	log.Infof("dropping state of least recently scraped target '%v'", target)
+	evictionsCounter.Inc()
+
+	statesMutex.Lock()
+	delete(lastContact, target)
+	delete(states, target)
+	delete(switches, target)
+	delete(readings, target)
+	for mac, t := range targetsByMac {
+		if t == target {
+			delete(targetsByMac, mac)
+		}
+	}
+	statesMutex.Unlock()
+
+	capabilityMutex.Lock()
+	delete(capabilitySets, target)
+	capabilityMutex.Unlock()
+
+	unsupportedMutex.Lock()
+	delete(unsupportedTargets, target)
+	unsupportedMutex.Unlock()
+
+	payloadMutex.Lock()
+	delete(payloadLogged, target)
+	payloadMutex.Unlock()
+
+	forgetWifi(target)
+	forgetPreferredFamily(target)
+	forgetInvalidSamples(target)
+	forgetButton(target)
+	forgetHealth(target)
+	forgetBoots(target)
+	forgetModule(target)
+}
@@ -0,0 +1,211 @@
+package mystrom
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// resolutionDelay -- time to wait for the AAAA records once the A records arrived (RFC 8305, section 3)
+const resolutionDelay = 50 * time.Millisecond
+
+var (
+	attemptDelay      = 250 * time.Millisecond
+	attemptDelayMutex sync.Mutex
+
+	// -- the family the name was last connected with, tried first by the next connection
+	preferredFamilies = make(map[string]string)
+)
+
+// SetConnectionAttemptDelay -- sets the delay between the connection attempts to the addresses of a
+// device name, 0 falls back to the dialing of the go runtime
+func SetConnectionAttemptDelay(delay time.Duration) {
+	attemptDelayMutex.Lock()
+	defer attemptDelayMutex.Unlock()
+
+	attemptDelay = delay
+}
+
+// forgetPreferredFamily --
+func forgetPreferredFamily(target string) {
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+
+	attemptDelayMutex.Lock()
+	defer attemptDelayMutex.Unlock()
+
+	delete(preferredFamilies, host)
+}
+
+// dialResult --
+type dialResult struct {
+	conn   net.Conn
+	family string
+	err    error
+}
+
+// lookupResult --
+type lookupResult struct {
+	family string
+	ips    []net.IP
+	err    error
+}
+
+// addressQueue -- the resolved addresses, handed out alternating between the families, IPv6 first
+// unless the other family was the last to connect
+type addressQueue struct {
+	ips  map[string][]net.IP
+	last string
+}
+
+// next --
+func (q *addressQueue) next() (net.IP, bool) {
+	order := []string{"ip6", "ip4"}
+	if q.last == "ip6" {
+		order = []string{"ip4", "ip6"}
+	}
+	for _, family := range order {
+		if len(q.ips[family]) > 0 {
+			ip := q.ips[family][0]
+			q.ips[family] = q.ips[family][1:]
+			q.last = family
+			return ip, true
+		}
+	}
+	return nil, false
+}
+
+// first -- the family handed out first
+func (q *addressQueue) first() string {
+	if q.last == "ip6" {
+		return "ip4"
+	}
+	return "ip6"
+}
+
+// empty --
+func (q *addressQueue) empty() bool {
+	return len(q.ips["ip6"]) == 0 && len(q.ips["ip4"]) == 0
+}
+
+// family --
+func family(ip net.IP) string {
+	if ip.To4() != nil {
+		return "ip4"
+	}
+	return "ip6"
+}
+
+// dialHappyEyeballs -- connects to a device name resolving to several addresses RFC 8305 style: the A and
+// AAAA records are looked up in parallel and the addresses are tried alternating between the families,
+// each attempt started after the attempt delay or once the previous one failed, the first connection wins
+func dialHappyEyeballs(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+
+	attemptDelayMutex.Lock()
+	delay := attemptDelay
+	preferred := preferredFamilies[host]
+	attemptDelayMutex.Unlock()
+
+	if err != nil || net.ParseIP(host) != nil || delay <= 0 || network != "tcp" {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// -- a dialer bound to a source address can only reach its family
+	families := []string{"ip6", "ip4"}
+	if local, ok := dialer.LocalAddr.(*net.TCPAddr); ok && local != nil {
+		if local.IP.To4() != nil {
+			families = []string{"ip4"}
+		} else {
+			families = []string{"ip6"}
+		}
+	}
+
+	lookups := make(chan lookupResult, len(families))
+	for _, family := range families {
+		go func(family string) {
+			ips, err := net.DefaultResolver.LookupIP(ctx, family, host)
+			lookups <- lookupResult{family: family, ips: ips, err: err}
+		}(family)
+	}
+
+	results := make(chan dialResult)
+	attempt := func(ip net.IP) {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		select {
+		case results <- dialResult{conn: conn, family: family(ip), err: err}:
+		case <-ctx.Done():
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+
+	queue := &addressQueue{ips: make(map[string][]net.IP), last: "ip4"}
+	if preferred == "ip4" {
+		queue.last = "ip6"
+	}
+	pendingLookups := len(families)
+	running := 0
+	started := false
+	var lastErr error
+	var nextAttempt, resolutionTimer <-chan time.Time
+
+	for {
+		if started && !queue.empty() && (running == 0 || nextAttempt == nil) {
+			ip, _ := queue.next()
+			running++
+			go attempt(ip)
+			nextAttempt = time.After(delay)
+		}
+		if running == 0 && queue.empty() && pendingLookups == 0 {
+			if lastErr == nil {
+				lastErr = fmt.Errorf("no addresses found for %v", host)
+			}
+			return nil, lastErr
+		}
+
+		select {
+		case lookup := <-lookups:
+			pendingLookups--
+			if lookup.err != nil {
+				lastErr = lookup.err
+			} else {
+				queue.ips[lookup.family] = append(queue.ips[lookup.family], lookup.ips...)
+			}
+			// -- start with the preferred family right away, with the other one only once the preferred
+			// one was given a moment to arrive
+			if !started {
+				if pendingLookups == 0 || (lookup.err == nil && lookup.family == queue.first()) {
+					started = true
+				} else if lookup.err == nil {
+					resolutionTimer = time.After(resolutionDelay)
+				}
+			}
+		case <-resolutionTimer:
+			resolutionTimer = nil
+			started = true
+		case <-nextAttempt:
+			nextAttempt = nil
+		case result := <-results:
+			running--
+			if result.err == nil {
+				attemptDelayMutex.Lock()
+				preferredFamilies[host] = result.family
+				attemptDelayMutex.Unlock()
+				return result.conn, nil
+			}
+			lastErr = result.err
+			nextAttempt = nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
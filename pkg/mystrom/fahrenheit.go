@@ -0,0 +1,48 @@
+package mystrom
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	fahrenheit      bool
+	fahrenheitMutex sync.Mutex
+)
+
+// SetFahrenheit -- additionally exports the temperature in degrees Fahrenheit, Celsius stays the canonical metric
+func SetFahrenheit(enabled bool) {
+	fahrenheitMutex.Lock()
+	defer fahrenheitMutex.Unlock()
+
+	fahrenheit = enabled
+}
+
+// registerFahrenheitMetrics --
+func registerFahrenheitMetrics(reg prometheus.Registerer, celsius float64, target string) error {
+	fahrenheitMutex.Lock()
+	enabled := fahrenheit
+	fahrenheitMutex.Unlock()
+
+	if !enabled {
+		return nil
+	}
+
+	collectorFahrenheit := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "temperature_fahrenheit",
+			Help:      "The currently measured temperature by the switch in degrees Fahrenheit, converted from mystrom_temperature",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorFahrenheit); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "temperature_fahrenheit", err.Error())
+	}
+
+	collectorFahrenheit.WithLabelValues(target).Set(celsius*9/5 + 32)
+
+	return nil
+}
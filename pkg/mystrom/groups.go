@@ -0,0 +1,174 @@
+package mystrom
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// groupCollector -- aggregates the latest readings of the configured devices by their group labels
+type groupCollector struct {
+	power    *prometheus.Desc
+	devices  *prometheus.Desc
+	relaysOn *prometheus.Desc
+}
+
+// groupKey --
+type groupKey struct {
+	label string
+	group string
+}
+
+// groupSum --
+type groupSum struct {
+	power    float64
+	devices  float64
+	relaysOn float64
+}
+
+// NewGroupCollector -- creates the collector exposing the aggregated readings per device group
+func NewGroupCollector(namespace string) prometheus.Collector {
+	labels := []string{"label", "group"}
+	return &groupCollector{
+		power: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "group_power"),
+			"Sum of the latest power readings of the devices in the group",
+			labels, nil),
+		devices: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "group_devices"),
+			"Number of devices in the group with a recent reading",
+			labels, nil),
+		relaysOn: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "group_relays_on"),
+			"Number of devices in the group with the relay turned on",
+			labels, nil),
+	}
+}
+
+// Describe --
+func (c *groupCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.power
+	ch <- c.devices
+	ch <- c.relaysOn
+}
+
+// Collect --
+func (c *groupCollector) Collect(ch chan<- prometheus.Metric) {
+	for key, sum := range aggregateGroups(time.Now()) {
+		ch <- prometheus.MustNewConstMetric(c.power, prometheus.GaugeValue, sum.power, key.label, key.group)
+		ch <- prometheus.MustNewConstMetric(c.devices, prometheus.GaugeValue, sum.devices, key.label, key.group)
+		ch <- prometheus.MustNewConstMetric(c.relaysOn, prometheus.GaugeValue, sum.relaysOn, key.label, key.group)
+	}
+}
+
+// aggregateGroups -- sums up the readings of the configured devices, readings older than
+// maxAccountingGap are left out, but the group is still reported
+func aggregateGroups(now time.Time) map[groupKey]*groupSum {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	result := make(map[groupKey]*groupSum)
+	for _, device := range settings.Devices {
+		state, known := states[device.Target]
+		recent := known && now.Sub(state.lastSeen) <= maxAccountingGap
+
+		for label, group := range device.Groups {
+			key := groupKey{label: label, group: group}
+			sum, ok := result[key]
+			if !ok {
+				sum = &groupSum{}
+				result[key] = sum
+			}
+			if !recent {
+				continue
+			}
+
+			sum.devices++
+			sum.power += state.lastPower
+			if state.lastRelay {
+				sum.relaysOn++
+			}
+		}
+	}
+	return result
+}
+
+// reportReading -- the relay and the plausible temperature of the latest report of a target
+type reportReading struct {
+	relay       bool
+	temperature *float64
+	time        time.Time
+}
+
+// -- the latest reports by target, for the group summaries
+var readings = make(map[string]reportReading)
+
+// GroupSummary -- the aggregated readings of the configured devices of a group, for dashboards
+type GroupSummary struct {
+	Group string `json:"group"`
+	// -- the group labels the group was found under
+	Labels   []string `json:"labels"`
+	Devices  int      `json:"devices"`
+	Down     int      `json:"down"`
+	RelaysOn int      `json:"relays_on"`
+	Power    float64  `json:"power_watts"`
+	// -- null if no device of the group reported a temperature recently
+	MaxTemperature *float64 `json:"max_temperature_celsius"`
+}
+
+// recordReading -- the temperature is nil if the report has none or it's implausible
+func recordReading(target string, relay bool, temperature *float64, now time.Time) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	readings[target] = reportReading{relay: relay, temperature: temperature, time: now}
+}
+
+// SummarizeGroup -- aggregates the configured devices having the group under the label, or under any
+// label if empty; devices without a response within maxAccountingGap are down. False if no configured
+// device is in the group
+func SummarizeGroup(group, label string, now time.Time) (GroupSummary, bool) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	summary := GroupSummary{Group: group, Labels: []string{}}
+	labels := make(map[string]bool)
+	for _, device := range settings.Devices {
+		member := false
+		for l, g := range device.Groups {
+			if g == group && (label == "" || l == label) {
+				member = true
+				labels[l] = true
+			}
+		}
+		if !member {
+			continue
+		}
+
+		summary.Devices++
+		if used, ok := lastContact[device.Target]; !ok || now.Sub(used) > maxAccountingGap {
+			summary.Down++
+			continue
+		}
+		if state, ok := states[device.Target]; ok && now.Sub(state.lastSeen) <= maxAccountingGap {
+			summary.Power += state.lastPower
+		}
+		reading, ok := readings[device.Target]
+		if !ok || now.Sub(reading.time) > maxAccountingGap {
+			continue
+		}
+		if reading.relay {
+			summary.RelaysOn++
+		}
+		if reading.temperature != nil && (summary.MaxTemperature == nil || *reading.temperature > *summary.MaxTemperature) {
+			summary.MaxTemperature = reading.temperature
+		}
+	}
+
+	for l := range labels {
+		summary.Labels = append(summary.Labels, l)
+	}
+	sort.Strings(summary.Labels)
+	return summary, summary.Devices > 0
+}
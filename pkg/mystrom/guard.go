@@ -0,0 +1,42 @@
+package mystrom
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+var (
+	// -- checks the address of every connection to a target, nil accepts all
+	addressGuard      func(target string, ip net.IP) error
+	addressGuardMutex sync.Mutex
+)
+
+// SetAddressGuard -- refuses the connections to a target the guard returns an error for; the guard is
+// given the address actually dialed, so names resolving to another address than when the target was
+// validated are refused as well
+func SetAddressGuard(guard func(target string, ip net.IP) error) {
+	addressGuardMutex.Lock()
+	defer addressGuardMutex.Unlock()
+
+	addressGuard = guard
+}
+
+// guardControl -- the control function of the dialer of the target, checking the address before
+// the connection is opened
+func guardControl(target string) func(network, address string, c syscall.RawConn) error {
+	addressGuardMutex.Lock()
+	guard := addressGuard
+	addressGuardMutex.Unlock()
+
+	if guard == nil {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		return guard(target, net.ParseIP(host))
+	}
+}
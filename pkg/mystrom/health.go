@@ -0,0 +1,89 @@
+package mystrom
+
+import (
+	"sync"
+	"time"
+)
+
+// -- the number of recent scrapes the health is derived from, the number of consecutive failed ones
+// after which a device is down and the age after which the history is unknown again, so devices dropped
+// for being down are retried
+const (
+	healthWindow    = 10
+	healthDownAfter = 3
+	healthMaxAge    = 15 * time.Minute
+)
+
+// the health of a device by its recent scrapes
+const (
+	HealthUnknown  = "unknown"
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// healthHistory -- the outcomes of the recent scrapes of a target, the latest last
+type healthHistory struct {
+	outcomes []bool
+	updated  time.Time
+}
+
+var (
+	healthHistories = make(map[string]*healthHistory)
+	healthMutex     sync.Mutex
+)
+
+// recordHealth -- remembers the outcome of a scrape, the history is dropped with the target from
+// lru.Targets
+func recordHealth(target string, ok bool) {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+
+	history, known := healthHistories[target]
+	if !known {
+		history = &healthHistory{}
+		healthHistories[target] = history
+	}
+	history.outcomes = append(history.outcomes, ok)
+	if len(history.outcomes) > healthWindow {
+		history.outcomes = history.outcomes[len(history.outcomes)-healthWindow:]
+	}
+	history.updated = time.Now()
+}
+
+// Health -- returns the health of the target: down after consecutive failed scrapes, degraded with
+// failed scrapes among the recent ones, ok otherwise and unknown if it wasn't scraped recently
+func Health(target string) string {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+
+	history, known := healthHistories[target]
+	if !known || len(history.outcomes) == 0 || time.Since(history.updated) > healthMaxAge {
+		return HealthUnknown
+	}
+
+	failed, consecutive := 0, 0
+	for _, ok := range history.outcomes {
+		if ok {
+			consecutive = 0
+			continue
+		}
+		failed++
+		consecutive++
+	}
+	switch {
+	case consecutive >= healthDownAfter || consecutive == len(history.outcomes):
+		return HealthDown
+	case failed > 0:
+		return HealthDegraded
+	}
+	return HealthOK
+}
+
+// forgetHealth --
+func forgetHealth(target string) {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+
+	delete(healthHistories, target)
+}
@@ -0,0 +1,42 @@
+package mystrom
+
+import (
+	"fmt"
+	"strings"
+
+	"mystrom-exporter/pkg/inventory"
+)
+
+// NormalizeMac -- converts the different notations of a mac address into upper case hex digits
+// without separators, the notation used by the device api
+func NormalizeMac(mac string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(mac))
+}
+
+// TargetByMac -- returns the target a device with the given mac address was last scraped with
+func TargetByMac(mac string) string {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	return targetsByMac[NormalizeMac(mac)]
+}
+
+// rememberMac --
+func rememberMac(target string, mac string) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	targetsByMac[NormalizeMac(mac)] = target
+}
+
+// observe -- records the successful contact with the device in the inventory
+func observe(target string, info Info) {
+	inventory.Observe(inventory.Device{
+		Mac:      NormalizeMac(info.Mac),
+		IP:       targetHost(target),
+		Target:   target,
+		Type:     fmt.Sprintf("%v", info.SwType),
+		Name:     info.Name,
+		Firmware: info.Version,
+	})
+}
@@ -0,0 +1,120 @@
+package mystrom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Module -- the metrics collected from a kind of device
+type Module struct {
+	// -- whether /report is requested, for the relay
+	Report bool
+	// -- whether the report has the power, with the metrics derived from it
+	Power bool
+	// -- whether the report has the temperature
+	Temperature bool
+	// -- whether /api/v1/device is requested, for the state of bulbs and led strips
+	Bulb bool
+	// -- whether /api/v1/device is requested for the state of a button, which is served while it sleeps
+	Button bool
+	// -- whether /api/v1/sensors is requested, for motion sensors
+	Sensors bool
+}
+
+// modules -- the modules by name, the names of device kinds are selected automatically for them
+var modules = map[string]Module{
+	"switch":      {Report: true, Power: true, Temperature: true},
+	"switch-zero": {Report: true, Temperature: true},
+	"bulb":        {Bulb: true},
+	"led-strip":   {Bulb: true},
+	"button":      {Button: true},
+	"button-plus": {Button: true},
+	"pir":         {Sensors: true},
+	// -- only the general information, for devices without a module of their own
+	"info": {},
+}
+
+var (
+	// -- the module collected from a target by its last scrape
+	selectedModules = make(map[string]string)
+	selectedMutex   sync.Mutex
+)
+
+// ValidateModule -- checks the module is known, an empty one is selected automatically
+func ValidateModule(name string) error {
+	if _, ok := modules[name]; !ok && name != "" {
+		return fmt.Errorf("unknown module '%v', must be one of %v", name, strings.Join(ModuleNames(), ", "))
+	}
+	return nil
+}
+
+// ModuleNames -- the names of the modules, sorted
+func ModuleNames() []string {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectModule -- returns the module for the device type reported in /api/v1/info; devices of an
+// unknown kind are collected as switch if they support /report, otherwise only their information
+func SelectModule(deviceType float64, capabilities map[string]bool) string {
+	if _, ok := modules[DeviceKind(deviceType)]; ok {
+		return DeviceKind(deviceType)
+	}
+	if capabilities["/report"] {
+		return "switch"
+	}
+	return "info"
+}
+
+// WithModule -- collects the metrics of the module instead of selecting it by the device type
+func (e *Exporter) WithModule(name string) *Exporter {
+	e.module = name
+	return e
+}
+
+// DetectModule -- returns the module of the target, the one of its last scrape or else selected by the
+// type the device reports in /api/v1/info, e.g. to tell switches from devices without relay
+func DetectModule(target string) (string, error) {
+	selectedMutex.Lock()
+	name, ok := selectedModules[target]
+	selectedMutex.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	e := NewExporter(target)
+	info, err := e.FetchInfo()
+	if err != nil {
+		return "", err
+	}
+	name = SelectModule(info.SwType, e.capabilities(info.Version))
+	rememberModule(target, name)
+	return name, nil
+}
+
+// HasRelay -- whether the devices of the module have a relay
+func HasRelay(name string) bool {
+	return modules[name].Report
+}
+
+// rememberModule --
+func rememberModule(target, name string) {
+	selectedMutex.Lock()
+	defer selectedMutex.Unlock()
+
+	selectedModules[target] = name
+}
+
+// forgetModule --
+func forgetModule(target string) {
+	selectedMutex.Lock()
+	defer selectedMutex.Unlock()
+
+	delete(selectedModules, target)
+}
@@ -1,15 +1,24 @@
 package mystrom
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/prometheus/common/log"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"mystrom-exporter/pkg/firmware"
+	"mystrom-exporter/pkg/lru"
+	"mystrom-exporter/pkg/storage"
 )
 
 const namespace = "mystrom"
@@ -18,25 +27,60 @@ const namespace = "mystrom"
 const reqTimeout = time.Second * 5
 
 type switchReport struct {
-	Power       float64 `json:"power"`
-	WattPerSec  float64 `json:"Ws"`
-	Relay       bool    `json:"relay"`
-	Temperature float64 `json:"temperature"`
+	Power       float64    `json:"power"`
+	WattPerSec  float64    `json:"Ws"`
+	Relay       bool       `json:"relay"`
+	Temperature float64    `json:"temperature"`
+	Time        deviceTime `json:"time"`
+	// -- only reported by some firmware versions, in seconds
+	Uptime *float64 `json:"uptime"`
+	// -- the measurements reported as null or left out, their metrics are left out
+	missing map[string]bool
 }
 
-type switchInfo struct {
+// Info -- the general information about a device from /api/v1/info
+type Info struct {
 	Version   string  `json:"version"`
 	Mac       string  `json:"mac"`
 	SwType    float64 `json:"type"`
+	Name      string  `json:"name"`
 	SSID      string  `json:"ssid"`
+	IP        string  `json:"ip"`
 	Static    bool    `json:"static"`
 	Connected bool    `json:"connected"`
+	// -- only reported by some firmware versions
+	BSSID      string   `json:"bssid"`
+	Channel    *float64 `json:"channel"`
+	Reconnects *float64 `json:"reconnects"`
+	// -- the wifi signal strength in dBm, named signal or rssi depending on the firmware
+	Signal *float64 `json:"signal"`
+	RSSI   *float64 `json:"rssi"`
+	// -- the time since the boot in seconds
+	Uptime *float64   `json:"uptime"`
+	Time   deviceTime `json:"time"`
+}
+
+// StatusError -- the device answered with an unexpected http status
+type StatusError struct {
+	Path string
+	Code int
+}
+
+// Error --
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %v from %v", e.Code, http.StatusText(e.Code), e.Path)
 }
 
 // Exporter --
 type Exporter struct {
 	myStromSwitchIp string
 	switchType      float64
+	// -- the module collected, selected by the device type if empty
+	module string
+	// -- the host which answered last, devices with several addresses stick to it for the scrape
+	host string
+	// -- the context of the requests, canceling them once done
+	ctx context.Context
 }
 
 // NewExporter --
@@ -46,96 +90,422 @@ func NewExporter(switchIP string) *Exporter {
 	}
 }
 
-// Scrape --
+// Scrape -- requests the device, the implausible samples are dropped and the outcome is remembered for
+// the health of the device unless the scrape was canceled
 func (e *Exporter) Scrape() (prometheus.Gatherer, error) {
+	restore := e.withScrapeTimeout()
+	reg, err := e.scrape()
+	restore()
+	if _, canceled := err.(*CanceledError); canceled {
+		return nil, err
+	}
+	recordHealth(e.myStromSwitchIp, err == nil)
+	if reg == nil {
+		return nil, err
+	}
+	if err != nil {
+		return reg, err
+	}
+	return checkPlausibility(reg, e.myStromSwitchIp)
+}
+
+// scrape --
+func (e *Exporter) scrape() (*prometheus.Registry, error) {
 	reg := prometheus.NewRegistry()
 
-	// --
-	bodyInfo, err := e.fetchData("/api/v1/info")
-	if err != nil {
+	// -- every target tried counts against the maximum number of targets, also the ones never answering
+	lru.Targets.Touch(e.myStromSwitchIp)
+	if err := checkUnsupported(e.myStromSwitchIp); err != nil {
+		return reg, err
 	}
 
-	info := switchInfo{}
-	err = json.Unmarshal(bodyInfo, &info)
+	// --
+	requested := time.Now()
+	info, err := e.FetchInfo()
+	if _, unreachable := err.(*connectError); unreachable {
+		// -- buttons sleep most of the time
+		if asleep, err := asleepButton(e.myStromSwitchIp); asleep != nil || err != nil {
+			return asleep, err
+		}
+	}
 	if err != nil {
-		// fmt.Println(err)
-		// ch <- prometheus.MustNewConstMetric(
-		// 	up, prometheus.GaugeValue, 0,
-		// )
-		return reg, fmt.Errorf("unable to decode switchReport: %v", err.Error())
+		return reg, err
 	}
-	log.Debugf("info: %#v", info)
+	skew, hasSkew := clockSkew(info.Time, requested, time.Now())
 	e.switchType = info.SwType
+	contacted(e.myStromSwitchIp, time.Now())
+	rememberMac(e.myStromSwitchIp, info.Mac)
+	observe(e.myStromSwitchIp, info)
+	capabilities := e.capabilities(info.Version)
+	moduleName := e.module
+	if moduleName == "" {
+		moduleName = SelectModule(info.SwType, capabilities)
+	}
+	module := modules[moduleName]
+
+	// -- the report is requested before the information is exposed, it tells the variant of the switch
+	var report switchReport
+	reportRequested := time.Now()
+	if module.Report && capabilities["/report"] {
+		if report, err = e.fetchReport(); err != nil {
+			return reg, err
+		}
+		// -- switches of an unknown type without power metering are taken as Switch Zero
+		if e.module == "" && moduleName == "switch" && report.missing["power"] && report.missing["Ws"] {
+			moduleName = "switch-zero"
+			module = modules[moduleName]
+		}
+	}
+	rememberModule(e.myStromSwitchIp, moduleName)
 
-	if err := registerInfoMetrics(reg, info, e.myStromSwitchIp); err != nil {
+	if err := registerInfoMetrics(reg, info, e.myStromSwitchIp, moduleName); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	if err := e.registerAddressMetrics(reg); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	if err := registerWifiMetrics(reg, info, e.myStromSwitchIp); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	uptime := info.Uptime
+	if uptime == nil {
+		uptime = report.Uptime
+	}
+	if err := registerUptimeMetrics(reg, uptime, e.myStromSwitchIp); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	if err := registerAnnotationMetrics(reg, storage.Annotations(NormalizeMac(info.Mac)), e.myStromSwitchIp); err != nil {
 		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
 	}
 
 	// --
-	bodyData, err := e.fetchData("/report")
+	if module.Button {
+		return reg, e.scrapeButton(reg, info, capabilities)
+	}
+	if module.Bulb {
+		if err := e.registerClockMetrics(reg, skew, hasSkew); err != nil {
+			return nil, err
+		}
+		return reg, e.scrapeBulb(reg, capabilities)
+	}
+	if module.Sensors {
+		if err := e.registerClockMetrics(reg, skew, hasSkew); err != nil {
+			return nil, err
+		}
+		return reg, e.scrapeSensors(reg, capabilities)
+	}
+	if !module.Report || !capabilities["/report"] {
+		return reg, e.registerClockMetrics(reg, skew, hasSkew)
+	}
+	if !hasSkew {
+		skew, hasSkew = clockSkew(report.Time, reportRequested, time.Now())
+	}
+	if err := e.registerClockMetrics(reg, skew, hasSkew); err != nil {
+		return nil, err
+	}
+
+	if err := registerMetrics(reg, report, e.myStromSwitchIp, module); err != nil {
+		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	var temperature *float64
+	if module.Temperature && !report.missing["temperature"] && plausible("mystrom_temperature", report.Temperature) {
+		temperature = &report.Temperature
+	}
+	recordReading(e.myStromSwitchIp, report.Relay, temperature, time.Now())
+
+	// -- an implausible power would distort the accumulated cost and standby time
+	if module.Power && !report.missing["power"] && plausible("mystrom_power", report.Power) {
+		if err := e.registerDerivedMetrics(reg, report); err != nil {
+			return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+		}
+	}
+
+	if module.Temperature {
+		return reg, e.scrapeTemperature(reg, capabilities)
+	}
+	return reg, nil
+}
+
+// registerClockMetrics -- only for devices reporting their time
+func (e *Exporter) registerClockMetrics(reg prometheus.Registerer, skew float64, hasSkew bool) error {
+	if !hasSkew {
+		return nil
+	}
+	if err := registerClockMetrics(reg, skew, e.myStromSwitchIp); err != nil {
+		return fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return nil
+}
+
+// FetchInfo -- returns the general information about the device
+func (e *Exporter) FetchInfo() (Info, error) {
+	info := Info{}
+
+	bodyInfo, contentType, err := e.fetchResponse("/api/v1/info")
+	if err != nil {
+		return info, err
+	}
+
+	// -- something else than a myStrom device answered, e.g. a web server or a router
+	if strings.HasPrefix(contentType, "text/html") {
+		return info, markUnsupported(e.myStromSwitchIp, "content type "+contentType)
+	}
+	if !json.Valid(bodyInfo) {
+		logPayload(e.myStromSwitchIp, "/api/v1/info", bodyInfo, fmt.Errorf("invalid JSON"))
+		return info, markUnsupported(e.myStromSwitchIp, "response is no valid JSON")
+	}
+
+	if err := json.Unmarshal(bodyInfo, &info); err != nil {
+		logPayload(e.myStromSwitchIp, "/api/v1/info", bodyInfo, err)
+		return info, markUnsupported(e.myStromSwitchIp, fmt.Sprintf("unable to decode switchInfo: %v", err.Error()))
+	}
+	log.Debugf("info: %#v", info)
+
+	return info, nil
+}
+
+// FetchRelay -- returns only the current state of the relay, without touching the accumulated state
+func (e *Exporter) FetchRelay() (bool, error) {
+	report, err := e.fetchReport()
+	if err != nil {
+		return false, err
+	}
+	return report.Relay, nil
+}
+
+// SetRelay -- switches the relay of the switch on or off
+func (e *Exporter) SetRelay(on bool) error {
+	state := "0"
+	if on {
+		state = "1"
+	}
+	// -- only recorded once the device switched, but as of the start of the request, so a poll after
+	// the request doesn't take the change for an external one
+	start := time.Now()
+	if _, err := e.fetchData("/relay?state=" + state); err != nil {
+		return err
+	}
+	recordSwitch(e.myStromSwitchIp, on, start)
+	return nil
+}
+
+// ToggleRelay -- toggles the relay of the switch and returns the new state
+func (e *Exporter) ToggleRelay() (bool, error) {
+	start := time.Now()
+	body, err := e.fetchData("/toggle")
 	if err != nil {
+		return false, err
 	}
 
 	report := switchReport{}
-	err = json.Unmarshal(bodyData, &report)
+	if err := json.Unmarshal(body, &report); err != nil {
+		logPayload(e.myStromSwitchIp, "/toggle", body, err)
+		return false, fmt.Errorf("unable to decode toggle response: %v", err.Error())
+	}
+	recordSwitch(e.myStromSwitchIp, report.Relay, start)
+	return report.Relay, nil
+}
+
+// Reboot -- asks the device to restart
+func (e *Exporter) Reboot() error {
+	_, err := e.fetchData("/api/v1/reboot")
+	return err
+}
+
+// CheckFirmware -- asks the device to look for a firmware update and returns its answer
+func (e *Exporter) CheckFirmware() ([]byte, error) {
+	return e.fetchData("/api/v1/firmware/check")
+}
+
+// FetchSettings -- returns the raw settings document of the device
+func (e *Exporter) FetchSettings() ([]byte, error) {
+	return e.fetchData("/api/v1/settings")
+}
+
+// Fetch -- returns the raw response of the device for the given path
+func (e *Exporter) Fetch(urlpath string) ([]byte, error) {
+	return e.fetchData(urlpath)
+}
+
+// SetName -- stores the name of the device on it, the name shown in the myStrom app
+func (e *Exporter) SetName(name string) error {
+	body, err := json.Marshal(map[string]string{"name": name})
 	if err != nil {
-		// fmt.Println(err)
-		// ch <- prometheus.MustNewConstMetric(
-		// 	up, prometheus.GaugeValue, 0,
-		// )
-		return reg, fmt.Errorf("unable to decode switchReport: %v", err.Error())
+		return fmt.Errorf("unable to encode name: %v", err.Error())
+	}
+	_, _, err = e.send(http.MethodPost, "/api/v1/settings", body)
+	return err
+}
+
+// fetchReport --
+func (e *Exporter) fetchReport() (switchReport, error) {
+	report := switchReport{}
+
+	bodyData, err := e.fetchData("/report")
+	if err != nil {
+		return report, err
+	}
+
+	if err := json.Unmarshal(bodyData, &report); err != nil {
+		logPayload(e.myStromSwitchIp, "/report", bodyData, err)
+		return report, fmt.Errorf("unable to decode switchReport: %v", err.Error())
 	}
 	log.Debugf("report: %#v", report)
 
-	if err := registerMetrics(reg, report, e.myStromSwitchIp, e.switchType); err != nil {
-		return nil, fmt.Errorf("failed to register metrics : %v", err.Error())
+	return report, nil
+}
+
+// registerDerivedMetrics -- metrics calculated from the values remembered between scrapes
+func (e *Exporter) registerDerivedMetrics(reg prometheus.Registerer, report switchReport) error {
+	// -- an implausible Ws is replaced by the power for the energy, like a missing one
+	if !plausible("mystrom_power", report.WattPerSec) {
+		missing := map[string]bool{"Ws": true}
+		for name := range report.missing {
+			missing[name] = true
+		}
+		report.missing = missing
 	}
+	state := updateState(e.myStromSwitchIp, report, time.Now())
 
-	return reg, nil
+	cfg := currentConfig()
+
+	if err := registerCostMetrics(reg, state, cfg.Tariff, e.myStromSwitchIp); err != nil {
+		return err
+	}
+
+	if err := registerEnergyMetrics(reg, state, e.myStromSwitchIp); err != nil {
+		return err
+	}
+
+	return registerStandbyMetrics(reg, state, cfg.Device(e.myStromSwitchIp), e.myStromSwitchIp)
 }
 
 // fetchData -- get the data from the switch under the given path
 func (e *Exporter) fetchData(urlpath string) ([]byte, error) {
-	url := "http://" + e.myStromSwitchIp + urlpath
+	body, _, err := e.fetchResponse(urlpath)
+	return body, err
+}
+
+// fetchResponse -- get the data and its content type from the switch under the given path
+func (e *Exporter) fetchResponse(urlpath string) ([]byte, string, error) {
+	return e.send(http.MethodGet, urlpath, nil)
+}
+
+// send -- requests the switch under the given path with the JSON body, if any, and returns the data
+// and its content type; the addresses of a device are tried in order until one of them can be connected
+func (e *Exporter) send(method, urlpath string, payload []byte) ([]byte, string, error) {
+	scheme, host := splitScheme(e.myStromSwitchIp)
+	hosts := []string{host}
+	if d := currentConfig().Device(e.myStromSwitchIp); d != nil {
+		scheme, hosts = d.Hosts()
+	}
+	if e.host != "" {
+		hosts = []string{e.host}
+	}
+
+	var err error
+	for i, host := range hosts {
+		var body []byte
+		var contentType string
+		body, contentType, err = e.fetchURL(method, scheme+"://"+host, urlpath, payload)
+		if _, connectErr := err.(*connectError); connectErr && i < len(hosts)-1 && e.requestContext().Err() == nil {
+			log.Debugf("target '%v' not reachable at '%v', trying the next address: %v", e.myStromSwitchIp, host, err)
+			addressFailoversCounterVec.WithLabelValues(e.myStromSwitchIp).Inc()
+			continue
+		}
+		if err == nil && len(hosts) > 1 {
+			e.host = host
+		}
+		return body, contentType, err
+	}
+	return []byte{}, "", err
+}
 
+// fetchURL --
+func (e *Exporter) fetchURL(method, baseURL, urlpath string, payload []byte) ([]byte, string, error) {
+	url := baseURL + urlpath
+
+	dialer, err := e.dialer()
+	if err != nil {
+		return []byte{}, "", err
+	}
+	tlsConfig, err := e.tlsConfig()
+	if err != nil {
+		return []byte{}, "", err
+	}
 	switchClient := http.Client{
 		Timeout: reqTimeout,
 		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialHappyEyeballs(ctx, dialer, network, address)
+			},
+			TLSClientConfig:    tlsConfig,
 			DisableCompression: true,
 		},
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(e.requestContext(), method, url, reqBody)
 	if err != nil {
-		// ch <- prometheus.MustNewConstMetric(
-		// 	up, prometheus.GaugeValue, 0,
-		// )
+		return []byte{}, "", fmt.Errorf("unable to create request: %v", err.Error())
 	}
 	req.Header.Set("User-Agent", "myStrom-exporter")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if d := currentConfig().Device(e.myStromSwitchIp); d != nil && d.BasicAuth != nil {
+		username, password, err := d.BasicAuth.Credentials()
+		if err != nil {
+			return []byte{}, "", fmt.Errorf("unable to get credentials: %v", err.Error())
+		}
+		req.SetBasicAuth(username, password)
+	}
+	token, err := e.token()
+	if err != nil {
+		return []byte{}, "", fmt.Errorf("unable to get token: %v", err.Error())
+	}
+	if token != "" {
+		req.Header.Set("Token", token)
+	}
 
 	res, getErr := switchClient.Do(req)
 	if getErr != nil {
-		// ch <- prometheus.MustNewConstMetric(
-		// 	up, prometheus.GaugeValue, 0,
-		// )
+		switch e.requestContext().Err() {
+		case context.Canceled:
+			return []byte{}, "", &CanceledError{Target: e.myStromSwitchIp}
+		case context.DeadlineExceeded:
+			return []byte{}, "", &connectError{fmt.Errorf("i/o timeout, the scrape of target exceeded the timeout")}
+		}
+		if netErr, ok := getErr.(net.Error); ok && netErr.Timeout() {
+			return []byte{}, "", &connectError{fmt.Errorf("i/o timeout while connecting with target: %v", getErr.Error())}
+		}
+		return []byte{}, "", &connectError{fmt.Errorf("unable to connect with target: %v", getErr.Error())}
 	}
-	if res.Body != nil {
-		defer res.Body.Close()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return []byte{}, "", &StatusError{Path: urlpath, Code: res.StatusCode}
 	}
 
 	body, readErr := ioutil.ReadAll(res.Body)
 	if readErr != nil {
+		if e.requestContext().Err() == context.Canceled {
+			return []byte{}, "", &CanceledError{Target: e.myStromSwitchIp}
+		}
 		// ch <- prometheus.MustNewConstMetric(
 		// 	up, prometheus.GaugeValue, 0,
 		// )
-		return []byte{}, fmt.Errorf("unable to read body: %v", readErr.Error())
+		return []byte{}, "", fmt.Errorf("unable to read body: %v", readErr.Error())
 	}
 
-	return body, nil
+	return body, res.Header.Get("Content-Type"), nil
 }
 
 // registerMetrics --
-func registerMetrics(reg prometheus.Registerer, data switchReport, target string, st float64) error {
+func registerMetrics(reg prometheus.Registerer, data switchReport, target string, module Module) error {
 
 	// --
 	collectorRelay := prometheus.NewGaugeVec(
@@ -156,7 +526,7 @@ func registerMetrics(reg prometheus.Registerer, data switchReport, target string
 		collectorRelay.WithLabelValues(target).Set(0)
 	}
 
-	if st != 114 {
+	if module.Power {
 		// --
 		collectorPower := prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -170,8 +540,12 @@ func registerMetrics(reg prometheus.Registerer, data switchReport, target string
 			return fmt.Errorf("failed to register metric %v: %v", "power", err.Error())
 		}
 
-		collectorPower.WithLabelValues(target).Set(data.Power)
+		if !data.missing["power"] {
+			collectorPower.WithLabelValues(target).Set(data.Power)
+		}
+	}
 
+	if module.Temperature {
 		// --
 		collectorTemperature := prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -185,15 +559,20 @@ func registerMetrics(reg prometheus.Registerer, data switchReport, target string
 			return fmt.Errorf("failed to register metric %v: %v", "temperature", err.Error())
 		}
 
-		collectorTemperature.WithLabelValues(target).Set(data.Temperature)
+		if !data.missing["temperature"] {
+			collectorTemperature.WithLabelValues(target).Set(data.Temperature)
 
+			if err := registerFahrenheitMetrics(reg, data.Temperature, target); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
 // registerMetrics --
-func registerInfoMetrics(reg prometheus.Registerer, data switchInfo, target string) error {
+func registerInfoMetrics(reg prometheus.Registerer, data Info, target, module string) error {
 
 	// --
 	collectorInfo := prometheus.NewGaugeVec(
@@ -210,5 +589,77 @@ func registerInfoMetrics(reg prometheus.Registerer, data switchInfo, target stri
 
 	collectorInfo.WithLabelValues(target, data.Version, data.Mac, fmt.Sprintf("%v", data.SwType), data.SSID).Set(1)
 
+	// -- the address the device reports, the one of the target for firmware not reporting it
+	ip := data.IP
+	if ip == "" {
+		ip = targetHost(target)
+	}
+
+	collectorDevice := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "device_info",
+			Help:      "The firmware, kind, mac address, ip address, name and scraped module of the device, always 1",
+		},
+		[]string{"instance", "firmware", "type", "mac", "ip", "name", "module"})
+
+	if err := reg.Register(collectorDevice); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "device_info", err.Error())
+	}
+
+	collectorDevice.WithLabelValues(target, data.Version, DeviceKind(data.SwType), NormalizeMac(data.Mac), ip, data.Name, module).Set(1)
+
+	// --
+	available, known := firmware.UpdateAvailable(fmt.Sprintf("%v", data.SwType), data.Version)
+	if !known {
+		return nil
+	}
+
+	collectorUpdate := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "firmware_update_available",
+			Help:      "Whether a newer firmware than the installed one is available for the device",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorUpdate); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "firmware_update_available", err.Error())
+	}
+
+	if available {
+		collectorUpdate.WithLabelValues(target).Set(1)
+	} else {
+		collectorUpdate.WithLabelValues(target).Set(0)
+	}
+
+	return nil
+}
+
+// registerAnnotationMetrics -- exposes the annotations of the device as labels prefixed with annotation_
+func registerAnnotationMetrics(reg prometheus.Registerer, annotations map[string]string, target string) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	labels := prometheus.Labels{"instance": target}
+	for key, value := range annotations {
+		labels["annotation_"+key] = value
+	}
+
+	collectorAnnotations := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "annotations",
+			Help:        "The annotations of the device kept by the exporter",
+			ConstLabels: labels,
+		})
+
+	if err := reg.Register(collectorAnnotations); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "annotations", err.Error())
+	}
+
+	collectorAnnotations.Set(1)
+
 	return nil
 }
@@ -0,0 +1,170 @@
+package mystrom
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"mystrom-exporter/pkg/lru"
+	"mystrom-exporter/pkg/testutil"
+)
+
+// gauge -- the value of the first sample of the metric, false if there is none
+func gauge(families []*dto.MetricFamily, name string) (float64, bool) {
+	for _, family := range families {
+		if family.GetName() == name && len(family.Metric) > 0 && family.Metric[0].Gauge != nil {
+			return family.Metric[0].Gauge.GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestScrapeFakeDevice(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      testutil.DeviceConfig
+		power       float64
+		hasPower    bool
+		temperature bool
+		invalid     bool
+	}{
+		{
+			name:        "switch",
+			config:      testutil.DeviceConfig{Mac: "64002D000011", Power: 42, Temperature: 21.5, Relay: true},
+			power:       42,
+			hasPower:    true,
+			temperature: true,
+		},
+		{
+			name: "string numbers and rssi",
+			config: testutil.DeviceConfig{Mac: "64002D000012", Power: 12.5, Temperature: 20,
+				Quirks: testutil.Quirks{StringNumbers: true, RSSI: true}},
+			power:       12.5,
+			hasPower:    true,
+			temperature: true,
+		},
+		{
+			name: "switch zero without power",
+			config: testutil.DeviceConfig{Mac: "64002D000013", Type: 999, Temperature: 20,
+				Quirks: testutil.Quirks{NoPower: true}},
+			temperature: true,
+		},
+		{
+			name: "broken temperature sensor",
+			config: testutil.DeviceConfig{Mac: "64002D000014", Power: 5,
+				Quirks: testutil.Quirks{BrokenTemperature: true}},
+			power:    5,
+			hasPower: true,
+		},
+		{
+			name:    "html",
+			config:  testutil.DeviceConfig{Mac: "64002D000015", Quirks: testutil.Quirks{HTML: true}},
+			invalid: true,
+		},
+	}
+	for _, test := range tests {
+		device := testutil.NewDevice(test.config)
+		gatherer, err := NewExporter(device.Target()).Scrape()
+		device.Close()
+
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%v: want an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+			continue
+		}
+		families, err := gatherer.Gather()
+		if err != nil {
+			t.Errorf("%v: unable to gather: %v", test.name, err)
+			continue
+		}
+		if power, ok := gauge(families, "mystrom_power"); ok != test.hasPower || power != test.power {
+			t.Errorf("%v: got power %v (%v), want %v (%v)", test.name, power, ok, test.power, test.hasPower)
+		}
+		if _, ok := gauge(families, "mystrom_temperature"); ok != test.temperature {
+			t.Errorf("%v: got temperature %v, want %v", test.name, ok, test.temperature)
+		}
+	}
+}
+
+func TestRelayFakeDevice(t *testing.T) {
+	device := testutil.NewDevice(testutil.DeviceConfig{Mac: "64002D000016"})
+	defer device.Close()
+
+	exporter := NewExporter(device.Target())
+	if err := exporter.SetRelay(true); err != nil {
+		t.Fatal(err)
+	}
+	if !device.Relay() {
+		t.Errorf("relay not turned on")
+	}
+	if err := exporter.SetName("kitchen"); err != nil {
+		t.Fatal(err)
+	}
+	if device.Name() != "kitchen" {
+		t.Errorf("got name %v, want kitchen", device.Name())
+	}
+}
+
+func TestAddressGuard(t *testing.T) {
+	device := testutil.NewDevice(testutil.DeviceConfig{Mac: "64002D000017"})
+	defer device.Close()
+
+	var checked []string
+	SetAddressGuard(func(target string, ip net.IP) error {
+		checked = append(checked, ip.String())
+		if ip.IsLoopback() {
+			return fmt.Errorf("local address %v isn't allowed", ip)
+		}
+		return nil
+	})
+	defer SetAddressGuard(nil)
+
+	if _, err := NewExporter(device.Target()).FetchInfo(); err == nil {
+		t.Errorf("connection to a refused address was opened")
+	}
+	if len(checked) == 0 || checked[0] != "127.0.0.1" {
+		t.Errorf("got checked addresses %v, want 127.0.0.1", checked)
+	}
+}
+
+func TestForgetEvictedTarget(t *testing.T) {
+	lru.Targets.OnEvict(Forget)
+	lru.Targets.SetMax(1)
+	defer lru.Targets.SetMax(0)
+
+	plug := testutil.NewDevice(testutil.DeviceConfig{Mac: "64002D000018", Power: 5})
+	defer plug.Close()
+	router := testutil.NewDevice(testutil.DeviceConfig{Mac: "64002D000019", Quirks: testutil.Quirks{HTML: true}})
+	defer router.Close()
+
+	if _, err := NewExporter(plug.Target()).Scrape(); err != nil {
+		t.Fatal(err)
+	}
+	if LastContact(plug.Target()).IsZero() || Health(plug.Target()) != HealthOK {
+		t.Fatalf("scrape of %v not remembered", plug.Target())
+	}
+
+	if _, err := NewExporter(router.Target()).Scrape(); err == nil {
+		t.Fatalf("scrape of html answering %v succeeded", router.Target())
+	}
+	if !LastContact(plug.Target()).IsZero() || Health(plug.Target()) != HealthUnknown {
+		t.Errorf("state of evicted %v still remembered", plug.Target())
+	}
+	if checkUnsupported(router.Target()) == nil {
+		t.Fatalf("%v not remembered as unsupported", router.Target())
+	}
+
+	if _, err := NewExporter(plug.Target()).Scrape(); err != nil {
+		t.Fatal(err)
+	}
+	if checkUnsupported(router.Target()) != nil {
+		t.Errorf("evicted %v still remembered as unsupported", router.Target())
+	}
+}
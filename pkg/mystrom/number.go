@@ -0,0 +1,117 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// -- the fields of the payloads some firmware builds report as strings, e.g. "12,5" or "true"
+var (
+	reportNumbers = []string{"power", "Ws", "temperature", "uptime"}
+	reportBools   = []string{"relay"}
+	infoNumbers   = []string{"type", "channel", "reconnects", "signal", "rssi", "uptime"}
+	infoBools     = []string{"static", "connected"}
+)
+
+// UnmarshalJSON -- accepts numbers and booleans as strings and remembers the measurements reported as
+// null or left out, e.g. the power by a Switch Zero
+func (r *switchReport) UnmarshalJSON(data []byte) error {
+	type plain switchReport
+	normalized, err := normalizePayload(data, reportNumbers, reportBools)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(normalized, (*plain)(r)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(normalized, &fields)
+	r.missing = make(map[string]bool)
+	for _, name := range reportNumbers {
+		if value, ok := fields[name]; !ok || string(value) == "null" {
+			r.missing[name] = true
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON -- accepts numbers and booleans as strings
+func (i *Info) UnmarshalJSON(data []byte) error {
+	type plain Info
+	normalized, err := normalizePayload(data, infoNumbers, infoBools)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, (*plain)(i))
+}
+
+// normalizePayload -- replaces the string values of the number and boolean fields of the object by
+// JSON numbers and booleans, empty strings by null; other values are left to the decoder
+func normalizePayload(data []byte, numbers, bools []string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		// -- not an object, the decoder reports it
+		return data, nil
+	}
+
+	changed := false
+	for _, name := range append(append([]string{}, numbers...), bools...) {
+		var value string
+		if err := json.Unmarshal(fields[name], &value); err != nil {
+			continue
+		}
+		isNumber := false
+		for _, number := range numbers {
+			isNumber = isNumber || number == name
+		}
+
+		var normalized interface{}
+		switch {
+		case strings.TrimSpace(value) == "":
+		case isNumber:
+			number, err := parseNumber(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%v' in field %v", value, name)
+			}
+			normalized = number
+		default:
+			flag, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid boolean '%v' in field %v", value, name)
+			}
+			normalized = flag
+		}
+		encoded, err := json.Marshal(normalized)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%v' in field %v", value, name)
+		}
+		fields[name] = encoded
+		changed = true
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(fields)
+}
+
+// parseNumber -- parses a number with a decimal point or comma; with both, the last one is the decimal
+// separator and the other one groups the thousands, several commas alone group the thousands
+func parseNumber(value string) (float64, error) {
+	value = strings.Replace(strings.TrimSpace(value), " ", "", -1)
+
+	commas, points := strings.Count(value, ","), strings.Count(value, ".")
+	switch {
+	case commas > 0 && points > 0 && strings.LastIndex(value, ",") > strings.LastIndex(value, "."):
+		value = strings.Replace(strings.Replace(value, ".", "", -1), ",", ".", 1)
+	case commas > 0 && points > 0:
+		value = strings.Replace(value, ",", "", -1)
+	case commas == 1:
+		value = strings.Replace(value, ",", ".", 1)
+	case commas > 1:
+		value = strings.Replace(value, ",", "", -1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
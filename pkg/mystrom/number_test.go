@@ -0,0 +1,136 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		invalid bool
+	}{
+		{value: "12.5", want: 12.5},
+		{value: "12,5", want: 12.5},
+		{value: " -3 ", want: -3},
+		{value: "1.234,5", want: 1234.5},
+		{value: "1,234.5", want: 1234.5},
+		{value: "1 234,5", want: 1234.5},
+		{value: "1,234,567", want: 1234567},
+		{value: "abc", invalid: true},
+		{value: "12,5W", invalid: true},
+		{value: "1e400", invalid: true},
+	}
+	for _, test := range tests {
+		got, err := parseNumber(test.value)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("parseNumber(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("parseNumber(%q) = %v, %v, want %v", test.value, got, err, test.want)
+		}
+	}
+}
+
+func TestSwitchReportUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		power   float64
+		relay   bool
+		missing []string
+		invalid bool
+	}{
+		{
+			name:    "numbers",
+			payload: `{"power":12.5,"Ws":12.3,"relay":true,"temperature":22.4,"uptime":10}`,
+			power:   12.5,
+			relay:   true,
+		},
+		{
+			name:    "numeric strings",
+			payload: `{"power":"12,5","Ws":"12.3","relay":"true","temperature":"22,4","uptime":"10"}`,
+			power:   12.5,
+			relay:   true,
+		},
+		{
+			name:    "null",
+			payload: `{"power":null,"Ws":null,"relay":false,"temperature":22.4,"uptime":10}`,
+			missing: []string{"power", "Ws"},
+		},
+		{
+			name:    "empty strings",
+			payload: `{"power":"","Ws":" ","relay":false,"temperature":22.4,"uptime":10}`,
+			missing: []string{"power", "Ws"},
+		},
+		{
+			name:    "missing fields",
+			payload: `{"relay":true}`,
+			relay:   true,
+			missing: []string{"power", "Ws", "temperature", "uptime"},
+		},
+		{name: "invalid number", payload: `{"power":"abc","relay":true}`, invalid: true},
+		{name: "invalid boolean", payload: `{"power":1,"relay":"maybe"}`, invalid: true},
+		{name: "out of range string", payload: `{"power":"1e400","relay":true}`, invalid: true},
+		{name: "out of range number", payload: `{"power":1e400,"relay":true}`, invalid: true},
+	}
+	for _, test := range tests {
+		var report switchReport
+		err := json.Unmarshal([]byte(test.payload), &report)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%v: got %+v, want an error", test.name, report)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+			continue
+		}
+		if report.Power != test.power || report.Relay != test.relay {
+			t.Errorf("%v: got power %v and relay %v, want %v and %v", test.name, report.Power, report.Relay,
+				test.power, test.relay)
+		}
+		missing := make(map[string]bool)
+		for _, name := range test.missing {
+			missing[name] = true
+		}
+		if !reflect.DeepEqual(report.missing, missing) {
+			t.Errorf("%v: got missing %v, want %v", test.name, report.missing, missing)
+		}
+	}
+}
+
+func TestInfoUnmarshal(t *testing.T) {
+	var info Info
+	payload := `{"version":"3.82.60","type":"106","rssi":"-61","static":"false","connected":"true"}`
+	if err := json.Unmarshal([]byte(payload), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.SwType != 106 || info.RSSI == nil || *info.RSSI != -61 || info.Static || !info.Connected {
+		t.Errorf("got %+v", info)
+	}
+}
+
+func TestPlausibleTemperature(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  bool
+	}{
+		{22.4, true},
+		{-40, true},
+		{100, true},
+		{-3276.8, false},
+		{100.1, false},
+	}
+	for _, test := range tests {
+		if got := plausible("mystrom_temperature", test.value); got != test.want {
+			t.Errorf("plausible(%v) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mystrom
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// -- the vendor prefixes of the mac addresses of myStrom devices
+var (
+	ouis      = []string{"64002D"}
+	ouisMutex sync.Mutex
+)
+
+// AddOUIs -- accepts the comma separated vendor prefixes as mac addresses of myStrom devices in addition
+// to the known ones, e.g. for devices with a replaced wifi module
+func AddOUIs(list string) error {
+	var added []string
+	for _, oui := range strings.Split(list, ",") {
+		oui = NormalizeMac(strings.TrimSpace(oui))
+		if oui == "" {
+			continue
+		}
+		if _, err := hex.DecodeString(oui); err != nil || len(oui) != 6 {
+			return fmt.Errorf("invalid vendor prefix '%v', must be 3 bytes like 64:00:2D", oui)
+		}
+		added = append(added, oui)
+	}
+
+	ouisMutex.Lock()
+	defer ouisMutex.Unlock()
+
+	ouis = append(ouis, added...)
+	return nil
+}
+
+// IsMystromMac -- whether the mac address, in any notation, has a vendor prefix of myStrom
+func IsMystromMac(mac string) bool {
+	mac = NormalizeMac(mac)
+
+	ouisMutex.Lock()
+	defer ouisMutex.Unlock()
+
+	for _, oui := range ouis {
+		if strings.HasPrefix(mac, oui) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// -- keys whose values are replaced before a payload is logged
+var redactedKeys = map[string]bool{
+	"mac":      true,
+	"ip":       true,
+	"gw":       true,
+	"dns":      true,
+	"mask":     true,
+	"ssid":     true,
+	"name":     true,
+	"token":    true,
+	"password": true,
+}
+
+var (
+	payloadEnabled  bool
+	payloadMaxBytes = 512
+	payloadRedact   = true
+	payloadInterval = time.Minute
+	payloadLogged   = make(map[string]time.Time)
+	payloadMutex    sync.Mutex
+)
+
+// SetPayloadLogging -- enables logging of raw device payloads which failed to parse,
+// only available in binaries built with the 'payloadlog' tag
+func SetPayloadLogging(enabled bool, maxBytes int, redact bool) error {
+	if enabled && !payloadLoggingBuilt {
+		return fmt.Errorf("payload logging isn't available, the exporter must be built with '-tags payloadlog'")
+	}
+	if maxBytes < 0 {
+		return fmt.Errorf("invalid payload size %v", maxBytes)
+	}
+
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+
+	payloadEnabled = enabled
+	payloadMaxBytes = maxBytes
+	payloadRedact = redact
+	return nil
+}
+
+// logPayload -- logs the raw payload of a failed parse, at most once per target and interval
+func logPayload(target, urlpath string, body []byte, parseErr error) {
+	if !payloadLoggingBuilt {
+		return
+	}
+
+	payloadMutex.Lock()
+	if !payloadEnabled {
+		payloadMutex.Unlock()
+		return
+	}
+	now := time.Now()
+	if last, ok := payloadLogged[target]; ok && now.Sub(last) < payloadInterval {
+		payloadMutex.Unlock()
+		return
+	}
+	// -- drop stale entries, so forgotten targets don't keep the map growing
+	for t, last := range payloadLogged {
+		if now.Sub(last) >= payloadInterval {
+			delete(payloadLogged, t)
+		}
+	}
+	payloadLogged[target] = now
+	maxBytes, redact := payloadMaxBytes, payloadRedact
+	payloadMutex.Unlock()
+
+	payload := body
+	if redact {
+		payload = redactPayload(payload)
+	}
+	truncated := ""
+	if maxBytes > 0 && len(payload) > maxBytes {
+		truncated = fmt.Sprintf(" (truncated from %v bytes)", len(payload))
+		payload = payload[:maxBytes]
+	}
+	log.Warnf("failed to parse %v of target '%v': %v, payload%v: %s", urlpath, target, parseErr, truncated, payload)
+}
+
+// redactPayload -- replaces the values of sensitive keys, payloads which are no JSON object are returned unchanged
+func redactPayload(body []byte) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return body
+	}
+	redacted, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue --
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if redactedKeys[key] {
+				v[key] = "REDACTED"
+				continue
+			}
+			v[key] = redactValue(inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+	}
+	return value
+}
@@ -0,0 +1,7 @@
+//go:build !payloadlog
+// +build !payloadlog
+
+package mystrom
+
+// payloadLoggingBuilt -- logging of raw payloads isn't compiled in
+const payloadLoggingBuilt = false
@@ -0,0 +1,7 @@
+//go:build payloadlog
+// +build payloadlog
+
+package mystrom
+
+// payloadLoggingBuilt -- raw payloads of failed parses can be logged
+const payloadLoggingBuilt = true
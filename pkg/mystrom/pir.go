@@ -0,0 +1,119 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// -- the fields of the sensors some firmware builds report as strings
+var (
+	sensorNumbers = []string{"light", "temperature"}
+	sensorBools   = []string{"motion"}
+)
+
+// sensorReport -- the readings of a motion sensor from /api/v1/sensors
+type sensorReport struct {
+	Motion      bool    `json:"motion"`
+	Light       float64 `json:"light"`
+	Temperature float64 `json:"temperature"`
+	// -- the fields reported as null, their metrics are left out
+	nulls map[string]bool
+}
+
+// UnmarshalJSON -- accepts numbers and booleans as strings and remembers the fields reported as null
+func (s *sensorReport) UnmarshalJSON(data []byte) error {
+	type plain sensorReport
+	normalized, err := normalizePayload(data, sensorNumbers, sensorBools)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(normalized, (*plain)(s)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(normalized, &fields)
+	s.nulls = make(map[string]bool)
+	for _, name := range sensorNumbers {
+		if value, ok := fields[name]; !ok || string(value) == "null" {
+			s.nulls[name] = true
+		}
+	}
+	return nil
+}
+
+// fetchSensors --
+func (e *Exporter) fetchSensors() (sensorReport, error) {
+	report := sensorReport{}
+
+	body, err := e.fetchData("/api/v1/sensors")
+	if err != nil {
+		return report, err
+	}
+
+	if err := json.Unmarshal(body, &report); err != nil {
+		logPayload(e.myStromSwitchIp, "/api/v1/sensors", body, err)
+		return report, fmt.Errorf("unable to decode sensorReport: %v", err.Error())
+	}
+	return report, nil
+}
+
+// scrapeSensors -- capabilities detected before /api/v1/sensors was known don't rule it out
+func (e *Exporter) scrapeSensors(reg prometheus.Registerer, capabilities map[string]bool) error {
+	if supported, known := capabilities["/api/v1/sensors"]; known && !supported {
+		return nil
+	}
+	report, err := e.fetchSensors()
+	if err != nil {
+		return err
+	}
+	if err := registerSensorMetrics(reg, report, e.myStromSwitchIp); err != nil {
+		return fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return nil
+}
+
+// registerSensorMetrics --
+func registerSensorMetrics(reg prometheus.Registerer, data sensorReport, target string) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value *float64
+	}{
+		{"motion_detected", "Whether the sensor currently detects motion", boolValue(data.Motion)},
+		{"light_lux", "The ambient light measured by the sensor", &data.Light},
+		{"temperature", "The temperature measured by the sensor", &data.Temperature},
+	}
+	if data.nulls["light"] {
+		gauges[1].value = nil
+	}
+	if data.nulls["temperature"] {
+		gauges[2].value = nil
+	}
+
+	for _, gauge := range gauges {
+		if gauge.value == nil {
+			continue
+		}
+		collector := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      gauge.name,
+				Help:      gauge.help,
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", gauge.name, err.Error())
+		}
+
+		collector.WithLabelValues(target).Set(*gauge.value)
+	}
+
+	if data.nulls["temperature"] {
+		return nil
+	}
+	return registerFahrenheitMetrics(reg, data.Temperature, target)
+}
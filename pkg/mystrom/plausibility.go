@@ -0,0 +1,132 @@
+package mystrom
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	// -- the number of implausible samples by target and metric
+	invalidSamples      = make(map[string]map[string]float64)
+	invalidSamplesMutex sync.Mutex
+)
+
+// checkPlausibility -- gathers the metrics of the scrape and drops the samples outside of the bounds of
+// their metric and those not being a number, they are counted in mystrom_invalid_samples_total
+func checkPlausibility(reg *prometheus.Registry, target string) (prometheus.Gatherer, error) {
+	families, err := reg.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := currentConfig()
+	invalid := make(map[string]float64)
+	checked := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		bounds, bounded := cfg.Bounds(family.GetName())
+		if bounded {
+			invalid[family.GetName()] += 0
+		}
+
+		metrics := family.Metric[:0]
+		for _, metric := range family.Metric {
+			value, ok := sampleValue(family.GetType(), metric)
+			if ok && !bounds.Contains(value) {
+				invalid[family.GetName()]++
+				log.Debugf("dropped implausible sample %v of target '%v': %v", family.GetName(), target, value)
+				continue
+			}
+			metrics = append(metrics, metric)
+		}
+		family.Metric = metrics
+		if len(family.Metric) > 0 {
+			checked = append(checked, family)
+		}
+	}
+
+	counted, err := registerInvalidSamples(invalid, target)
+	if err != nil {
+		return nil, err
+	}
+	checked = append(checked, counted...)
+	sort.Slice(checked, func(i, j int) bool {
+		return checked[i].GetName() < checked[j].GetName()
+	})
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return checked, nil
+	}), nil
+}
+
+// sampleValue -- the value of a gauge, counter or untyped sample
+func sampleValue(metricType dto.MetricType, metric *dto.Metric) (float64, bool) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return metric.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
+
+// registerInvalidSamples -- adds the implausible samples of the scrape to the totals of the target and
+// returns them, for the metrics with bounds or implausible samples
+func registerInvalidSamples(invalid map[string]float64, target string) ([]*dto.MetricFamily, error) {
+	if len(invalid) == 0 {
+		return nil, nil
+	}
+
+	invalidSamplesMutex.Lock()
+	totals, ok := invalidSamples[target]
+	if !ok {
+		totals = make(map[string]float64)
+		invalidSamples[target] = totals
+	}
+	for metric, count := range invalid {
+		totals[metric] += count
+	}
+	snapshot := make(map[string]float64, len(totals))
+	for metric, total := range totals {
+		snapshot[metric] = total
+	}
+	invalidSamplesMutex.Unlock()
+
+	// --
+	reg := prometheus.NewRegistry()
+	collectorInvalid := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invalid_samples_total",
+			Help:      "Number of samples dropped because they were outside of the plausible range of their metric or not a number",
+		},
+		[]string{"instance", "metric"})
+
+	if err := reg.Register(collectorInvalid); err != nil {
+		return nil, fmt.Errorf("failed to register metric %v: %v", "invalid_samples_total", err.Error())
+	}
+
+	for metric, total := range snapshot {
+		collectorInvalid.WithLabelValues(target, metric).Add(total)
+	}
+	return reg.Gather()
+}
+
+// forgetInvalidSamples --
+func forgetInvalidSamples(target string) {
+	invalidSamplesMutex.Lock()
+	defer invalidSamplesMutex.Unlock()
+
+	delete(invalidSamples, target)
+}
+
+// plausible -- whether the value is within the bounds of the metric
+func plausible(metric string, value float64) bool {
+	bounds, _ := currentConfig().Bounds(metric)
+	return bounds.Contains(value)
+}
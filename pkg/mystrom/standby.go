@@ -0,0 +1,67 @@
+package mystrom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// accountStandby -- a device is in standby when the relay is on but the power stays below the
+// configured threshold, the elapsed time is counted when it was in standby at both readings, whatever
+// their distance
+func (s *targetState) accountStandby(device *config.Device, report switchReport, elapsed time.Duration) {
+	if device == nil || device.StandbyThreshold == 0 {
+		return
+	}
+
+	standby := report.Relay && report.Power <= device.StandbyThreshold
+	if standby && s.standby {
+		s.standbySeconds += elapsed.Seconds()
+	}
+	s.standby = standby
+}
+
+// registerStandbyMetrics --
+func registerStandbyMetrics(reg prometheus.Registerer, state targetState, device *config.Device, target string) error {
+	if device == nil || device.StandbyThreshold == 0 {
+		return nil
+	}
+
+	collectorStandby := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "standby",
+			Help:      "Whether the devices attached to the switch are in standby (relay on, power below the configured threshold)",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorStandby); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "standby", err.Error())
+	}
+
+	if state.standby {
+		collectorStandby.WithLabelValues(target).Set(1)
+	} else {
+		collectorStandby.WithLabelValues(target).Set(0)
+	}
+
+	// --
+	collectorStandbySeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "standby_seconds_total",
+			Help:      "Accumulated time the devices attached to the switch spent in standby",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorStandbySeconds); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "standby_seconds_total", err.Error())
+	}
+
+	collectorStandbySeconds.WithLabelValues(target).Add(state.standbySeconds)
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package mystrom
+
+import (
+	"sync"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// maxAccountingGap -- without Ws, readings further apart are accounted at the power of the earlier one
+// instead of the average of both, as what happened in between is unknown
+const maxAccountingGap = 10 * time.Minute
+
+// targetState -- what the exporter remembers about a target between two scrapes
+type targetState struct {
+	created        time.Time
+	lastSeen       time.Time
+	lastPower      float64
+	lastRelay      bool
+	cost           map[string]float64
+	standby        bool
+	standbySeconds float64
+	// -- the consumed energy in Ws
+	energy float64
+}
+
+var (
+	settings     = &config.Config{}
+	states       = make(map[string]*targetState)
+	targetsByMac = make(map[string]string)
+	statesMutex  sync.Mutex
+)
+
+// SetConfig -- configures the settings used for the derived metrics like cost and standby
+func SetConfig(cfg *config.Config) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	settings = cfg
+}
+
+// currentConfig --
+func currentConfig() *config.Config {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	return settings
+}
+
+// CountersCreated -- returns when the counters accumulated by the exporter for the target were
+// created, zero if there are none
+func CountersCreated(target string) time.Time {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	if state, ok := states[target]; ok {
+		return state.created
+	}
+	return time.Time{}
+}
+
+// updateState -- accounts the report against the values accumulated since the previous
+// report of the target and returns a snapshot of the state
+func updateState(target string, report switchReport, now time.Time) targetState {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	state, known := states[target]
+	if !known {
+		state = &targetState{created: now, cost: make(map[string]float64)}
+		states[target] = state
+	}
+
+	var elapsed time.Duration
+	if known && now.After(state.lastSeen) {
+		elapsed = now.Sub(state.lastSeen)
+		// -- a device booted since was without power before, and so were the devices attached to it
+		if report.Uptime != nil && *report.Uptime >= 0 && time.Duration(*report.Uptime*float64(time.Second)) < elapsed {
+			elapsed = time.Duration(*report.Uptime * float64(time.Second))
+		}
+	}
+
+	average := state.averagePower(report, elapsed)
+	state.accountCost(settings.Tariff, average, elapsed, now)
+	state.accountStandby(settings.Device(target), report, elapsed)
+	state.energy += average * elapsed.Seconds()
+
+	state.lastSeen = now
+	state.lastPower = report.Power
+	state.lastRelay = report.Relay
+
+	snapshot := *state
+	snapshot.cost = make(map[string]float64, len(state.cost))
+	for window, value := range state.cost {
+		snapshot.cost[window] = value
+	}
+	return snapshot
+}
@@ -0,0 +1,67 @@
+package mystrom
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+func TestUpdateStateAccountsGaps(t *testing.T) {
+	SetConfig(&config.Config{Tariff: &config.Tariff{Price: 0.3}})
+	defer SetConfig(&config.Config{})
+
+	uptime := 600.0
+	noWs := map[string]bool{"Ws": true}
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		first  switchReport
+		second switchReport
+		gap    time.Duration
+		energy float64
+	}{
+		{
+			name:   "ws over a long gap",
+			first:  switchReport{Power: 100, WattPerSec: 100},
+			second: switchReport{Power: 10, WattPerSec: 50},
+			gap:    30 * time.Minute,
+			energy: 50 * 1800,
+		},
+		{
+			name:   "trapezoid without ws",
+			first:  switchReport{Power: 100, missing: noWs},
+			second: switchReport{Power: 0, missing: noWs},
+			gap:    time.Minute,
+			energy: 50 * 60,
+		},
+		{
+			name:   "last power over a long gap without ws",
+			first:  switchReport{Power: 100, missing: noWs},
+			second: switchReport{Power: 0, missing: noWs},
+			gap:    30 * time.Minute,
+			energy: 100 * 1800,
+		},
+		{
+			name:   "booted during the gap",
+			first:  switchReport{Power: 100, WattPerSec: 100},
+			second: switchReport{Power: 10, WattPerSec: 10, Uptime: &uptime},
+			gap:    30 * time.Minute,
+			energy: 10 * 600,
+		},
+	}
+	for _, test := range tests {
+		target := "state-test-" + test.name
+		updateState(target, test.first, start)
+		state := updateState(target, test.second, start.Add(test.gap))
+
+		if math.Abs(state.energy-test.energy) > 1e-6 {
+			t.Errorf("%v: got energy %v Ws, want %v", test.name, state.energy, test.energy)
+		}
+		if cost := state.cost[config.DefaultTariffWindow]; math.Abs(cost-test.energy/3.6e6*0.3) > 1e-9 {
+			t.Errorf("%v: got cost %v, want %v", test.name, cost, test.energy/3.6e6*0.3)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package mystrom
+
+import (
+	"time"
+)
+
+// switchRecord -- the last relay state set by the exporter
+type switchRecord struct {
+	relay bool
+	time  time.Time
+}
+
+// -- guarded by the states mutex
+var switches = make(map[string]switchRecord)
+
+// recordSwitch -- remembers that the exporter switched the relay of the target by a request started at
+// the given time
+func recordSwitch(target string, relay bool, started time.Time) {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	switches[target] = switchRecord{relay: relay, time: started}
+}
+
+// SwitchedSince -- whether the exporter switched the relay of the target to the given state after the
+// given time, to tell changes by the exporter from the ones by the button of the device or other apps
+func SwitchedSince(target string, relay bool, since time.Time) bool {
+	statesMutex.Lock()
+	defer statesMutex.Unlock()
+
+	record, ok := switches[target]
+	return ok && record.relay == relay && !record.time.Before(since)
+}
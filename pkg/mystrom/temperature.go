@@ -0,0 +1,102 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// -- the fields of /temp some firmware builds report as strings
+var tempNumbers = []string{"measured", "compensation", "compensated"}
+
+var (
+	rawTemperature      bool
+	rawTemperatureMutex sync.Mutex
+)
+
+// tempReport -- the temperature of a switch from /temp, the measured one is heated by the switch itself
+// and compensated by an offset depending on the firmware
+type tempReport struct {
+	Measured     *float64 `json:"measured"`
+	Compensation *float64 `json:"compensation"`
+	Compensated  *float64 `json:"compensated"`
+}
+
+// UnmarshalJSON -- accepts numbers as strings
+func (t *tempReport) UnmarshalJSON(data []byte) error {
+	type plain tempReport
+	normalized, err := normalizePayload(data, tempNumbers, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, (*plain)(t))
+}
+
+// SetRawTemperature -- additionally exports the raw and compensated temperature of /temp, at the cost of
+// another request to the switch per scrape
+func SetRawTemperature(enabled bool) {
+	rawTemperatureMutex.Lock()
+	defer rawTemperatureMutex.Unlock()
+
+	rawTemperature = enabled
+}
+
+// scrapeTemperature -- only for switches supporting /temp
+func (e *Exporter) scrapeTemperature(reg prometheus.Registerer, capabilities map[string]bool) error {
+	rawTemperatureMutex.Lock()
+	enabled := rawTemperature
+	rawTemperatureMutex.Unlock()
+
+	if !enabled || !capabilities["/temp"] {
+		return nil
+	}
+
+	body, err := e.fetchData("/temp")
+	if err != nil {
+		return err
+	}
+	temp := tempReport{}
+	if err := json.Unmarshal(body, &temp); err != nil {
+		logPayload(e.myStromSwitchIp, "/temp", body, err)
+		return fmt.Errorf("unable to decode tempReport: %v", err.Error())
+	}
+
+	if err := registerTemperatureMetrics(reg, temp, e.myStromSwitchIp); err != nil {
+		return fmt.Errorf("failed to register metrics : %v", err.Error())
+	}
+	return nil
+}
+
+// registerTemperatureMetrics -- the values left out by the firmware aren't exported
+func registerTemperatureMetrics(reg prometheus.Registerer, data tempReport, target string) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value *float64
+	}{
+		{"temperature_raw", "The temperature measured by the sensor of the switch, before the compensation of its own heat", data.Measured},
+		{"temperature_compensation", "The offset subtracted from the measured temperature by the firmware of the switch", data.Compensation},
+		{"temperature_compensated", "The measured temperature compensated by the firmware of the switch", data.Compensated},
+	}
+	for _, gauge := range gauges {
+		if gauge.value == nil {
+			continue
+		}
+		collector := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      gauge.name,
+				Help:      gauge.help,
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", gauge.name, err.Error())
+		}
+
+		collector.WithLabelValues(target).Set(*gauge.value)
+	}
+	return nil
+}
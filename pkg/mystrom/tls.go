@@ -0,0 +1,101 @@
+package mystrom
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+)
+
+var (
+	// -- the verification of the certificates of devices reached by https without their own
+	tlsCAFile     string
+	tlsSkipVerify bool
+	// -- the CA bundles by path, read once
+	caPools  = make(map[string]*x509.CertPool)
+	tlsMutex sync.Mutex
+)
+
+// SetTLS -- verifies the certificates of the devices reached by https with the CA bundle, the system roots
+// if empty, or not at all with insecureSkipVerify; devices can override both in the configuration file
+func SetTLS(caFile string, insecureSkipVerify bool) error {
+	if caFile != "" {
+		if _, err := caPool(caFile); err != nil {
+			return err
+		}
+	}
+
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	tlsCAFile, tlsSkipVerify = caFile, insecureSkipVerify
+	return nil
+}
+
+// tlsConfig -- the client configuration for the target, the settings of its device take precedence
+func (e *Exporter) tlsConfig() (*tls.Config, error) {
+	tlsMutex.Lock()
+	caFile, skipVerify := tlsCAFile, tlsSkipVerify
+	tlsMutex.Unlock()
+
+	serverName := ""
+	if d := currentConfig().Device(e.myStromSwitchIp); d != nil && d.TLS != nil {
+		if d.TLS.CAFile != "" {
+			caFile = d.TLS.CAFile
+		}
+		skipVerify = skipVerify || d.TLS.InsecureSkipVerify
+		serverName = d.TLS.ServerName
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: skipVerify, ServerName: serverName}
+	if caFile != "" {
+		pool, err := caPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// caPool --
+func caPool(path string) (*x509.CertPool, error) {
+	tlsMutex.Lock()
+	defer tlsMutex.Unlock()
+
+	if pool, ok := caPools[path]; ok {
+		return pool, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA bundle: %v", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificate found in CA bundle %v", path)
+	}
+	caPools[path] = pool
+	return pool, nil
+}
+
+// splitScheme -- the scheme and the host of a target, which may be given as https://host[:port]
+func splitScheme(target string) (string, string) {
+	for _, scheme := range []string{"http", "https"} {
+		if strings.HasPrefix(target, scheme+"://") {
+			return scheme, strings.TrimPrefix(target, scheme+"://")
+		}
+	}
+	return "http", target
+}
+
+// targetHost -- the host of the target without scheme and port
+func targetHost(target string) string {
+	_, host := splitScheme(target)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,38 @@
+package mystrom
+
+import (
+	"sync"
+
+	"mystrom-exporter/pkg/config"
+)
+
+var (
+	// -- the file with the token sent to the devices without one of their own
+	tokenFile      string
+	tokenFileMutex sync.Mutex
+)
+
+// SetTokenFile -- sends the token in the file to the devices protecting their api, unless the configuration
+// of a device has its own; the file is read on every request to pick up rotated tokens
+func SetTokenFile(path string) {
+	tokenFileMutex.Lock()
+	defer tokenFileMutex.Unlock()
+
+	tokenFile = path
+}
+
+// token -- the token of the target, empty without one
+func (e *Exporter) token() (string, error) {
+	if d := currentConfig().Device(e.myStromSwitchIp); d != nil && (d.Token != "" || d.TokenFile != "") {
+		return d.APIToken()
+	}
+
+	tokenFileMutex.Lock()
+	path := tokenFile
+	tokenFileMutex.Unlock()
+
+	if path == "" {
+		return "", nil
+	}
+	return config.ReadToken(path)
+}
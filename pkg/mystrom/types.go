@@ -0,0 +1,57 @@
+package mystrom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// KnownEndpoints -- the device endpoints the exporter knows how to use
+var KnownEndpoints = []string{"/api/v1/info", "/report", "/temp", "/api/v1/settings", "/api/v1/device",
+	"/api/v1/sensors"}
+
+// deviceKinds -- the kind of device by the type reported in /api/v1/info
+var deviceKinds = map[float64]string{
+	101: "switch",
+	102: "bulb",
+	103: "button-plus",
+	104: "button",
+	105: "led-strip",
+	106: "switch",
+	107: "switch",
+	110: "pir",
+	112: "gateway",
+	113: "modulo",
+	114: "switch-zero",
+	118: "button-plus",
+	120: "switch-zero",
+}
+
+// DeviceKind -- returns the kind of device for the type reported in /api/v1/info
+func DeviceKind(deviceType float64) string {
+	if kind, ok := deviceKinds[deviceType]; ok {
+		return kind
+	}
+	return "unknown"
+}
+
+// EndpointResult -- the outcome of requesting a single endpoint of a device
+type EndpointResult struct {
+	Path     string
+	Err      error
+	Duration time.Duration
+}
+
+// Probe -- requests every known endpoint of the device and checks the answer is JSON
+func (e *Exporter) Probe() []EndpointResult {
+	results := make([]EndpointResult, 0, len(KnownEndpoints))
+	for _, path := range KnownEndpoints {
+		start := time.Now()
+		body, err := e.fetchData(path)
+		if err == nil && !json.Valid(body) {
+			err = fmt.Errorf("response is no valid JSON")
+		}
+		results = append(results, EndpointResult{Path: path, Err: err, Duration: time.Since(start)})
+	}
+	return results
+}
@@ -0,0 +1,65 @@
+package mystrom
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// UnsupportedError -- the target answered, but isn't a myStrom device
+type UnsupportedError struct {
+	Target string
+	Reason string
+	Until  time.Time
+}
+
+// Error --
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("target '%v' is no supported myStrom device (%v), not retried until %v",
+		e.Target, e.Reason, e.Until.Format(time.RFC3339))
+}
+
+var (
+	unsupportedTTL     = 15 * time.Minute
+	unsupportedTargets = make(map[string]*UnsupportedError)
+	unsupportedMutex   sync.Mutex
+)
+
+// SetUnsupportedTTL -- sets how long a target which isn't a myStrom device is remembered, 0 disables it
+func SetUnsupportedTTL(ttl time.Duration) {
+	unsupportedMutex.Lock()
+	defer unsupportedMutex.Unlock()
+
+	unsupportedTTL = ttl
+}
+
+// checkUnsupported -- returns the remembered error if the target was found not to be a myStrom device
+func checkUnsupported(target string) error {
+	unsupportedMutex.Lock()
+	defer unsupportedMutex.Unlock()
+
+	unsupported, ok := unsupportedTargets[target]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(unsupported.Until) {
+		delete(unsupportedTargets, target)
+		return nil
+	}
+	return unsupported
+}
+
+// markUnsupported -- remembers that the target isn't a myStrom device
+func markUnsupported(target, reason string) error {
+	unsupportedMutex.Lock()
+	defer unsupportedMutex.Unlock()
+
+	unsupported := &UnsupportedError{Target: target, Reason: reason, Until: time.Now().Add(unsupportedTTL)}
+	if unsupportedTTL > 0 {
+		log.Warnf("target '%v' is no myStrom device: %v", target, reason)
+		unsupportedTargets[target] = unsupported
+	}
+	return unsupported
+}
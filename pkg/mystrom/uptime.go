@@ -0,0 +1,90 @@
+package mystrom
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bootTolerance -- the boot time derived from the uptime jitters with the latency of the requests, a
+// later boot time only counts as reboot beyond this tolerance
+const bootTolerance = time.Minute
+
+// bootState -- the boot time of a device last seen and the reboots since the exporter knows it
+type bootState struct {
+	boot  time.Time
+	boots float64
+}
+
+var (
+	bootStates = make(map[string]*bootState)
+	bootMutex  sync.Mutex
+)
+
+// updateBoots -- returns the number of reboots of the target seen by the exporter, a boot time derived
+// from the uptime later than the previous one is a reboot, e.g. after a power cut
+func updateBoots(target string, uptime float64, now time.Time) float64 {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+
+	boot := now.Add(-time.Duration(uptime * float64(time.Second)))
+	state, known := bootStates[target]
+	if !known {
+		state = &bootState{boot: boot}
+		bootStates[target] = state
+	}
+	if boot.Sub(state.boot) > bootTolerance {
+		state.boots++
+	}
+	state.boot = boot
+	return state.boots
+}
+
+// forgetBoots --
+func forgetBoots(target string) {
+	bootMutex.Lock()
+	defer bootMutex.Unlock()
+
+	delete(bootStates, target)
+}
+
+// registerUptimeMetrics -- only for firmware reporting the uptime
+func registerUptimeMetrics(reg prometheus.Registerer, uptime *float64, target string) error {
+	if uptime == nil {
+		return nil
+	}
+
+	// --
+	collectorUptime := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "device_uptime_seconds",
+			Help:      "Time since the boot of the device",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorUptime); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "device_uptime_seconds", err.Error())
+	}
+
+	collectorUptime.WithLabelValues(target).Set(*uptime)
+
+	// --
+	collectorBoots := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "device_boots_total",
+			Help:      "Number of reboots of the device seen by the exporter, detected by the uptime starting over",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorBoots); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "device_boots_total", err.Error())
+	}
+
+	collectorBoots.WithLabelValues(target).Add(updateBoots(target, *uptime, time.Now()))
+
+	return nil
+}
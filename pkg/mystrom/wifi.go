@@ -0,0 +1,142 @@
+package mystrom
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// wifiState -- the network a device was last connected to, to count roaming between scrapes
+type wifiState struct {
+	ssid    string
+	bssid   string
+	changes float64
+}
+
+var (
+	wifiStates = make(map[string]*wifiState)
+	wifiMutex  sync.Mutex
+)
+
+// updateWifi -- returns the number of network changes seen for the target, a change of the
+// ssid or the access point (bssid) counts as one
+func updateWifi(target string, info Info) float64 {
+	wifiMutex.Lock()
+	defer wifiMutex.Unlock()
+
+	state, known := wifiStates[target]
+	if !known {
+		state = &wifiState{ssid: info.SSID, bssid: info.BSSID}
+		wifiStates[target] = state
+	}
+	if state.ssid != info.SSID || state.bssid != info.BSSID {
+		state.changes++
+		state.ssid = info.SSID
+		state.bssid = info.BSSID
+	}
+	return state.changes
+}
+
+// forgetWifi --
+func forgetWifi(target string) {
+	wifiMutex.Lock()
+	defer wifiMutex.Unlock()
+
+	delete(wifiStates, target)
+}
+
+// registerWifiMetrics -- the channel, signal strength and reconnects are only exported when the firmware
+// reports them
+func registerWifiMetrics(reg prometheus.Registerer, data Info, target string) error {
+	if data.SSID == "" {
+		return nil
+	}
+
+	// --
+	collectorInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "wifi_info",
+			Help:      "The wifi network the device is connected to",
+		},
+		[]string{"instance", "ssid", "bssid"})
+
+	if err := reg.Register(collectorInfo); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "wifi_info", err.Error())
+	}
+
+	collectorInfo.WithLabelValues(target, data.SSID, data.BSSID).Set(1)
+
+	// --
+	collectorChanges := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "wifi_network_changes_total",
+			Help:      "Number of changes of the ssid or access point of the device seen by the exporter between scrapes",
+		},
+		[]string{"instance"})
+
+	if err := reg.Register(collectorChanges); err != nil {
+		return fmt.Errorf("failed to register metric %v: %v", "wifi_network_changes_total", err.Error())
+	}
+
+	collectorChanges.WithLabelValues(target).Add(updateWifi(target, data))
+
+	// --
+	if data.Channel != nil {
+		collectorChannel := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "wifi_channel",
+				Help:      "The wifi channel the device is connected on",
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collectorChannel); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", "wifi_channel", err.Error())
+		}
+
+		collectorChannel.WithLabelValues(target).Set(*data.Channel)
+	}
+
+	// --
+	rssi := data.Signal
+	if rssi == nil {
+		rssi = data.RSSI
+	}
+	if rssi != nil {
+		collectorRSSI := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "wifi_rssi_dbm",
+				Help:      "The strength of the wifi signal received by the device",
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collectorRSSI); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", "wifi_rssi_dbm", err.Error())
+		}
+
+		collectorRSSI.WithLabelValues(target).Set(*rssi)
+	}
+
+	// --
+	if data.Reconnects != nil {
+		collectorReconnects := prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "wifi_reconnects_total",
+				Help:      "Number of wifi reconnects reported by the device since its boot",
+			},
+			[]string{"instance"})
+
+		if err := reg.Register(collectorReconnects); err != nil {
+			return fmt.Errorf("failed to register metric %v: %v", "wifi_reconnects_total", err.Error())
+		}
+
+		collectorReconnects.WithLabelValues(target).Add(*data.Reconnects)
+	}
+
+	return nil
+}
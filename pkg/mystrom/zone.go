@@ -0,0 +1,48 @@
+package mystrom
+
+import (
+	"fmt"
+	"net"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// dialer -- returns the dialer for the requests to the device, bound to the source address of its zone
+// and refusing the addresses rejected by the address guard
+func (e *Exporter) dialer() (*net.Dialer, error) {
+	d := &net.Dialer{Timeout: reqTimeout, Control: guardControl(e.myStromSwitchIp)}
+
+	zone := currentConfig().Zone(e.myStromSwitchIp)
+	if zone == nil {
+		return d, nil
+	}
+	ip, err := sourceAddress(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine source address of zone %v: %v", zone.Name, err.Error())
+	}
+	d.LocalAddr = &net.TCPAddr{IP: ip}
+	return d, nil
+}
+
+// sourceAddress -- the configured address or the first IPv4 address of the configured interface,
+// looked up on every request as the address of the interface may change
+func sourceAddress(zone *config.Zone) (net.IP, error) {
+	if zone.SourceAddress != "" {
+		return net.ParseIP(zone.SourceAddress), nil
+	}
+
+	iface, err := net.InterfaceByName(zone.Interface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %v has no IPv4 address", zone.Interface)
+}
@@ -0,0 +1,130 @@
+package poller
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// -- a power reading differing from the previous one by more than both is a change
+	adaptiveMinWatts = 1.0
+	adaptiveMinShare = 0.1
+	// -- the factors of the interval after a poll with and without a change
+	adaptiveSpeedup  = 0.5
+	adaptiveSlowdown = 1.25
+	// -- the shortest interval allowed
+	adaptiveMinPeriod = time.Second
+)
+
+// adaptiveState -- the readings of the last poll of a target and its current interval
+type adaptiveState struct {
+	readings map[string]float64
+	interval time.Duration
+}
+
+var (
+	// -- the bounds of the adapted intervals, 0 disables the adaption
+	adaptiveMin time.Duration
+	adaptiveMax time.Duration
+
+	adaptiveStates = make(map[string]*adaptiveState)
+	adaptiveMutex  sync.Mutex
+
+	intervalGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "poll_interval_seconds",
+			Help:      "The current interval of the polls of the target, adapted to the changes of its readings",
+		},
+		[]string{"target"})
+)
+
+// SetAdaptive -- polls targets with changing readings more often, down to the min interval, and stable ones
+// less often, up to the max interval; both 0 disable the adaption
+func SetAdaptive(min, max time.Duration) error {
+	if min == 0 && max == 0 {
+		return nil
+	}
+	if min < adaptiveMinPeriod || max < min {
+		return fmt.Errorf("the intervals must be at least %v and min must not exceed max", adaptiveMinPeriod)
+	}
+	adaptiveMin, adaptiveMax = min, max
+	return nil
+}
+
+// adapt -- halves the interval of the target if the power or relay changed since the last poll, else
+// lengthens it by a quarter
+func adapt(target string, families []*dto.MetricFamily, interval time.Duration) {
+	if adaptiveMax == 0 {
+		return
+	}
+
+	readings := make(map[string]float64)
+	for _, family := range families {
+		if (family.GetName() == "mystrom_power" || family.GetName() == "mystrom_relay") &&
+			len(family.Metric) > 0 && family.Metric[0].Gauge != nil {
+			readings[family.GetName()] = family.Metric[0].Gauge.GetValue()
+		}
+	}
+
+	adaptiveMutex.Lock()
+	defer adaptiveMutex.Unlock()
+
+	state, ok := adaptiveStates[target]
+	if !ok {
+		state = &adaptiveState{interval: clampInterval(interval)}
+		adaptiveStates[target] = state
+	} else if changed(state.readings, readings) {
+		state.interval = clampInterval(time.Duration(float64(state.interval) * adaptiveSpeedup))
+	} else {
+		state.interval = clampInterval(time.Duration(float64(state.interval) * adaptiveSlowdown))
+	}
+	state.readings = readings
+	intervalGaugeVec.WithLabelValues(target).Set(state.interval.Seconds())
+}
+
+// changed -- whether the relay switched or the power changed noticeably
+func changed(previous, current map[string]float64) bool {
+	if previous["mystrom_relay"] != current["mystrom_relay"] {
+		return true
+	}
+	before, after := previous["mystrom_power"], current["mystrom_power"]
+	delta := math.Abs(after - before)
+	return delta > adaptiveMinWatts && delta > adaptiveMinShare*math.Abs(before)
+}
+
+// clampInterval --
+func clampInterval(interval time.Duration) time.Duration {
+	if interval < adaptiveMin {
+		return adaptiveMin
+	}
+	if interval > adaptiveMax {
+		return adaptiveMax
+	}
+	return interval
+}
+
+// currentInterval -- the adapted interval of the target, the given one without adaption
+func currentInterval(target string, interval time.Duration) time.Duration {
+	adaptiveMutex.Lock()
+	defer adaptiveMutex.Unlock()
+
+	if state, ok := adaptiveStates[target]; ok {
+		return state.interval
+	}
+	return interval
+}
+
+// forgetAdaptive --
+func forgetAdaptive(target string) {
+	adaptiveMutex.Lock()
+	defer adaptiveMutex.Unlock()
+
+	delete(adaptiveStates, target)
+	intervalGaugeVec.DeleteLabelValues(target)
+}
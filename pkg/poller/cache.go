@@ -0,0 +1,34 @@
+package poller
+
+import (
+	"mystrom-exporter/pkg/sink"
+)
+
+// cache -- the sink keeping the last poll of every polled target for the scrapes
+type cache struct{}
+
+// Cache -- returns the sink serving the polls to the scrapes, see Gatherer
+func Cache() sink.Sink {
+	return cache{}
+}
+
+// Name --
+func (cache) Name() string {
+	return "prometheus"
+}
+
+// Write -- keeps the poll unless the target stopped being polled meanwhile
+func (cache) Write(batch sink.Batch) error {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+
+	if _, ok := stops[batch.Target]; ok {
+		results[batch.Target] = &pollResult{families: batch.Families, time: batch.Time}
+	}
+	return nil
+}
+
+// Close --
+func (cache) Close() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package poller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// powerHistogramVec -- the distribution of the polled power readings, nil if disabled
+var powerHistogramVec *prometheus.HistogramVec
+
+// ParseBuckets -- parses a comma separated list of bucket upper bounds, sorted ascending
+func ParseBuckets(value string) ([]float64, error) {
+	buckets := []float64{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(entry, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket '%v': %v", entry, err.Error())
+		}
+		buckets = append(buckets, bound)
+	}
+	sort.Float64s(buckets)
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return nil, fmt.Errorf("duplicate bucket '%v'", buckets[i])
+		}
+	}
+	return buckets, nil
+}
+
+// SetPowerBuckets -- enables the histogram of the polled power readings with the given buckets,
+// must be called before the collectors are registered; no buckets disable it
+func SetPowerBuckets(buckets []float64) {
+	if len(buckets) == 0 {
+		powerHistogramVec = nil
+		return
+	}
+
+	powerHistogramVec = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "polled_power_watts",
+			Help:      "Distribution of the power readings of the polled devices, e.g. for load-duration curves",
+			Buckets:   buckets,
+		},
+		[]string{"target"})
+}
+
+// observePower -- adds the power reading of a poll to the histogram
+func observePower(target string, families []*dto.MetricFamily) {
+	if powerHistogramVec == nil {
+		return
+	}
+
+	for _, family := range families {
+		if family.GetName() != "mystrom_power" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if metric.Gauge != nil {
+				powerHistogramVec.WithLabelValues(target).Observe(metric.Gauge.GetValue())
+			}
+		}
+	}
+}
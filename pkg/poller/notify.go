@@ -0,0 +1,70 @@
+package poller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+var (
+	relayWebhook *config.RelayWebhook
+
+	webhookCallsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "relay_webhook_calls_total",
+			Help:      "Number of calls of the relay webhook by result",
+		},
+		[]string{"result"})
+)
+
+// SetRelayWebhook -- calls the webhook with the relay changes seen by the relay poller, nil disables it
+func SetRelayWebhook(webhook *config.RelayWebhook) {
+	relayWebhook = webhook
+}
+
+// notifyWebhook -- posts the event to the webhook without blocking the poller
+func notifyWebhook(event RelayEvent) {
+	if relayWebhook == nil || (!event.External && !relayWebhook.All) {
+		return
+	}
+	webhook := relayWebhook
+
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Errorf("unable to encode the relay change of target '%v': %v", event.Target, err)
+			return
+		}
+		req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+		if err != nil {
+			log.Errorf("unable to create the relay webhook request: %v", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "myStrom-exporter")
+		for key, value := range webhook.Headers {
+			req.Header.Set(key, value)
+		}
+
+		client := http.Client{Timeout: webhook.Timeout}
+		res, err := client.Do(req)
+		if err != nil {
+			webhookCallsCounterVec.WithLabelValues("error").Inc()
+			log.Errorf("failed to call the relay webhook for target '%v': %v", event.Target, err)
+			return
+		}
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			webhookCallsCounterVec.WithLabelValues("error").Inc()
+			log.Errorf("relay webhook for target '%v' answered with %v", event.Target, res.Status)
+			return
+		}
+		webhookCallsCounterVec.WithLabelValues("ok").Inc()
+	}()
+}
@@ -0,0 +1,218 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/budget"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/sink"
+)
+
+const namespace = "mystrom_exporter"
+
+// pollResult -- the metrics of the last successful poll of a target
+type pollResult struct {
+	families []*dto.MetricFamily
+	time     time.Time
+}
+
+var (
+	results      = make(map[string]*pollResult)
+	resultsMutex sync.Mutex
+	// -- closed to stop the polling loop of a single target
+	stops = make(map[string]chan struct{})
+
+	// -- closed to stop all polling loops
+	stopping = make(chan struct{})
+	stopOnce sync.Once
+	running  sync.WaitGroup
+
+	pollsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "polls_total",
+			Help:      "Number of polls of the targets by target and result",
+		},
+		[]string{"target", "result"})
+)
+
+// Collectors -- returns the metrics of the poller to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	if powerHistogramVec != nil {
+		return []prometheus.Collector{pollsCounterVec, shedCounterVec, intervalGaugeVec, webhookCallsCounterVec, powerHistogramVec}
+	}
+	return []prometheus.Collector{pollsCounterVec, shedCounterVec, intervalGaugeVec, webhookCallsCounterVec}
+}
+
+// Initialize -- starts polling the metrics of the given targets in the given interval
+func Initialize(targets []string, interval time.Duration) {
+	resultsMutex.Lock()
+	defer resultsMutex.Unlock()
+
+	for _, target := range targets {
+		if _, ok := results[target]; ok {
+			continue
+		}
+		results[target] = nil
+		stops[target] = make(chan struct{})
+		running.Add(1)
+		go poll(target, interval, stops[target])
+	}
+}
+
+// Remove -- stops polling the metrics and the relay state of the target and drops its last poll
+func Remove(target string) {
+	resultsMutex.Lock()
+	if stop, ok := stops[target]; ok {
+		close(stop)
+		delete(stops, target)
+		delete(results, target)
+	}
+	resultsMutex.Unlock()
+
+	relayMutex.Lock()
+	if state, ok := relayTargets[target]; ok {
+		close(state.stop)
+		delete(relayTargets, target)
+	}
+	relayMutex.Unlock()
+
+	forgetSmoothed(target)
+	forgetAdaptive(target)
+	Forget(target)
+}
+
+// Forget -- drops the metric series of the target, when it's no longer polled or dropped from lru.Targets
+func Forget(target string) {
+	for _, result := range []string{"ok", "error", "shed", "budget_exceeded"} {
+		pollsCounterVec.DeleteLabelValues(target, result)
+	}
+	if powerHistogramVec != nil {
+		powerHistogramVec.DeleteLabelValues(target)
+	}
+}
+
+// Stop -- stops all polling loops and waits for the polls in flight until the context is done,
+// the subscribers waiting for relay changes are released
+func Stop(ctx context.Context) error {
+	stopOnce.Do(func() {
+		close(stopping)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Gatherer -- returns the metrics of the last poll of the target if it isn't older than maxAge,
+// optionally with the time of the poll as timestamp of the samples; nil if there is no such poll
+func Gatherer(target string, maxAge time.Duration, timestamps bool) prometheus.Gatherer {
+	resultsMutex.Lock()
+	result := results[target]
+	resultsMutex.Unlock()
+
+	if result == nil || time.Since(result.time) > maxAge {
+		return nil
+	}
+	if !timestamps {
+		return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return result.families, nil
+		})
+	}
+
+	// -- the cached families are shared, the timestamps are set on a copy
+	timestampMs := proto.Int64(result.time.UnixNano() / int64(time.Millisecond))
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families := make([]*dto.MetricFamily, 0, len(result.families))
+		for _, family := range result.families {
+			family = proto.Clone(family).(*dto.MetricFamily)
+			for _, metric := range family.Metric {
+				metric.TimestampMs = timestampMs
+			}
+			families = append(families, family)
+		}
+		return families, nil
+	})
+}
+
+// poll -- polls a single target until the poller or the target is stopped, the interval is adapted to
+// the changes of its readings if enabled
+func poll(target string, interval time.Duration, stop <-chan struct{}) {
+	defer running.Done()
+
+	for {
+		start := time.Now()
+		pollOnce(target, currentInterval(target, interval))
+
+		timer := time.NewTimer(currentInterval(target, interval) - time.Since(start))
+		select {
+		case <-stopping:
+			timer.Stop()
+			return
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// pollOnce -- a poll waits at most the interval for a free slot, the next one is due then
+func pollOnce(target string, interval time.Duration) {
+	if err := budget.Check(target); err != nil {
+		pollsCounterVec.WithLabelValues(target, "budget_exceeded").Inc()
+		log.Debug(err)
+		return
+	}
+
+	class := priority(target)
+	if acquired, reason := acquire(class, interval); !acquired {
+		if reason != "" {
+			pollsCounterVec.WithLabelValues(target, "shed").Inc()
+			shedCounterVec.WithLabelValues(class, reason).Inc()
+			log.Debugf("skipped poll of target '%v' of %v priority: %v", target, class, reason)
+		}
+		return
+	}
+	defer release()
+
+	start := time.Now()
+	families, err := scrape(target)
+	budget.Account(target, time.Since(start))
+	if err != nil {
+		pollsCounterVec.WithLabelValues(target, "error").Inc()
+		log.Errorf("failed to poll target '%v': %v", target, err)
+		return
+	}
+	pollsCounterVec.WithLabelValues(target, "ok").Inc()
+	adapt(target, families, interval)
+	observePower(target, families)
+	families = smooth(target, families)
+
+	sink.Publish(sink.Batch{Target: target, Time: start, Families: families})
+}
+
+// scrape --
+func scrape(target string) ([]*dto.MetricFamily, error) {
+	gatherer, err := mystrom.NewExporter(target).Scrape()
+	if err != nil {
+		return nil, err
+	}
+	return gatherer.Gather()
+}
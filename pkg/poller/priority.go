@@ -0,0 +1,143 @@
+package poller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// -- the priority classes of the devices, while all slots are taken the polls of higher priority get
+// the next free one and the ones of low priority are skipped
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// slotWaiter -- a poll waiting for a free slot, ready is closed once the slot is handed over
+type slotWaiter struct {
+	rank  int
+	ready chan struct{}
+}
+
+var (
+	priorities     = make(map[string]string)
+	maxConcurrency int
+	inFlight       int
+	waiting        []*slotWaiter
+	slotsMutex     sync.Mutex
+
+	shedCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "polls_shed_total",
+			Help:      "Number of polls skipped for lack of a free slot by priority and reason (busy or behind)",
+		},
+		[]string{"priority", "reason"})
+)
+
+// SetPriorities -- the priority classes by target, targets not given are of normal priority
+func SetPriorities(byTarget map[string]string) {
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	priorities = byTarget
+}
+
+// SetMaxConcurrency -- limits the number of polls in flight, 0 doesn't limit them
+func SetMaxConcurrency(max int) {
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	maxConcurrency = max
+}
+
+// priority --
+func priority(target string) string {
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	if p, ok := priorities[target]; ok && p != "" {
+		return p
+	}
+	return PriorityNormal
+}
+
+// rank -- higher ranks get free slots first
+func rank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 2
+	case PriorityLow:
+		return 0
+	}
+	return 1
+}
+
+// acquire -- takes a slot for a poll; polls of low priority don't wait for one, the others wait at
+// most the given time, since the next poll of the target is due then; returns why no slot was taken
+func acquire(priority string, patience time.Duration) (bool, string) {
+	slotsMutex.Lock()
+	if maxConcurrency == 0 || inFlight < maxConcurrency {
+		inFlight++
+		slotsMutex.Unlock()
+		return true, ""
+	}
+	if priority == PriorityLow {
+		slotsMutex.Unlock()
+		return false, "busy"
+	}
+	w := &slotWaiter{rank: rank(priority), ready: make(chan struct{})}
+	waiting = append(waiting, w)
+	slotsMutex.Unlock()
+
+	timer := time.NewTimer(patience)
+	defer timer.Stop()
+
+	reason := "behind"
+	select {
+	case <-w.ready:
+		return true, ""
+	case <-timer.C:
+	case <-stopping:
+		reason = ""
+	}
+
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	select {
+	case <-w.ready:
+		// -- handed over meanwhile
+		return true, ""
+	default:
+	}
+	for i, other := range waiting {
+		if other == w {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	return false, reason
+}
+
+// release -- hands the slot over to the waiting poll of the highest priority, the longest waiting first
+func release() {
+	slotsMutex.Lock()
+	defer slotsMutex.Unlock()
+
+	if len(waiting) == 0 {
+		inFlight--
+		return
+	}
+	next := 0
+	for i, w := range waiting {
+		if w.rank > waiting[next].rank {
+			next = i
+		}
+	}
+	w := waiting[next]
+	waiting = append(waiting[:next], waiting[next+1:]...)
+	close(w.ready)
+}
@@ -0,0 +1,146 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+// RelayEvent -- a change of the relay state seen by the poller
+type RelayEvent struct {
+	Target   string    `json:"target"`
+	Relay    bool      `json:"relay"`
+	Previous bool      `json:"previous"`
+	Time     time.Time `json:"time"`
+	// -- not switched through the exporter, e.g. by the button of the device or another app
+	External bool `json:"external"`
+}
+
+// relayTarget -- the last known relay state of a polled target
+type relayTarget struct {
+	known       bool
+	relay       bool
+	polled      time.Time
+	subscribers []chan RelayEvent
+	// -- closed to stop polling the target
+	stop chan struct{}
+}
+
+var (
+	relayTargets = make(map[string]*relayTarget)
+	relayMutex   sync.Mutex
+)
+
+// InitializeRelay -- starts polling the relay state of the given targets in the given interval
+func InitializeRelay(targets []string, interval time.Duration) {
+	relayMutex.Lock()
+	defer relayMutex.Unlock()
+
+	for _, target := range targets {
+		if _, ok := relayTargets[target]; ok {
+			continue
+		}
+		relayTargets[target] = &relayTarget{stop: make(chan struct{})}
+		running.Add(1)
+		go pollRelay(target, interval, relayTargets[target].stop)
+	}
+}
+
+// WaitRelayChange -- blocks until the relay of the target changes or the context is done,
+// the second return value is false if the target isn't polled
+func WaitRelayChange(ctx context.Context, target string) (*RelayEvent, bool) {
+	relayMutex.Lock()
+	state, ok := relayTargets[target]
+	if !ok {
+		relayMutex.Unlock()
+		return nil, false
+	}
+	ch := make(chan RelayEvent, 1)
+	state.subscribers = append(state.subscribers, ch)
+	relayMutex.Unlock()
+
+	select {
+	case event := <-ch:
+		return &event, true
+	case <-ctx.Done():
+		unsubscribe(target, ch)
+		return nil, true
+	case <-stopping:
+		unsubscribe(target, ch)
+		return nil, true
+	}
+}
+
+// unsubscribe --
+func unsubscribe(target string, ch chan RelayEvent) {
+	relayMutex.Lock()
+	defer relayMutex.Unlock()
+
+	state, ok := relayTargets[target]
+	if !ok {
+		return
+	}
+	for i, subscriber := range state.subscribers {
+		if subscriber == ch {
+			state.subscribers = append(state.subscribers[:i], state.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// pollRelay -- polls a single target until the poller or the target is stopped
+func pollRelay(target string, interval time.Duration, stop <-chan struct{}) {
+	defer running.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// -- devices without relay, e.g. bulbs and buttons, answer /report with errors
+		if module, err := mystrom.DetectModule(target); err != nil {
+			log.Debugf("failed to detect the module of target '%v': %v", target, err)
+		} else if !mystrom.HasRelay(module) {
+			log.Debugf("target '%v' is a %v without relay, not polled", target, module)
+		} else if relay, err := mystrom.NewExporter(target).FetchRelay(); err != nil {
+			log.Debugf("failed to poll relay of target '%v': %v", target, err)
+		} else {
+			updateRelay(target, relay, time.Now())
+		}
+
+		select {
+		case <-stopping:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateRelay -- records the state and notifies the subscribers if it changed
+func updateRelay(target string, relay bool, now time.Time) {
+	relayMutex.Lock()
+	defer relayMutex.Unlock()
+
+	state, ok := relayTargets[target]
+	if !ok {
+		return
+	}
+	if state.known && state.relay != relay {
+		external := !mystrom.SwitchedSince(target, relay, state.polled)
+		log.Infof("relay of target '%v' changed to %v, external: %v", target, relay, external)
+		event := RelayEvent{Target: target, Relay: relay, Previous: state.relay, Time: now, External: external}
+		for _, subscriber := range state.subscribers {
+			subscriber <- event
+		}
+		state.subscribers = nil
+		notifyWebhook(event)
+	}
+	state.known = true
+	state.relay = relay
+	state.polled = now
+}
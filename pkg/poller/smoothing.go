@@ -0,0 +1,76 @@
+package poller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// -- the weight of the latest reading in the exponential moving average, 0 disables it
+	smoothingAlpha float64
+	// -- the moving average of the power by target
+	smoothedPower      = make(map[string]float64)
+	smoothedPowerMutex sync.Mutex
+)
+
+// SetSmoothing -- adds the exponential moving average of the polled power readings with the weight
+// alpha of the latest reading as mystrom_power_smoothed to the polls, 0 disables it
+func SetSmoothing(alpha float64) error {
+	if alpha < 0 || alpha > 1 {
+		return fmt.Errorf("alpha must be between 0 and 1")
+	}
+	smoothingAlpha = alpha
+	return nil
+}
+
+// smooth -- updates the moving average of the target with the power reading of the poll and returns
+// the families with its gauge added
+func smooth(target string, families []*dto.MetricFamily) []*dto.MetricFamily {
+	if smoothingAlpha == 0 {
+		return families
+	}
+
+	for _, family := range families {
+		if family.GetName() != "mystrom_power" || len(family.Metric) == 0 || family.Metric[0].Gauge == nil {
+			continue
+		}
+		metric := family.Metric[0]
+
+		smoothedPowerMutex.Lock()
+		average, ok := smoothedPower[target]
+		if !ok {
+			average = metric.Gauge.GetValue()
+		} else {
+			average += smoothingAlpha * (metric.Gauge.GetValue() - average)
+		}
+		smoothedPower[target] = average
+		smoothedPowerMutex.Unlock()
+
+		families = append(families, &dto.MetricFamily{
+			Name: proto.String("mystrom_power_smoothed"),
+			Help: proto.String("Exponential moving average of the polled power readings, with the weight poll.smoothing-alpha of the latest poll"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label: metric.Label,
+				Gauge: &dto.Gauge{Value: proto.Float64(average)},
+			}},
+		})
+		sort.Slice(families, func(i, j int) bool {
+			return families[i].GetName() < families[j].GetName()
+		})
+		return families
+	}
+	return families
+}
+
+// forgetSmoothed --
+func forgetSmoothed(target string) {
+	smoothedPowerMutex.Lock()
+	defer smoothedPowerMutex.Unlock()
+
+	delete(smoothedPower, target)
+}
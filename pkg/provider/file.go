@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/common/log"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// -- changes within this delay are read at once, e.g. a file written in several steps
+	fileSettleDelay = time.Second
+	// -- interval to check the file when it can't be watched
+	filePollInterval = 30 * time.Second
+)
+
+// File -- reads the targets from a file in the format of Prometheus' file_sd, as JSON or as YAML for
+// the extensions .yml and .yaml; the label __mac_address is taken as mac address of the target
+type File struct {
+	path string
+}
+
+// NewFile --
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Name --
+func (f *File) Name() string {
+	return "file"
+}
+
+// List --
+func (f *File) List() ([]Target, error) {
+	content, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the targets file: %v", err.Error())
+	}
+
+	var entries []TargetsEntry
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yml", ".yaml":
+		err = yaml.UnmarshalStrict(content, &entries)
+	default:
+		err = json.Unmarshal(content, &entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the targets file %v: %v", f.path, err.Error())
+	}
+	return fromEntries(entries), nil
+}
+
+// Watch --
+func (f *File) Watch(ctx context.Context) <-chan struct{} {
+	return watchFile(ctx, f.path, f.List)
+}
+
+// watchFile -- signals the changes of the file, the directory is watched to notice files replaced by a
+// rename as well; if it can't be watched, the file is listed in an interval instead
+func watchFile(ctx context.Context, path string, list func() ([]Target, error)) <-chan struct{} {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(path))
+		if err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		log.Warnf("unable to watch %v, checking it every %v instead: %v", path, filePollInterval, err)
+		return pollChanges(ctx, filePollInterval, list)
+	}
+
+	changes := make(chan struct{})
+	go func() {
+		defer watcher.Close()
+
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if filepath.Clean(event.Name) == filepath.Clean(path) {
+					settle = time.After(fileSettleDelay)
+				}
+			case err := <-watcher.Errors:
+				log.Errorf("error watching %v: %v", path, err)
+			case <-settle:
+				settle = nil
+				select {
+				case changes <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes
+}
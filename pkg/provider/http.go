@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the maximum size of a target list
+const maxListBytes = 10 << 20
+
+// HTTP -- fetches the targets in the format of Prometheus' http_sd from an url, the label
+// __mac_address is taken as mac address of the target
+type HTTP struct {
+	cfg    config.HTTPProvider
+	client *http.Client
+}
+
+// NewHTTP --
+func NewHTTP(cfg config.HTTPProvider) *HTTP {
+	return &HTTP{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name --
+func (h *HTTP) Name() string {
+	return "http"
+}
+
+// List --
+func (h *HTTP) List() ([]Target, error) {
+	header := http.Header{}
+	if h.cfg.BasicAuth != nil {
+		username, password, err := h.cfg.BasicAuth.Credentials()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get credentials: %v", err.Error())
+		}
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	token, err := h.cfg.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	var entries []TargetsEntry
+	if err := getJSON(h.client, h.cfg.URL, header, &entries); err != nil {
+		return nil, err
+	}
+	return fromEntries(entries), nil
+}
+
+// Watch -- fetches the targets in the refresh interval and signals when they changed
+func (h *HTTP) Watch(ctx context.Context) <-chan struct{} {
+	return pollChanges(ctx, h.cfg.RefreshInterval, h.List)
+}
+
+// statusError -- the source of the targets answered with an unexpected http status
+type statusError struct {
+	code   int
+	status string
+}
+
+// Error --
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unable to fetch the targets: unexpected status %v", e.status)
+}
+
+// getJSON -- fetches the document at the url with the given headers and decodes it into v
+func getJSON(client *http.Client, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %v", err.Error())
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "myStrom-exporter")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to fetch the targets: %v", err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &statusError{code: res.StatusCode, status: res.Status}
+	}
+
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxListBytes)).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode the targets: %v", err.Error())
+	}
+	return nil
+}
+
+// fromEntries -- returns the targets of service discovery entries
+func fromEntries(entries []TargetsEntry) []Target {
+	var targets []Target
+	for _, entry := range entries {
+		for _, target := range entry.Targets {
+			labels := make(map[string]string, len(entry.Labels))
+			for key, value := range entry.Labels {
+				labels[key] = value
+			}
+			t := Target{Target: target, Mac: normalizeMac(labels["__mac_address"]), Labels: labels}
+			delete(labels, "__mac_address")
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the credentials kubernetes mounts into every pod
+const serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// endpointsList -- the part of the endpoints list of the kubernetes api used
+type endpointsList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Subsets []struct {
+			Addresses []struct {
+				IP       string `json:"ip"`
+				Hostname string `json:"hostname"`
+			} `json:"addresses"`
+			Ports []struct {
+				Name string `json:"name"`
+				Port int    `json:"port"`
+			} `json:"ports"`
+		} `json:"subsets"`
+	} `json:"items"`
+}
+
+// Kubernetes -- offers the addresses of the endpoints matching a label selector, using the service
+// account of the pod the exporter runs in
+type Kubernetes struct {
+	cfg       config.KubernetesProvider
+	server    string
+	namespace string
+	client    *http.Client
+}
+
+// NewKubernetes -- returns the provider for the cluster the exporter runs in
+func NewKubernetes(cfg config.KubernetesProvider) (*Kubernetes, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountPath + "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the cluster ca: %v", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in the cluster ca")
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		content, err := ioutil.ReadFile(serviceAccountPath + "namespace")
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the namespace of the pod: %v", err.Error())
+		}
+		namespace = strings.TrimSpace(string(content))
+	}
+
+	return &Kubernetes{
+		cfg:       cfg,
+		server:    "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// Name --
+func (k *Kubernetes) Name() string {
+	return "kubernetes"
+}
+
+// List --
+func (k *Kubernetes) List() ([]Target, error) {
+	// -- the token is rotated by kubernetes, it's read for every request
+	token, err := ioutil.ReadFile(serviceAccountPath + "token")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the service account token: %v", err.Error())
+	}
+
+	query := url.Values{}
+	if k.cfg.LabelSelector != "" {
+		query.Set("labelSelector", k.cfg.LabelSelector)
+	}
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%v/api/v1/namespaces/%v/endpoints?%v", k.server, url.PathEscape(k.namespace), query.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %v", err.Error())
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the endpoints: %v", err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list the endpoints: unexpected status %v", res.Status)
+	}
+
+	var list endpointsList
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to decode the endpoints: %v", err.Error())
+	}
+
+	var targets []Target
+	for _, item := range list.Items {
+		for _, subset := range item.Subsets {
+			port := 0
+			for _, p := range subset.Ports {
+				if k.cfg.Port == "" || p.Name == k.cfg.Port {
+					port = p.Port
+					break
+				}
+			}
+			for _, address := range subset.Addresses {
+				target := address.IP
+				if port != 0 && port != 80 {
+					target = net.JoinHostPort(address.IP, strconv.Itoa(port))
+				}
+				targets = append(targets, Target{
+					Target: target,
+					Labels: map[string]string{
+						"__meta_kubernetes_namespace":      item.Metadata.Namespace,
+						"__meta_kubernetes_endpoints_name": item.Metadata.Name,
+						"__meta_kubernetes_hostname":       address.Hostname,
+					},
+				})
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Watch -- lists the endpoints in the refresh interval and signals when they changed
+func (k *Kubernetes) Watch(ctx context.Context) <-chan struct{} {
+	return pollChanges(ctx, k.cfg.RefreshInterval, k.List)
+}
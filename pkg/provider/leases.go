@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+// -- failed probes are repeated after this delay, successful ones as long as the lease doesn't change
+const probeRetryDelay = 10 * time.Minute
+
+// Probe -- asks the target for its mac address and device type to confirm it is a myStrom device
+type Probe func(target string) (mac string, deviceType string, err error)
+
+// lease -- an active lease of a dhcp server
+type lease struct {
+	ip       string
+	mac      string
+	hostname string
+}
+
+// probeResult -- the outcome of the last probe of a lease
+type probeResult struct {
+	deviceType string
+	ok         bool
+	time       time.Time
+}
+
+// Leases -- offers the hosts of a dnsmasq or Kea lease file whose mac address has the vendor prefix of
+// myStrom, once a probe confirmed they are myStrom devices; for networks where the broadcasts of the
+// discovery are blocked
+type Leases struct {
+	path   string
+	format string
+	probe  Probe
+
+	probed      map[string]probeResult
+	probedMutex sync.Mutex
+}
+
+// NewLeases -- the format is dnsmasq or kea, it's detected by the content if empty
+func NewLeases(path, format string, probe Probe) *Leases {
+	return &Leases{path: path, format: format, probe: probe, probed: make(map[string]probeResult)}
+}
+
+// Name --
+func (l *Leases) Name() string {
+	return "dhcp_leases"
+}
+
+// List --
+func (l *Leases) List() ([]Target, error) {
+	leases, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	l.probedMutex.Lock()
+	defer l.probedMutex.Unlock()
+
+	targets := []Target{}
+	current := make(map[string]bool)
+	for _, candidate := range leases {
+		if !mystrom.IsMystromMac(candidate.mac) {
+			continue
+		}
+		key := candidate.ip + "/" + candidate.mac
+		current[key] = true
+
+		result, ok := l.probed[key]
+		if !ok || (!result.ok && time.Since(result.time) > probeRetryDelay) {
+			result = probeResult{time: time.Now()}
+			mac, deviceType, err := l.probe(candidate.ip)
+			switch {
+			case err != nil:
+				log.Debugf("lease %v of %v isn't a reachable myStrom device: %v", candidate.ip, candidate.mac, err)
+			case normalizeMac(mac) != candidate.mac:
+				log.Warnf("lease %v of %v is answered by the device %v", candidate.ip, candidate.mac, mac)
+			default:
+				result.ok, result.deviceType = true, deviceType
+			}
+			l.probed[key] = result
+		}
+		if !result.ok {
+			continue
+		}
+
+		targets = append(targets, Target{
+			Target: candidate.ip,
+			Mac:    candidate.mac,
+			Type:   result.deviceType,
+			Labels: map[string]string{"__meta_dhcp_hostname": candidate.hostname},
+		})
+	}
+
+	// -- leases which ended are probed again when they return
+	for key := range l.probed {
+		if !current[key] {
+			delete(l.probed, key)
+		}
+	}
+	return targets, nil
+}
+
+// Watch --
+func (l *Leases) Watch(ctx context.Context) <-chan struct{} {
+	return watchFile(ctx, l.path, l.List)
+}
+
+// read -- returns the active leases of the file
+func (l *Leases) read() ([]lease, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the lease file: %v", err.Error())
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	format := l.format
+	if format == "" {
+		format = "dnsmasq"
+		if head, err := reader.Peek(8); err == nil && string(head) == "address," {
+			format = "kea"
+		}
+	}
+
+	if format == "kea" {
+		return readKeaLeases(reader, time.Now())
+	}
+	return readDnsmasqLeases(reader, time.Now())
+}
+
+// readDnsmasqLeases -- reads the lines of expiry time, mac address, ip, hostname and client id
+func readDnsmasqLeases(r io.Reader, now time.Time) ([]lease, error) {
+	var leases []lease
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		// -- 0 is an infinite lease
+		expiry, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || (expiry != 0 && time.Unix(expiry, 0).Before(now)) {
+			continue
+		}
+		hostname := fields[3]
+		if hostname == "*" {
+			hostname = ""
+		}
+		leases = append(leases, lease{ip: fields[2], mac: normalizeMac(fields[1]), hostname: hostname})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the lease file: %v", err.Error())
+	}
+	return leases, nil
+}
+
+// readKeaLeases -- reads the memfile of the Kea dhcp4 server, a csv file with a header to which updates
+// of the leases are appended, the last line of an address wins
+func readKeaLeases(r io.Reader, now time.Time) ([]lease, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the lease file: %v", err.Error())
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"address", "hwaddr", "expire", "hostname", "state"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the lease file has no column %v", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if columns[name] < len(record) {
+			return record[columns[name]]
+		}
+		return ""
+	}
+
+	byAddress := make(map[string]lease)
+	seen := make(map[string]bool)
+	var order []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the lease file: %v", err.Error())
+		}
+
+		address := field(record, "address")
+		if !seen[address] {
+			seen[address] = true
+			order = append(order, address)
+		}
+		// -- state 0 is an assigned lease, 1 declined and 2 expired
+		expire, err := strconv.ParseInt(field(record, "expire"), 10, 64)
+		if err != nil || field(record, "state") != "0" || time.Unix(expire, 0).Before(now) {
+			delete(byAddress, address)
+			continue
+		}
+		byAddress[address] = lease{ip: address, mac: normalizeMac(field(record, "hwaddr")), hostname: field(record, "hostname")}
+	}
+
+	leases := make([]lease, 0, len(byAddress))
+	for _, address := range order {
+		if l, ok := byAddress[address]; ok {
+			leases = append(leases, l)
+		}
+	}
+	return leases, nil
+}
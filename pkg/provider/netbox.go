@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// netboxObject -- the fields of the ip addresses and interfaces of the Netbox api used
+type netboxObject struct {
+	ID                 int    `json:"id"`
+	Address            string `json:"address"`
+	DNSName            string `json:"dns_name"`
+	Description        string `json:"description"`
+	MacAddress         string `json:"mac_address"`
+	AssignedObjectType string `json:"assigned_object_type"`
+	AssignedObjectID   int    `json:"assigned_object_id"`
+}
+
+// netboxPage -- a page of a list of the Netbox api
+type netboxPage struct {
+	Next    string         `json:"next"`
+	Results []netboxObject `json:"results"`
+}
+
+// Netbox -- offers the ip addresses with the tag from Netbox, the mac address is taken from the
+// interface the address is assigned to
+type Netbox struct {
+	cfg    config.IPAMProvider
+	client *http.Client
+}
+
+// NewNetbox --
+func NewNetbox(cfg config.IPAMProvider) *Netbox {
+	return &Netbox{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name --
+func (n *Netbox) Name() string {
+	return "netbox"
+}
+
+// List --
+func (n *Netbox) List() ([]Target, error) {
+	token, err := n.cfg.APIToken()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Token "+token)
+
+	base := strings.TrimSuffix(n.cfg.URL, "/")
+	addresses, err := n.list(base+"/api/ipam/ip-addresses/?"+url.Values{"tag": {n.cfg.Tag}, "limit": {"1000"}}.Encode(), header)
+	if err != nil {
+		return nil, err
+	}
+
+	// -- the mac addresses of the interfaces the addresses are assigned to
+	query := url.Values{"limit": {"1000"}}
+	for _, a := range addresses {
+		if a.AssignedObjectType == "dcim.interface" {
+			query.Add("id", fmt.Sprintf("%d", a.AssignedObjectID))
+		}
+	}
+	macs := make(map[int]string)
+	if len(query["id"]) > 0 {
+		interfaces, err := n.list(base+"/api/dcim/interfaces/?"+query.Encode(), header)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range interfaces {
+			macs[i.ID] = i.MacAddress
+		}
+	}
+
+	targets := make([]Target, 0, len(addresses))
+	for _, a := range addresses {
+		ip, _, err := net.ParseCIDR(a.Address)
+		if err != nil {
+			continue
+		}
+		mac := ""
+		if a.AssignedObjectType == "dcim.interface" {
+			mac = macs[a.AssignedObjectID]
+		}
+		targets = append(targets, Target{
+			Target: ip.String(),
+			Mac:    normalizeMac(mac),
+			Labels: map[string]string{
+				"__meta_netbox_dns_name":    a.DNSName,
+				"__meta_netbox_description": a.Description,
+			},
+		})
+	}
+	return targets, nil
+}
+
+// list -- returns the results of all pages of the list
+func (n *Netbox) list(next string, header http.Header) ([]netboxObject, error) {
+	var results []netboxObject
+	for next != "" {
+		var page netboxPage
+		if err := getJSON(n.client, next, header, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page.Results...)
+		next = page.Next
+	}
+	return results, nil
+}
+
+// Watch -- lists the addresses in the refresh interval and signals when they changed
+func (n *Netbox) Watch(ctx context.Context) <-chan struct{} {
+	return pollChanges(ctx, n.cfg.RefreshInterval, n.List)
+}
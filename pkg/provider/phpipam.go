@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// phpIPAMResponse -- the envelope of the responses of the phpIPAM api
+type phpIPAMResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// PhpIPAM -- offers the addresses with the tag from phpIPAM, authenticated with the app code token
+// of the api application
+type PhpIPAM struct {
+	cfg    config.IPAMProvider
+	client *http.Client
+}
+
+// NewPhpIPAM --
+func NewPhpIPAM(cfg config.IPAMProvider) *PhpIPAM {
+	return &PhpIPAM{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name --
+func (p *PhpIPAM) Name() string {
+	return "phpipam"
+}
+
+// List --
+func (p *PhpIPAM) List() ([]Target, error) {
+	token, err := p.cfg.APIToken()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("token", token)
+	base := fmt.Sprintf("%v/api/%v", strings.TrimSuffix(p.cfg.URL, "/"), url.PathEscape(p.cfg.AppID))
+
+	// -- the tags are referenced by their id
+	var tags []struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := p.get(base+"/addresses/tags/", header, &tags); err != nil {
+		return nil, err
+	}
+	tagID := ""
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Type, p.cfg.Tag) {
+			tagID = tag.ID
+		}
+	}
+	if tagID == "" {
+		return nil, fmt.Errorf("tag '%v' doesn't exist", p.cfg.Tag)
+	}
+
+	var addresses []struct {
+		IP          string `json:"ip"`
+		Mac         string `json:"mac"`
+		Hostname    string `json:"hostname"`
+		Description string `json:"description"`
+	}
+	err = p.get(base+"/addresses/tags/"+url.PathEscape(tagID)+"/addresses/", header, &addresses)
+	if statusErr, ok := err.(*statusError); ok && statusErr.code == http.StatusNotFound {
+		return []Target{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Target, 0, len(addresses))
+	for _, a := range addresses {
+		targets = append(targets, Target{
+			Target: a.IP,
+			Mac:    normalizeMac(a.Mac),
+			Labels: map[string]string{
+				"__meta_phpipam_hostname":    a.Hostname,
+				"__meta_phpipam_description": a.Description,
+			},
+		})
+	}
+	return targets, nil
+}
+
+// get -- fetches the data of the api response, a tag without addresses is answered as not found
+func (p *PhpIPAM) get(url string, header http.Header, v interface{}) error {
+	var response phpIPAMResponse
+	if err := getJSON(p.client, url, header, &response); err != nil {
+		return err
+	}
+	if !response.Success {
+		return fmt.Errorf("phpIPAM refused the request: %v", response.Message)
+	}
+	if len(response.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		return fmt.Errorf("unable to decode the response: %v", err.Error())
+	}
+	return nil
+}
+
+// Watch -- lists the addresses in the refresh interval and signals when they changed
+func (p *PhpIPAM) Watch(ctx context.Context) <-chan struct{} {
+	return pollChanges(ctx, p.cfg.RefreshInterval, p.List)
+}
@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const namespace = "mystrom_exporter"
+
+// Target -- a device offered by a provider
+type Target struct {
+	Target   string            `json:"target"`
+	Mac      string            `json:"mac,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Provider string            `json:"provider"`
+}
+
+// TargetProvider -- a source of targets, List returns the current targets and the channel returned by
+// Watch is signalled whenever they changed, until the context is done
+type TargetProvider interface {
+	Name() string
+	List() ([]Target, error)
+	Watch(ctx context.Context) <-chan struct{}
+}
+
+var (
+	providers []TargetProvider
+	// -- the last successfully listed targets by provider
+	listed      = make(map[string][]Target)
+	listeners   []func([]Target)
+	targetsLock sync.Mutex
+
+	targetsGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "provider_targets",
+			Help:      "Number of targets offered by the provider",
+		},
+		[]string{"provider"})
+	errorsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "provider_errors_total",
+			Help:      "Number of failures of the provider to list its targets, the last listed targets are kept",
+		},
+		[]string{"provider"})
+)
+
+// Collectors -- returns the metrics of the providers to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{targetsGaugeVec, errorsCounterVec}
+}
+
+// Register -- adds a provider, must be called before Run; of targets offered by several providers
+// the one of the first registered provider is used
+func Register(p TargetProvider) {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+
+	providers = append(providers, p)
+}
+
+// OnChange -- calls the function with all targets whenever the targets of a provider changed
+func OnChange(f func([]Target)) {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+
+	listeners = append(listeners, f)
+}
+
+// Run -- lists the targets of all providers once and keeps watching them until the context is done
+func Run(ctx context.Context) {
+	targetsLock.Lock()
+	registered := append([]TargetProvider{}, providers...)
+	targetsLock.Unlock()
+
+	for _, p := range registered {
+		refresh(p, false)
+	}
+	for _, p := range registered {
+		go watch(ctx, p)
+	}
+}
+
+// Targets -- returns the targets of all providers sorted by target
+func Targets() []Target {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+
+	return merged()
+}
+
+// watch --
+func watch(ctx context.Context, p TargetProvider) {
+	changes := p.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+			refresh(p, true)
+		}
+	}
+}
+
+// refresh -- lists the targets of the provider and optionally notifies the listeners
+func refresh(p TargetProvider, notify bool) {
+	targets, err := p.List()
+	if err != nil {
+		errorsCounterVec.WithLabelValues(p.Name()).Inc()
+		log.Errorf("failed to list the targets of provider %v: %v", p.Name(), err)
+		return
+	}
+	for i := range targets {
+		targets[i].Provider = p.Name()
+	}
+	targetsGaugeVec.WithLabelValues(p.Name()).Set(float64(len(targets)))
+
+	targetsLock.Lock()
+	listed[p.Name()] = targets
+	all := merged()
+	callbacks := append([]func([]Target){}, listeners...)
+	targetsLock.Unlock()
+	pruneShardKeys(all)
+
+	if !notify {
+		return
+	}
+	for _, f := range callbacks {
+		f(all)
+	}
+}
+
+// pollChanges -- lists the targets in the interval and signals when they changed, for providers whose
+// source can't notify about changes
+func pollChanges(ctx context.Context, interval time.Duration, list func() ([]Target, error)) <-chan struct{} {
+	changes := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last []Target
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			targets, err := list()
+			if err == nil && reflect.DeepEqual(targets, last) {
+				continue
+			}
+			last = targets
+			select {
+			case changes <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// normalizeMac -- the mac address in upper case without separators
+func normalizeMac(mac string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(mac))
+}
+
+// merged -- returns the targets of all providers without duplicates, must be called with the targets locked
+func merged() []Target {
+	var all []Target
+	seen := make(map[string]bool)
+	for _, p := range providers {
+		for _, t := range listed[p.Name()] {
+			if seen[t.Target] {
+				continue
+			}
+			seen[t.Target] = true
+			all = append(all, t)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Target < all[j].Target
+	})
+	return all
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+
+	"mystrom-exporter/pkg/inventory"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/shard"
+	"mystrom-exporter/pkg/storage"
+)
+
+// TargetsEntry -- an entry of the service discovery in the format of Prometheus' http_sd and file_sd
+type TargetsEntry struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// -- whether the targets are labeled with the health of their recent scrapes and the name of the device
+var (
+	healthLabels     bool
+	deviceNameLabels bool
+)
+
+// SetHealthLabels -- labels the targets with __meta_mystrom_health, ok, degraded, down or unknown, so
+// relabeling can drop dead devices
+func SetHealthLabels(enabled bool) {
+	healthLabels = enabled
+}
+
+// SetDeviceNameLabels -- labels the targets with device_name, the name assigned in the myStrom app as
+// reported by the last scrape of the device
+func SetDeviceNameLabels(enabled bool) {
+	deviceNameLabels = enabled
+}
+
+// Discover -- returns the targets of all providers as service discovery scraped through the exporter
+// at the given address; targets without their own metrics path are scraped on the device path
+func Discover(address, devicePath, exporterInstance string) ([]byte, error) {
+	list := []TargetsEntry{}
+
+	// -- with sharding, every instance only offers the devices of its own shard
+	for _, t := range Owned(Targets()) {
+		labels := map[string]string{
+			"instance":   t.Target,
+			"__provider": t.Provider,
+		}
+		if _, ok := t.Labels["__metrics_path__"]; !ok {
+			labels["__metrics_path__"] = devicePath
+			labels["__param_target"] = t.Target
+		}
+		if t.Mac != "" {
+			labels["__mac_address"] = t.Mac
+		}
+		if t.Type != "" {
+			labels["__device_type"] = t.Type
+		}
+		if shard.Enabled() {
+			labels["__shard"] = shard.String()
+		}
+		if exporterInstance != "" {
+			labels["exporter_instance"] = exporterInstance
+		}
+		if healthLabels {
+			labels["__meta_mystrom_health"] = mystrom.Health(t.Target)
+		}
+		if deviceNameLabels {
+			if name := inventory.DeviceName(mystrom.NormalizeMac(t.Mac), t.Target); name != "" {
+				labels["device_name"] = name
+			}
+		}
+		// -- like the meta labels of the service discoveries of Prometheus, dropped after relabeling unless
+		// mapped, e.g. by a labelmap rule
+		if t.Mac != "" {
+			for key, value := range storage.Annotations(strings.ToUpper(t.Mac)) {
+				labels["__meta_mystrom_annotation_"+key] = value
+			}
+		}
+		for key, value := range t.Labels {
+			labels[key] = value
+		}
+
+		list = append(list, TargetsEntry{Targets: []string{address}, Labels: labels})
+	}
+
+	return json.Marshal(list)
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"sync"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/shard"
+)
+
+var (
+	// -- the key assigning each target to a shard, decided once so polling and discovery agree and a
+	// device unreachable at first doesn't move to another shard once it answers
+	shardKeys      = make(map[string]string)
+	shardKeysMutex sync.Mutex
+
+	// -- asks a device for its mac address, replaceable for the unreachable devices of tests
+	fetchMac = func(target string) (string, error) {
+		info, err := mystrom.NewExporter(target).FetchInfo()
+		return info.Mac, err
+	}
+)
+
+// Owned -- returns the targets belonging to the shard of this instance, all of them without sharding.
+// A target is assigned by the mac address of its provider, else by the one its device reports, else by
+// the target itself; the devices are asked concurrently and only once
+func Owned(all []Target) []Target {
+	if !shard.Enabled() {
+		return all
+	}
+	keys := shardKeysOf(all)
+
+	owned := make([]Target, 0, len(all))
+	for _, t := range all {
+		if shard.Owns(keys[t.Target]) {
+			owned = append(owned, t)
+		}
+	}
+	return owned
+}
+
+// shardKeysOf -- the shard keys of the targets, asking the devices of the undecided ones without
+// holding the lock
+func shardKeysOf(all []Target) map[string]string {
+	keys := make(map[string]string, len(all))
+	var undecided []string
+
+	shardKeysMutex.Lock()
+	for _, t := range all {
+		if key, ok := shardKeys[t.Target]; ok {
+			keys[t.Target] = key
+		} else if t.Mac != "" {
+			keys[t.Target] = mystrom.NormalizeMac(t.Mac)
+			shardKeys[t.Target] = keys[t.Target]
+		} else if _, pending := keys[t.Target]; !pending {
+			keys[t.Target] = ""
+			undecided = append(undecided, t.Target)
+		}
+	}
+	shardKeysMutex.Unlock()
+
+	fetched := make([]string, len(undecided))
+	var wg sync.WaitGroup
+	for i, target := range undecided {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			mac, err := fetchMac(target)
+			if err != nil || mac == "" {
+				log.Warnf("unable to get the mac of target '%v' for sharding, assigning it by the target: %v", target, err)
+				fetched[i] = target
+				return
+			}
+			fetched[i] = mystrom.NormalizeMac(mac)
+		}(i, target)
+	}
+	wg.Wait()
+
+	shardKeysMutex.Lock()
+	defer shardKeysMutex.Unlock()
+
+	for i, target := range undecided {
+		// -- a concurrent call may have decided meanwhile, its decision stands
+		if key, ok := shardKeys[target]; ok {
+			keys[target] = key
+			continue
+		}
+		shardKeys[target] = fetched[i]
+		keys[target] = fetched[i]
+	}
+	return keys
+}
+
+// pruneShardKeys -- forgets the keys of the targets no longer offered by any provider
+func pruneShardKeys(all []Target) {
+	offered := make(map[string]bool, len(all))
+	for _, t := range all {
+		offered[t.Target] = true
+	}
+
+	shardKeysMutex.Lock()
+	defer shardKeysMutex.Unlock()
+
+	for target := range shardKeys {
+		if !offered[target] {
+			delete(shardKeys, target)
+		}
+	}
+}
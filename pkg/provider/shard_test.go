@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"mystrom-exporter/pkg/shard"
+)
+
+func TestOwnedDecidesOnce(t *testing.T) {
+	if err := shard.Initialize("1/2"); err != nil {
+		t.Fatal(err)
+	}
+	defer shard.Initialize("1/1")
+
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	reachable := false
+	fetchMac = func(target string) (string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls[target]++
+		if !reachable {
+			return "", errors.New("unreachable")
+		}
+		return "64002D" + target[len(target)-6:], nil
+	}
+
+	var all []Target
+	for _, target := range []string{"10.0.0.1:000001", "10.0.0.2:000002", "10.0.0.3:000003", "10.0.0.4:000004"} {
+		all = append(all, Target{Target: target})
+	}
+	all = append(all, Target{Target: "10.0.0.5", Mac: "64:00:2d:00:00:05"})
+
+	first := Owned(all)
+	reachable = true
+	second := Owned(all)
+
+	if len(first) != len(second) {
+		t.Fatalf("ownership changed once the devices answered: %v, then %v", first, second)
+	}
+	for i := range first {
+		if first[i].Target != second[i].Target {
+			t.Errorf("ownership changed once the devices answered: %v, then %v", first, second)
+		}
+	}
+	for target, n := range calls {
+		if n != 1 {
+			t.Errorf("target %v was asked %d times, want once", target, n)
+		}
+	}
+	if calls["10.0.0.5"] != 0 {
+		t.Errorf("target with the mac of its provider was asked")
+	}
+
+	pruneShardKeys(all[:1])
+	if _, ok := shardKeys["10.0.0.2:000002"]; ok {
+		t.Errorf("key of a target no longer offered was kept")
+	}
+}
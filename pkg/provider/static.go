@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"context"
+)
+
+// Static -- a provider of a fixed list of targets, e.g. the devices of the configuration file
+type Static struct {
+	name    string
+	targets []Target
+}
+
+// NewStatic --
+func NewStatic(name string, targets []Target) *Static {
+	return &Static{name: name, targets: targets}
+}
+
+// Name --
+func (s *Static) Name() string {
+	return s.name
+}
+
+// List --
+func (s *Static) List() ([]Target, error) {
+	return append([]Target{}, s.targets...), nil
+}
+
+// Watch -- the targets never change
+func (s *Static) Watch(ctx context.Context) <-chan struct{} {
+	return make(chan struct{})
+}
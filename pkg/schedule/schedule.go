@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/control"
+	"mystrom-exporter/pkg/leader"
+)
+
+const namespace = "mystrom_exporter"
+
+var (
+	executionsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "schedule_executions_total",
+			Help:      "Number of relay operations executed by schedules by schedule, action and result",
+		},
+		[]string{"schedule", "action", "result"})
+	lastExecutionGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "schedule_last_execution_timestamp_seconds",
+			Help:      "Time of the last execution of the schedule",
+		},
+		[]string{"schedule"})
+)
+
+// Collectors -- returns the metrics of the schedule engine to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{executionsCounterVec, lastExecutionGaugeVec}
+}
+
+// Initialize -- starts the engine running the schedules of the configuration
+func Initialize(cfg *config.Config) {
+	if len(cfg.Schedules) == 0 {
+		return
+	}
+
+	log.Infof("starting schedule engine with %d schedules", len(cfg.Schedules))
+	go run(cfg)
+}
+
+// run -- checks the schedules once at the start of every minute
+func run(cfg *config.Config) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(next.Sub(now))
+
+		// -- of redundant instances only the leader switches the relays
+		if !leader.IsLeader() {
+			continue
+		}
+		for i := range cfg.Schedules {
+			s := &cfg.Schedules[i]
+			on, off := s.Due(next)
+			if on {
+				execute(cfg, s, control.ActionOn, next)
+			}
+			if off {
+				execute(cfg, s, control.ActionOff, next)
+			}
+		}
+	}
+}
+
+// execute -- runs the action against all targets of the schedule
+func execute(cfg *config.Config, s *config.Schedule, action control.Action, now time.Time) {
+	log.Infof("running schedule %v: turning relays %v", s.Name, action)
+	lastExecutionGaugeVec.WithLabelValues(s.Name).Set(float64(now.Unix()))
+
+	for _, target := range cfg.ScheduleTargets(s) {
+		result := "ok"
+		if err := control.Execute(target, action, "schedule"); err != nil {
+			result = "error"
+			if _, ok := err.(*control.BlockedError); ok {
+				result = "blocked"
+			}
+		}
+		executionsCounterVec.WithLabelValues(s.Name, string(action), result).Inc()
+	}
+}
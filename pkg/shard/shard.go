@@ -0,0 +1,59 @@
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+var (
+	index = 1
+	total = 1
+)
+
+// Initialize -- sets the shard of this instance in the form N/M, e.g. 2/3 for the second of three instances
+func Initialize(spec string) error {
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid shard '%v', expected N/M", spec)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid shard '%v': %v", spec, err.Error())
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid shard '%v': %v", spec, err.Error())
+	}
+	if m < 1 || n < 1 || n > m {
+		return fmt.Errorf("invalid shard '%v', N must be between 1 and M", spec)
+	}
+
+	index, total = n, m
+	return nil
+}
+
+// Enabled -- whether the devices are split across several instances
+func Enabled() bool {
+	return total > 1
+}
+
+// Of -- returns the shard a device belongs to by the hash of its mac address
+func Of(mac string) int {
+	h := fnv.New32a()
+	h.Write([]byte(mystrom.NormalizeMac(mac)))
+	return int(h.Sum32()%uint32(total)) + 1
+}
+
+// Owns -- whether the device with the given mac address belongs to the shard of this instance
+func Owns(mac string) bool {
+	return Of(mac) == index
+}
+
+// String -- the shard of this instance in the form N/M
+func String() string {
+	return fmt.Sprintf("%d/%d", index, total)
+}
@@ -0,0 +1,302 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// -- the size of the segment files is a fraction of the size of the buffer, within these bounds
+const (
+	minSegmentBytes = 64 << 10
+	maxSegmentBytes = 8 << 20
+)
+
+// bufferedBatch -- a poll as line of a segment file, the families are protobuf encoded
+type bufferedBatch struct {
+	Target   string    `json:"target"`
+	Time     time.Time `json:"time"`
+	Families [][]byte  `json:"families"`
+}
+
+// segment -- a file of the buffer
+type segment struct {
+	path    string
+	size    int64
+	records int
+}
+
+// diskBuffer -- keeps the polls a sink failed to write in segment files of a directory, oldest first;
+// it's only used by the worker of its sink
+type diskBuffer struct {
+	name         string
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	segments []*segment
+	// -- the segment written, the last one
+	writer *os.File
+	// -- the oldest segment read, with the number of records read from it
+	reader     *os.File
+	readBuffer *bufio.Reader
+	read       int
+	// -- the next poll to replay, read ahead
+	next *Batch
+}
+
+var (
+	bufferBytesGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sink_buffer_bytes",
+			Help:      "Size of the polls buffered on disk for the sink, including the replayed part of the oldest segment",
+		},
+		[]string{"sink"})
+	bufferRecordsGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sink_buffer_polls",
+			Help:      "Number of polls buffered on disk for the sink waiting to be replayed",
+		},
+		[]string{"sink"})
+)
+
+// openBuffer -- opens the buffer in the directory, the polls buffered by a previous run are kept
+func openBuffer(name, dir string, maxBytes int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create the buffer directory: %v", err.Error())
+	}
+
+	segmentBytes := maxBytes / 8
+	if segmentBytes < minSegmentBytes {
+		segmentBytes = minSegmentBytes
+	}
+	if segmentBytes > maxSegmentBytes {
+		segmentBytes = maxSegmentBytes
+	}
+	b := &diskBuffer{name: name, dir: dir, maxBytes: maxBytes, segmentBytes: segmentBytes}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the buffer directory: %v", err.Error())
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl") {
+			continue
+		}
+		s := &segment{path: filepath.Join(dir, file.Name()), size: file.Size()}
+		if s.records, err = countLines(s.path); err != nil {
+			return nil, err
+		}
+		b.segments = append(b.segments, s)
+	}
+	// -- the names are the zero padded creation times
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].path < b.segments[j].path
+	})
+	b.update()
+	return b, nil
+}
+
+// empty -- whether there are no polls to replay
+func (b *diskBuffer) empty() bool {
+	return len(b.segments) == 0
+}
+
+// append -- adds the poll to the buffer, the oldest segments are dropped once the buffer exceeds its
+// size; returns the number of polls dropped
+func (b *diskBuffer) append(batch Batch) (int, error) {
+	record := bufferedBatch{Target: batch.Target, Time: batch.Time}
+	for _, family := range batch.Families {
+		encoded, err := proto.Marshal(family)
+		if err != nil {
+			return 0, fmt.Errorf("unable to encode the poll: %v", err.Error())
+		}
+		record.Families = append(record.Families, encoded)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("unable to encode the poll: %v", err.Error())
+	}
+	line = append(line, '\n')
+
+	if b.writer == nil || b.segments[len(b.segments)-1].size >= b.segmentBytes {
+		if err := b.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := b.writer.Write(line); err != nil {
+		return 0, fmt.Errorf("unable to write the buffer: %v", err.Error())
+	}
+	last := b.segments[len(b.segments)-1]
+	last.size += int64(len(line))
+	last.records++
+
+	dropped := 0
+	for b.size() > b.maxBytes && len(b.segments) > 1 {
+		dropped += b.segments[0].records - b.read
+		if err := b.removeOldest(); err != nil {
+			return dropped, err
+		}
+	}
+	b.update()
+	return dropped, nil
+}
+
+// peek -- returns the oldest poll without removing it
+func (b *diskBuffer) peek() (*Batch, error) {
+	for b.next == nil && len(b.segments) > 0 {
+		if b.reader == nil {
+			file, err := os.Open(b.segments[0].path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read the buffer: %v", err.Error())
+			}
+			b.reader, b.readBuffer, b.read = file, bufio.NewReader(file), 0
+		}
+
+		line, err := b.readBuffer.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			// -- the segment is replayed completely
+			if err := b.removeOldest(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("unable to read the buffer: %v", err.Error())
+		}
+
+		batch, err := decodeBuffered(line)
+		if err != nil {
+			// -- e.g. a line cut off by a crash, it's skipped
+			b.read++
+			continue
+		}
+		b.next = batch
+	}
+	return b.next, nil
+}
+
+// pop -- removes the oldest poll returned by peek
+func (b *diskBuffer) pop() {
+	if b.next == nil {
+		return
+	}
+	b.next = nil
+	b.read++
+	b.update()
+}
+
+// close --
+func (b *diskBuffer) close() {
+	if b.writer != nil {
+		b.writer.Close()
+	}
+	if b.reader != nil {
+		b.reader.Close()
+	}
+}
+
+// rotate -- starts a new segment
+func (b *diskBuffer) rotate() error {
+	if b.writer != nil {
+		b.writer.Close()
+	}
+	path := filepath.Join(b.dir, fmt.Sprintf("%020d.jsonl", time.Now().UnixNano()))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to create a buffer segment: %v", err.Error())
+	}
+	b.writer = file
+	b.segments = append(b.segments, &segment{path: path})
+	return nil
+}
+
+// removeOldest -- deletes the oldest segment
+func (b *diskBuffer) removeOldest() error {
+	oldest := b.segments[0]
+	if b.reader != nil {
+		b.reader.Close()
+		b.reader, b.readBuffer, b.read, b.next = nil, nil, 0, nil
+	}
+	if len(b.segments) == 1 && b.writer != nil {
+		b.writer.Close()
+		b.writer = nil
+	}
+	b.segments = b.segments[1:]
+	b.update()
+	if err := os.Remove(oldest.path); err != nil {
+		return fmt.Errorf("unable to remove a buffer segment: %v", err.Error())
+	}
+	return nil
+}
+
+// size -- the size of all segments
+func (b *diskBuffer) size() int64 {
+	var size int64
+	for _, s := range b.segments {
+		size += s.size
+	}
+	return size
+}
+
+// update -- updates the metrics of the buffer
+func (b *diskBuffer) update() {
+	records := -b.read
+	for _, s := range b.segments {
+		records += s.records
+	}
+	bufferBytesGaugeVec.WithLabelValues(b.name).Set(float64(b.size()))
+	bufferRecordsGaugeVec.WithLabelValues(b.name).Set(float64(records))
+}
+
+// decodeBuffered -- decodes a line of a segment
+func decodeBuffered(line []byte) (*Batch, error) {
+	var record bufferedBatch
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, err
+	}
+	batch := &Batch{Target: record.Target, Time: record.Time}
+	for _, encoded := range record.Families {
+		family := &dto.MetricFamily{}
+		if err := proto.Unmarshal(encoded, family); err != nil {
+			return nil, err
+		}
+		batch.Families = append(batch.Families, family)
+	}
+	return batch, nil
+}
+
+// countLines -- the number of polls in a segment
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the buffer: %v", err.Error())
+	}
+	defer file.Close()
+
+	lines := 0
+	reader := bufio.NewReader(file)
+	for {
+		_, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to read the buffer: %v", err.Error())
+		}
+		lines++
+	}
+}
@@ -0,0 +1,422 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/leader"
+)
+
+// -- the delays between the retries of a failed write
+const (
+	retryMinDelay = time.Second
+	retryMaxDelay = time.Minute
+)
+
+// output -- a registered sink, the batches of a push sink are queued and written by its own worker
+type output struct {
+	sink Sink
+	push bool
+
+	queue      []*Batch
+	queueMutex sync.Mutex
+	wake       chan struct{}
+	closed     chan struct{}
+	done       chan struct{}
+	size       int
+	options    config.SinkOptions
+	// -- the polls failed to be written are kept on disk if set
+	buffer *diskBuffer
+}
+
+var (
+	outputs      []*output
+	outputsMutex sync.Mutex
+
+	writesCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sink_writes_total",
+			Help:      "Number of writes of polled metrics to the sinks by sink and result",
+		},
+		[]string{"sink", "result"})
+	queueGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sink_queue_length",
+			Help:      "Number of polls waiting to be written to the sink",
+		},
+		[]string{"sink"})
+	droppedCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sink_dropped_total",
+			Help:      "Number of polls dropped because the queue or the buffer of the sink was full, the sink rejected them or the exporter shut down",
+		},
+		[]string{"sink"})
+)
+
+// Collectors -- returns the metrics of the sinks to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{writesCounterVec, queueGaugeVec, droppedCounterVec, bufferBytesGaugeVec, bufferRecordsGaugeVec}
+}
+
+// Register -- adds a sink written synchronously with every poll on every instance, for the local
+// consumers of the polls like the cache of the scrapes
+func Register(s Sink) {
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	outputs = append(outputs, &output{sink: s})
+}
+
+// RegisterPush -- adds a sink written in the background by the leader; up to the queue size polls are
+// kept in memory while the sink is unavailable and retried, the oldest are dropped first. With a buffer
+// path the polls are moved to disk instead once a write failed and replayed in order on recovery
+func RegisterPush(s Sink, options config.SinkOptions) error {
+	o := &output{
+		sink:    s,
+		push:    true,
+		wake:    make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+		size:    options.QueueSize,
+		options: options,
+	}
+	if options.BufferPath != "" {
+		buffer, err := openBuffer(s.Name(), options.BufferPath, options.BufferMaxBytes)
+		if err != nil {
+			return fmt.Errorf("sink %v: %v", s.Name(), err.Error())
+		}
+		o.buffer = buffer
+	}
+	queueGaugeVec.WithLabelValues(s.Name()).Set(0)
+	droppedCounterVec.WithLabelValues(s.Name()).Add(0)
+
+	outputsMutex.Lock()
+	outputs = append(outputs, o)
+	outputsMutex.Unlock()
+
+	go o.run()
+	return nil
+}
+
+// Publish -- hands the poll to all sinks, the push sinks only receive it on the leader
+func Publish(batch Batch) {
+	outputsMutex.Lock()
+	registered := append([]*output{}, outputs...)
+	outputsMutex.Unlock()
+
+	for _, o := range registered {
+		if !o.push {
+			write(o.sink, batch)
+			continue
+		}
+		if !leader.IsLeader() {
+			continue
+		}
+		if filtered, ok := o.filter(batch); ok {
+			o.enqueue(filtered)
+		}
+	}
+}
+
+// Close -- writes the queued polls of the push sinks until the context is done and closes the sinks,
+// the polls still queued then are dropped; sinks with a buffer move them to disk instead
+func Close(ctx context.Context) {
+	outputsMutex.Lock()
+	registered := append([]*output{}, outputs...)
+	outputsMutex.Unlock()
+
+	for _, o := range registered {
+		if o.push {
+			close(o.closed)
+		}
+	}
+	for _, o := range registered {
+		if o.push {
+			select {
+			case <-o.done:
+			case <-ctx.Done():
+				o.queueMutex.Lock()
+				log.Warnf("dropping %d queued polls of sink %v on shutdown", len(o.queue), o.sink.Name())
+				o.queueMutex.Unlock()
+				continue
+			}
+		}
+		if err := o.sink.Close(); err != nil {
+			log.Errorf("failed to close sink %v: %v", o.sink.Name(), err)
+		}
+	}
+}
+
+// write --
+func write(s Sink, batch Batch) error {
+	if err := s.Write(batch); err != nil {
+		writesCounterVec.WithLabelValues(s.Name(), "error").Inc()
+		log.Errorf("failed to write the poll of target '%v' to sink %v: %v", batch.Target, s.Name(), err)
+		return err
+	}
+	writesCounterVec.WithLabelValues(s.Name(), "ok").Inc()
+	return nil
+}
+
+// filter -- returns the poll with the metrics written to the sink, false if none is
+func (o *output) filter(batch Batch) (Batch, bool) {
+	if len(o.options.IncludeMetrics) == 0 && len(o.options.ExcludeMetrics) == 0 {
+		return batch, true
+	}
+	filtered := batch
+	filtered.Families = nil
+	for _, family := range batch.Families {
+		if o.options.Matches(family.GetName()) {
+			filtered.Families = append(filtered.Families, family)
+		}
+	}
+	return filtered, len(filtered.Families) > 0
+}
+
+// enqueue -- adds the poll to the queue, dropping the oldest poll if it is full
+func (o *output) enqueue(batch Batch) {
+	o.queueMutex.Lock()
+	if len(o.queue) >= o.size {
+		o.queue = o.queue[1:]
+		droppedCounterVec.WithLabelValues(o.sink.Name()).Inc()
+	}
+	o.queue = append(o.queue, &batch)
+	queueGaugeVec.WithLabelValues(o.sink.Name()).Set(float64(len(o.queue)))
+	o.queueMutex.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next -- waits for the oldest queued poll, false once the sink is closed and the queue is empty
+func (o *output) next() (*Batch, bool) {
+	for {
+		o.queueMutex.Lock()
+		if len(o.queue) > 0 {
+			batch := o.queue[0]
+			o.queueMutex.Unlock()
+			return batch, true
+		}
+		o.queueMutex.Unlock()
+
+		select {
+		case <-o.wake:
+		case <-o.closed:
+			o.queueMutex.Lock()
+			empty := len(o.queue) == 0
+			o.queueMutex.Unlock()
+			if empty {
+				return nil, false
+			}
+		}
+	}
+}
+
+// remove -- removes the poll from the queue unless it was dropped meanwhile
+func (o *output) remove(batch *Batch) {
+	o.queueMutex.Lock()
+	defer o.queueMutex.Unlock()
+
+	if len(o.queue) > 0 && o.queue[0] == batch {
+		o.queue = o.queue[1:]
+	}
+	queueGaugeVec.WithLabelValues(o.sink.Name()).Set(float64(len(o.queue)))
+}
+
+// run -- writes the queued polls in order, a failed write is retried with an increasing delay; once the
+// sink is closed failed writes are dropped. With a buffer the polls are moved to disk after a failed write
+// and replayed before newer polls, on close the queued polls are moved to disk for the next start
+func (o *output) run() {
+	defer close(o.done)
+	if o.buffer != nil {
+		defer o.buffer.close()
+	}
+	if f, ok := o.sink.(Flusher); ok && f.FlushInterval() > 0 {
+		o.runFlushing(f)
+		return
+	}
+
+	delay := retryMinDelay
+	for {
+		if o.buffer != nil && o.isClosed() {
+			o.spill()
+			return
+		}
+		if o.buffer != nil && !o.buffer.empty() {
+			// -- the queued polls are newer than the buffered ones
+			o.spill()
+			if o.replay() {
+				delay = retryMinDelay
+			} else {
+				o.wait(&delay)
+			}
+			continue
+		}
+
+		batch, ok := o.next()
+		if !ok {
+			return
+		}
+		if err := write(o.sink, *batch); err == nil || isPermanent(err) {
+			o.remove(batch)
+			if err != nil {
+				droppedCounterVec.WithLabelValues(o.sink.Name()).Inc()
+			}
+			delay = retryMinDelay
+			continue
+		}
+
+		if o.buffer != nil {
+			o.spill()
+			o.wait(&delay)
+			continue
+		}
+		if !o.wait(&delay) {
+			o.remove(batch)
+			droppedCounterVec.WithLabelValues(o.sink.Name()).Inc()
+		}
+	}
+}
+
+// runFlushing -- writes the queued polls at once in the flush interval or when half of the queue is
+// filled, failed writes are retried with the next flush; once the sink is closed the queued polls are
+// written a last time
+func (o *output) runFlushing(f Flusher) {
+	ticker := time.NewTicker(f.FlushInterval())
+	defer ticker.Stop()
+
+	for {
+		closed := false
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+			o.queueMutex.Lock()
+			full := len(o.queue) >= (o.size+1)/2
+			o.queueMutex.Unlock()
+			if !full {
+				continue
+			}
+		case <-o.closed:
+			closed = true
+		}
+
+		if o.buffer != nil && closed {
+			o.spill()
+			return
+		}
+		if o.buffer != nil && !o.buffer.empty() {
+			// -- the queued polls are newer than the buffered ones
+			o.spill()
+			for !o.buffer.empty() {
+				if !o.replay() {
+					break
+				}
+			}
+			if !o.buffer.empty() {
+				continue
+			}
+		}
+
+		o.queueMutex.Lock()
+		queued := append([]*Batch{}, o.queue...)
+		o.queueMutex.Unlock()
+
+		batches := make([]Batch, 0, len(queued))
+		for _, batch := range queued {
+			batches = append(batches, *batch)
+		}
+		written, err := f.WriteAll(batches)
+		if err != nil {
+			writesCounterVec.WithLabelValues(o.sink.Name(), "error").Inc()
+			log.Errorf("failed to write %d polls to sink %v: %v", len(batches)-written, o.sink.Name(), err)
+		} else if len(batches) > 0 {
+			writesCounterVec.WithLabelValues(o.sink.Name(), "ok").Inc()
+		}
+		for _, batch := range queued[:written] {
+			o.remove(batch)
+		}
+
+		switch {
+		case err != nil && o.buffer != nil:
+			o.spill()
+		case closed:
+			if err != nil {
+				droppedCounterVec.WithLabelValues(o.sink.Name()).Add(float64(len(batches) - written))
+			}
+			return
+		}
+	}
+}
+
+// wait -- waits for the delay before the next retry and increases it, false if the sink was closed
+func (o *output) wait(delay *time.Duration) bool {
+	select {
+	case <-o.closed:
+		return false
+	case <-time.After(*delay):
+		if *delay *= 2; *delay > retryMaxDelay {
+			*delay = retryMaxDelay
+		}
+		return true
+	}
+}
+
+// isClosed --
+func (o *output) isClosed() bool {
+	select {
+	case <-o.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// spill -- moves the queued polls to the disk buffer
+func (o *output) spill() {
+	o.queueMutex.Lock()
+	queued := o.queue
+	o.queue = nil
+	queueGaugeVec.WithLabelValues(o.sink.Name()).Set(0)
+	o.queueMutex.Unlock()
+
+	for _, batch := range queued {
+		dropped, err := o.buffer.append(*batch)
+		if err != nil {
+			dropped++
+			log.Errorf("failed to buffer the poll of target '%v' for sink %v: %v", batch.Target, o.sink.Name(), err)
+		}
+		droppedCounterVec.WithLabelValues(o.sink.Name()).Add(float64(dropped))
+	}
+}
+
+// replay -- writes the oldest buffered poll, false if it failed
+func (o *output) replay() bool {
+	batch, err := o.buffer.peek()
+	if err != nil {
+		log.Errorf("failed to read the buffer of sink %v: %v", o.sink.Name(), err)
+		return false
+	}
+	if batch == nil {
+		// -- the last segment was removed
+		log.Infof("replayed the buffered polls of sink %v", o.sink.Name())
+		return true
+	}
+	if err := write(o.sink, *batch); err != nil && !isPermanent(err) {
+		return false
+	} else if err != nil {
+		droppedCounterVec.WithLabelValues(o.sink.Name()).Inc()
+	}
+	o.buffer.pop()
+	return true
+}
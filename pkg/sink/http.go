@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// post -- sends the body to the url with the credentials, the token is sent with the given scheme
+func post(client *http.Client, url string, header http.Header, basicAuth *config.BasicAuth, scheme, token string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %v", err.Error())
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", "myStrom-exporter")
+	if basicAuth != nil {
+		username, password, err := basicAuth.Credentials()
+		if err != nil {
+			return fmt.Errorf("unable to get credentials: %v", err.Error())
+		}
+		req.SetBasicAuth(username, password)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", scheme+" "+token)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send the poll: %v", err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		err := fmt.Errorf("unable to send the poll: unexpected status %v: %s", res.Status, bytes.TrimSpace(message))
+		// -- a rejected poll, e.g. with samples too old for the receiver, fails again when retried
+		if res.StatusCode >= 400 && res.StatusCode <= 499 && res.StatusCode != http.StatusTooManyRequests {
+			return &permanentError{err}
+		}
+		return err
+	}
+	return nil
+}
+
+// permanentError -- a write which fails again when retried, the poll is dropped
+type permanentError struct {
+	error
+}
+
+// isPermanent --
+func isPermanent(err error) bool {
+	_, ok := err.(*permanentError)
+	return ok
+}
+
+// finite -- returns the samples without the values NaN and infinity, which json and the line protocol
+// can't represent
+func finite(samples []Sample) []Sample {
+	kept := samples[:0]
+	for _, s := range samples {
+		if !math.IsNaN(s.Value) && !math.IsInf(s.Value, 0) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
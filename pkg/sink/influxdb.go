@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the characters escaped in the line protocol
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// InfluxDB -- writes every poll in the line protocol, one measurement per metric with the labels as
+// tags and the value in the field value
+type InfluxDB struct {
+	cfg    config.InfluxDBSink
+	client *http.Client
+}
+
+// NewInfluxDB --
+func NewInfluxDB(cfg config.InfluxDBSink) *InfluxDB {
+	return &InfluxDB{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name --
+func (i *InfluxDB) Name() string {
+	return "influxdb"
+}
+
+// Write --
+func (i *InfluxDB) Write(batch Batch) error {
+	token, err := i.cfg.InfluxToken()
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	return post(i.client, i.cfg.URL, header, i.cfg.BasicAuth, "Token", token, lineProtocol(batch))
+}
+
+// Close --
+func (i *InfluxDB) Close() error {
+	return nil
+}
+
+// lineProtocol -- encodes the samples of the batch with the time of the poll in nanoseconds
+func lineProtocol(batch Batch) []byte {
+	timestamp := strconv.FormatInt(batch.Time.UnixNano(), 10)
+
+	var b strings.Builder
+	for _, s := range finite(batch.Samples()) {
+		b.WriteString(measurementEscaper.Replace(s.Name))
+		for _, name := range s.SortedLabels() {
+			// -- the line protocol has no empty tags
+			if s.Labels[name] == "" {
+				continue
+			}
+			b.WriteString("," + tagEscaper.Replace(name) + "=" + tagEscaper.Replace(s.Labels[name]))
+		}
+		b.WriteString(" value=" + strconv.FormatFloat(s.Value, 'g', -1, 64) + " " + timestamp + "\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// jsonLine -- a line of the json file, one per poll
+type jsonLine struct {
+	Target  string    `json:"target"`
+	Time    time.Time `json:"time"`
+	Samples []Sample  `json:"samples"`
+}
+
+// JSONFile -- appends every poll as a line of json to a file, for log shippers or later analysis
+type JSONFile struct {
+	file *os.File
+}
+
+// NewJSONFile -- opens the file for appending, it is created if it doesn't exist
+func NewJSONFile(path string) (*JSONFile, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the json file: %v", err.Error())
+	}
+	return &JSONFile{file: file}, nil
+}
+
+// Name --
+func (j *JSONFile) Name() string {
+	return "json_file"
+}
+
+// Write --
+func (j *JSONFile) Write(batch Batch) error {
+	line, err := json.Marshal(jsonLine{Target: batch.Target, Time: batch.Time, Samples: finite(batch.Samples())})
+	if err != nil {
+		return fmt.Errorf("unable to encode the poll: %v", err.Error())
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write the json file: %v", err.Error())
+	}
+	return nil
+}
+
+// Close --
+func (j *JSONFile) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,94 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the maximum time to wait for the broker
+const mqttTimeout = 10 * time.Second
+
+// mqttMessage -- the payload published for a sample
+type mqttMessage struct {
+	Value  float64           `json:"value"`
+	Labels map[string]string `json:"labels"`
+	Time   time.Time         `json:"time"`
+}
+
+// MQTT -- publishes every sample of a poll to the topic <topic_prefix>/<target>/<metric>, samples of a
+// metric with several label sets share the topic and are told apart by the labels of the payload
+type MQTT struct {
+	cfg    config.MQTTSink
+	client mqtt.Client
+}
+
+// NewMQTT -- the connection is opened with the first write and reestablished by the client
+func NewMQTT(cfg config.MQTTSink) *MQTT {
+	clientID := cfg.ClientID
+	if clientID == "" {
+		hostname, _ := os.Hostname()
+		clientID = fmt.Sprintf("mystrom-exporter-%v-%d", hostname, os.Getpid())
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetConnectTimeout(mqttTimeout).
+		SetAutoReconnect(true).
+		SetCredentialsProvider(func() (string, string) {
+			password, err := cfg.MQTTPassword()
+			if err != nil {
+				log.Errorf("unable to get the password of the mqtt broker: %v", err)
+			}
+			return cfg.Username, password
+		})
+	return &MQTT{cfg: cfg, client: mqtt.NewClient(options)}
+}
+
+// Name --
+func (m *MQTT) Name() string {
+	return "mqtt"
+}
+
+// Write --
+func (m *MQTT) Write(batch Batch) error {
+	if !m.client.IsConnected() {
+		token := m.client.Connect()
+		if !token.WaitTimeout(mqttTimeout) {
+			return fmt.Errorf("unable to connect to the broker: timeout")
+		}
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("unable to connect to the broker: %v", err.Error())
+		}
+	}
+
+	for _, s := range finite(batch.Samples()) {
+		payload, err := json.Marshal(mqttMessage{Value: s.Value, Labels: s.Labels, Time: batch.Time})
+		if err != nil {
+			return fmt.Errorf("unable to encode the sample: %v", err.Error())
+		}
+		token := m.client.Publish(m.cfg.TopicPrefix+"/"+batch.Target+"/"+s.Name, m.cfg.QoS, m.cfg.Retain, payload)
+		if !token.WaitTimeout(mqttTimeout) {
+			return fmt.Errorf("unable to publish to the broker: timeout")
+		}
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("unable to publish to the broker: %v", err.Error())
+		}
+	}
+	return nil
+}
+
+// Close -- disconnects from the broker, waiting up to a second for messages in flight
+func (m *MQTT) Close() error {
+	if m.client.IsConnected() {
+		m.client.Disconnect(1000)
+	}
+	return nil
+}
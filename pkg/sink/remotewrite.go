@@ -0,0 +1,166 @@
+package sink
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/common/log"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// RemoteWrite -- sends the polls with the remote write protocol of Prometheus, e.g. to Prometheus with
+// the remote write receiver enabled, Mimir, Thanos or VictoriaMetrics; with a flush interval the polls
+// of all targets in the interval are sent together
+type RemoteWrite struct {
+	cfg    config.RemoteWriteSink
+	client *http.Client
+}
+
+// NewRemoteWrite --
+func NewRemoteWrite(cfg config.RemoteWriteSink) *RemoteWrite {
+	return &RemoteWrite{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Name --
+func (r *RemoteWrite) Name() string {
+	return "remote_write"
+}
+
+// Write --
+func (r *RemoteWrite) Write(batch Batch) error {
+	return r.send([]Batch{batch})
+}
+
+// FlushInterval --
+func (r *RemoteWrite) FlushInterval() time.Duration {
+	return r.cfg.FlushInterval
+}
+
+// WriteAll -- sends the polls in requests of at most the max batch bytes before compression, a poll
+// exceeding it is sent alone; polls rejected by the receiver are dropped
+func (r *RemoteWrite) WriteAll(batches []Batch) (int, error) {
+	written := 0
+	for written < len(batches) {
+		n := 1
+		size := len(writeRequest(batches[written : written+1]))
+		for written+n < len(batches) {
+			// -- the series of several polls of a target are merged, the sum is an upper bound
+			next := len(writeRequest(batches[written+n : written+n+1]))
+			if size+next > r.cfg.MaxBatchBytes {
+				break
+			}
+			size += next
+			n++
+		}
+
+		err := r.send(batches[written : written+n])
+		if isPermanent(err) {
+			droppedCounterVec.WithLabelValues(r.Name()).Add(float64(n))
+			log.Errorf("sink %v rejected %d polls: %v", r.Name(), n, err)
+			err = nil
+		}
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// send -- sends the polls in a single request
+func (r *RemoteWrite) send(batches []Batch) error {
+	token, err := r.cfg.Token()
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Encoding", "snappy")
+	header.Set("Content-Type", "application/x-protobuf")
+	header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return post(r.client, r.cfg.URL, header, r.cfg.BasicAuth, "Bearer", token, snappy.Encode(nil, writeRequest(batches)))
+}
+
+// Close --
+func (r *RemoteWrite) Close() error {
+	return nil
+}
+
+// timeSeries -- the samples of a series in the polls of a request
+type timeSeries struct {
+	labels  [][2]string
+	samples []timedValue
+}
+
+// timedValue -- a sample with the time of its poll in milliseconds
+type timedValue struct {
+	value     float64
+	timestamp int64
+}
+
+// writeRequest -- encodes the samples of the polls as prometheus.WriteRequest, the samples of a series
+// in several polls are sent as one time series
+func writeRequest(batches []Batch) []byte {
+	var order []string
+	series := make(map[string]*timeSeries)
+	for _, batch := range batches {
+		timestamp := batch.Time.UnixNano() / 1e6
+		for _, s := range batch.Samples() {
+			// -- the labels of a time series must be sorted by name and must not be empty
+			labels := [][2]string{{"__name__", s.Name}}
+			for name, value := range s.Labels {
+				if value != "" {
+					labels = append(labels, [2]string{name, value})
+				}
+			}
+			sort.Slice(labels, func(i, j int) bool {
+				return labels[i][0] < labels[j][0]
+			})
+
+			var key strings.Builder
+			for _, label := range labels {
+				key.WriteString(label[0] + "\xff" + label[1] + "\xff")
+			}
+			ts, ok := series[key.String()]
+			if !ok {
+				ts = &timeSeries{labels: labels}
+				series[key.String()] = ts
+				order = append(order, key.String())
+			}
+			ts.samples = append(ts.samples, timedValue{s.Value, timestamp})
+		}
+	}
+
+	var request []byte
+	for _, key := range order {
+		ts := series[key]
+		var encoded []byte
+		for _, label := range ts.labels {
+			var pair []byte
+			pair = protowire.AppendTag(pair, 1, protowire.BytesType)
+			pair = protowire.AppendString(pair, label[0])
+			pair = protowire.AppendTag(pair, 2, protowire.BytesType)
+			pair = protowire.AppendString(pair, label[1])
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, pair)
+		}
+		for _, s := range ts.samples {
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, sample)
+		}
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, encoded)
+	}
+	return request
+}
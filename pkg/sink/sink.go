@@ -0,0 +1,114 @@
+package sink
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "mystrom_exporter"
+
+// Batch -- the metrics of one poll of a target
+type Batch struct {
+	Target   string
+	Time     time.Time
+	Families []*dto.MetricFamily
+}
+
+// Sample -- a single series of a batch, histograms and summaries are split into the series Prometheus
+// exposes for them
+type Sample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// Sink -- an output of the polled metrics; Write is never called concurrently for the same sink and a
+// failed write of a queued sink is retried with the same batch
+type Sink interface {
+	Name() string
+	Write(batch Batch) error
+	Close() error
+}
+
+// Flusher -- a sink writing the queued polls at once in its flush interval; WriteAll returns the number
+// of polls written from the start, polls the sink rejected count as written
+type Flusher interface {
+	Sink
+	FlushInterval() time.Duration
+	WriteAll(batches []Batch) (int, error)
+}
+
+// Samples -- returns the series of the batch
+func (b Batch) Samples() []Sample {
+	var samples []Sample
+	for _, family := range b.Families {
+		name := family.GetName()
+		for _, metric := range family.Metric {
+			labels := make(map[string]string, len(metric.Label))
+			for _, pair := range metric.Label {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				samples = append(samples, Sample{name, labels, metric.GetCounter().GetValue()})
+			case dto.MetricType_GAUGE:
+				samples = append(samples, Sample{name, labels, metric.GetGauge().GetValue()})
+			case dto.MetricType_UNTYPED:
+				samples = append(samples, Sample{name, labels, metric.GetUntyped().GetValue()})
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, q := range summary.Quantile {
+					samples = append(samples, Sample{name, with(labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue()})
+				}
+				samples = append(samples,
+					Sample{name + "_sum", labels, summary.GetSampleSum()},
+					Sample{name + "_count", labels, float64(summary.GetSampleCount())})
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.Bucket {
+					samples = append(samples, Sample{name + "_bucket", with(labels, "le", formatFloat(bucket.GetUpperBound())), float64(bucket.GetCumulativeCount())})
+				}
+				if n := len(histogram.Bucket); n == 0 || !math.IsInf(histogram.Bucket[n-1].GetUpperBound(), 1) {
+					samples = append(samples, Sample{name + "_bucket", with(labels, "le", "+Inf"), float64(histogram.GetSampleCount())})
+				}
+				samples = append(samples,
+					Sample{name + "_sum", labels, histogram.GetSampleSum()},
+					Sample{name + "_count", labels, float64(histogram.GetSampleCount())})
+			}
+		}
+	}
+	return samples
+}
+
+// SortedLabels -- returns the label names of the sample in order
+func (s Sample) SortedLabels() []string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// with -- returns a copy of the labels with an additional label
+func with(labels map[string]string, name, value string) map[string]string {
+	copied := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	copied[name] = value
+	return copied
+}
+
+// formatFloat -- formats the bound of a bucket or a quantile the way Prometheus does
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
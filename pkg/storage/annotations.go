@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"sync"
+)
+
+const annotationsFile = "annotations.json"
+
+var (
+	annotations      = make(map[string]map[string]string)
+	annotationsMutex sync.Mutex
+)
+
+// LoadAnnotations -- restores the annotations of the devices from the storage path
+func LoadAnnotations() error {
+	annotationsMutex.Lock()
+	defer annotationsMutex.Unlock()
+
+	loaded := make(map[string]map[string]string)
+	if _, err := LoadJSON(annotationsFile, &loaded); err != nil {
+		return err
+	}
+	annotations = loaded
+	return nil
+}
+
+// Annotations -- returns a copy of the annotations of the device with the given normalized mac address
+func Annotations(mac string) map[string]string {
+	annotationsMutex.Lock()
+	defer annotationsMutex.Unlock()
+
+	values := make(map[string]string, len(annotations[mac]))
+	for key, value := range annotations[mac] {
+		values[key] = value
+	}
+	return values
+}
+
+// SetAnnotations -- replaces the annotations of the device and persists all annotations,
+// an empty set removes the device
+func SetAnnotations(mac string, values map[string]string) error {
+	annotationsMutex.Lock()
+	defer annotationsMutex.Unlock()
+
+	if len(values) == 0 {
+		delete(annotations, mac)
+	} else {
+		annotations[mac] = values
+	}
+	return SaveJSON(annotationsFile, annotations)
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const countersFile = "counters.json"
+
+// CounterSample -- the value of a single counter with its labels
+type CounterSample struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// SaveCounters -- writes the current values of the named counters of the gatherer to disk
+func SaveCounters(gatherer prometheus.Gatherer, names []string) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("unable to gather counters: %v", err.Error())
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	counters := make(map[string][]CounterSample)
+	for _, family := range families {
+		if !wanted[family.GetName()] || family.GetType() != dto.MetricType_COUNTER {
+			continue
+		}
+		for _, metric := range family.Metric {
+			labels := make(map[string]string, len(metric.Label))
+			for _, pair := range metric.Label {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			counters[family.GetName()] = append(counters[family.GetName()], CounterSample{
+				Labels: labels,
+				Value:  metric.GetCounter().GetValue(),
+			})
+		}
+	}
+
+	return SaveJSON(countersFile, counters)
+}
+
+// LoadCounters -- returns the counters written by SaveCounters by name, nothing if there is no checkpoint
+func LoadCounters() (map[string][]CounterSample, error) {
+	counters := make(map[string][]CounterSample)
+	if _, err := LoadJSON(countersFile, &counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat -- sortable by name, used for the file names of the archived settings
+const snapshotTimeFormat = "20060102T150405Z"
+
+// SettingsChange -- a single value differing between two snapshots of the settings
+type SettingsChange struct {
+	Key      string      `json:"key"`
+	Archived interface{} `json:"archived"`
+	Current  interface{} `json:"current"`
+}
+
+// ArchiveSettings -- stores the settings of the device unless they equal the latest snapshot,
+// returns whether a new snapshot was written
+func ArchiveSettings(mac string, data []byte) (bool, error) {
+	latest, _, err := LatestSettings(mac)
+	if err != nil {
+		return false, err
+	}
+	if latest != nil {
+		if changes, err := DiffSettings(latest, data); err == nil && len(changes) == 0 {
+			return false, nil
+		}
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, data, "", "  "); err != nil {
+		return false, fmt.Errorf("settings are no valid JSON: %v", err.Error())
+	}
+
+	name := filepath.Join("settings", mac, time.Now().UTC().Format(snapshotTimeFormat)+".json")
+	return true, writeFile(name, pretty.Bytes())
+}
+
+// LatestSettings -- returns the most recent snapshot of the device and when it was taken,
+// nil if there is none
+func LatestSettings(mac string) ([]byte, time.Time, error) {
+	dir := filepath.Join(basePath, "settings", mac)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to list snapshots: %v", err.Error())
+	}
+
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, time.Time{}, nil
+	}
+	sort.Strings(names)
+	name := names[len(names)-1]
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to read snapshot: %v", err.Error())
+	}
+	taken, _ := time.Parse(snapshotTimeFormat, strings.TrimSuffix(name, ".json"))
+	return data, taken, nil
+}
+
+// DiffSettings -- compares two JSON documents by their flattened keys
+func DiffSettings(archived []byte, current []byte) ([]SettingsChange, error) {
+	var a, c interface{}
+	if err := json.Unmarshal(archived, &a); err != nil {
+		return nil, fmt.Errorf("unable to decode archived settings: %v", err.Error())
+	}
+	if err := json.Unmarshal(current, &c); err != nil {
+		return nil, fmt.Errorf("unable to decode current settings: %v", err.Error())
+	}
+
+	flatA := make(map[string]interface{})
+	flatC := make(map[string]interface{})
+	flatten("", a, flatA)
+	flatten("", c, flatC)
+
+	keys := make(map[string]bool)
+	for key := range flatA {
+		keys[key] = true
+	}
+	for key := range flatC {
+		keys[key] = true
+	}
+
+	changes := []SettingsChange{}
+	for key := range keys {
+		if !reflect.DeepEqual(flatA[key], flatC[key]) {
+			changes = append(changes, SettingsChange{Key: key, Archived: flatA[key], Current: flatC[key]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// flatten -- nested objects become dotted keys, arrays and scalars are compared as a whole
+func flatten(prefix string, value interface{}, result map[string]interface{}) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		result[prefix] = value
+		return
+	}
+	for key, v := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flatten(key, v, result)
+	}
+}
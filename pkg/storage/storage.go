@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var basePath = "data"
+
+// Initialize -- sets the directory the exporter keeps its state in, it's created on first write
+func Initialize(path string) {
+	basePath = path
+}
+
+// SaveJSON -- encodes the value and writes it to the file with the given name below the storage path
+func SaveJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode %v: %v", name, err.Error())
+	}
+	return writeFile(name, data)
+}
+
+// LoadJSON -- decodes the file with the given name into the value, returns false if there is no such file
+func LoadJSON(name string, v interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(basePath, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read %v: %v", name, err.Error())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("unable to decode %v: %v", name, err.Error())
+	}
+	return true, nil
+}
+
+// writeFile -- writes the file below the storage path atomically by renaming a temporary file
+func writeFile(name string, data []byte) error {
+	filename := filepath.Join(basePath, name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o750); err != nil {
+		return fmt.Errorf("unable to create directory: %v", err.Error())
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return fmt.Errorf("unable to create file: %v", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write %v: %v", filename, err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write %v: %v", filename, err.Error())
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("unable to write %v: %v", filename, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Announcer -- sends the udp announcements of fake devices like the ones broadcast by myStrom devices
+// to port 7979, to a given address so tests don't need broadcasts
+type Announcer struct {
+	connection *net.UDPConn
+
+	mutex   sync.Mutex
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewAnnouncer -- an announcer sending to the address, e.g. 127.0.0.1:7979
+func NewAnnouncer(address string) (*Announcer, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve address: %v", err.Error())
+	}
+	connection, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect: %v", err.Error())
+	}
+	return &Announcer{connection: connection}, nil
+}
+
+// Announce -- sends a single announcement of the device, the mac address followed by the device type;
+// the mac address may be given with or without separators
+func (a *Announcer) Announce(mac string, deviceType int) error {
+	hardwareAddr, err := hex.DecodeString(mac)
+	if err != nil {
+		hardwareAddr, err = net.ParseMAC(mac)
+	}
+	if err != nil || len(hardwareAddr) != 6 {
+		return fmt.Errorf("invalid mac address %v", mac)
+	}
+	packet := append([]byte(hardwareAddr), byte(deviceType), 0)
+	if _, err := a.connection.Write(packet); err != nil {
+		return fmt.Errorf("unable to send announcement: %v", err.Error())
+	}
+	return nil
+}
+
+// Start -- announces the device in the interval until Stop, like a device does every few seconds
+func (a *Announcer) Start(mac string, deviceType int, interval time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.stop == nil {
+		a.stop = make(chan struct{})
+	}
+	stop := a.stop
+	a.stopped.Add(1)
+	go func() {
+		defer a.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			a.Announce(mac, deviceType)
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop -- stops the announcements started and waits for them to end
+func (a *Announcer) Stop() {
+	a.mutex.Lock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+	a.mutex.Unlock()
+
+	a.stopped.Wait()
+}
+
+// Close -- stops the announcements and closes the connection
+func (a *Announcer) Close() error {
+	a.Stop()
+	return a.connection.Close()
+}
@@ -0,0 +1,249 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quirks -- the deviations of firmware builds seen in the field the fake device can reproduce
+type Quirks struct {
+	// -- reports numbers as strings with a decimal comma, e.g. "12,5"
+	StringNumbers bool
+	// -- names the wifi signal strength rssi instead of signal
+	RSSI bool
+	// -- reports the power and energy as null, like a Switch Zero
+	NoPower bool
+	// -- reports the temperature sensor as failed with -3276.8
+	BrokenTemperature bool
+	// -- answers /api/v1/info with an html page, like a device of another vendor
+	HTML bool
+	// -- the endpoints answered with 404, e.g. /temp for firmware without it
+	MissingEndpoints []string
+	// -- the token required in the Token header, none if empty
+	Token string
+}
+
+// DeviceConfig -- the identity, readings and quirks of a fake device
+type DeviceConfig struct {
+	Mac         string
+	Type        int
+	Version     string
+	Name        string
+	Power       float64
+	Temperature float64
+	Relay       bool
+	Quirks      Quirks
+}
+
+// Device -- a fake myStrom switch answering the endpoints of its api on a local port
+type Device struct {
+	server *httptest.Server
+	start  time.Time
+
+	mutex    sync.Mutex
+	config   DeviceConfig
+	requests map[string]int
+}
+
+// NewDevice -- starts a fake device, a switch with firmware 3.82.60 where the config leaves it empty
+func NewDevice(config DeviceConfig) *Device {
+	if config.Mac == "" {
+		config.Mac = "64002D000001"
+	}
+	if config.Type == 0 {
+		config.Type = 106
+	}
+	if config.Version == "" {
+		config.Version = "3.82.60"
+	}
+	if config.Name == "" {
+		config.Name = "fake"
+	}
+
+	d := &Device{
+		start:    time.Now(),
+		config:   config,
+		requests: make(map[string]int),
+	}
+	d.server = httptest.NewServer(http.HandlerFunc(d.serve))
+	return d
+}
+
+// Target -- the host and port of the device as passed to the exporter
+func (d *Device) Target() string {
+	return strings.TrimPrefix(d.server.URL, "http://")
+}
+
+// Close -- stops the device
+func (d *Device) Close() {
+	d.server.Close()
+}
+
+// Mac -- the mac address of the device, e.g. to announce it
+func (d *Device) Mac() string {
+	return d.config.Mac
+}
+
+// SetPower --
+func (d *Device) SetPower(power float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.config.Power = power
+}
+
+// SetTemperature --
+func (d *Device) SetTemperature(temperature float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.config.Temperature = temperature
+}
+
+// Relay -- the state of the relay, e.g. after a control request
+func (d *Device) Relay() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.config.Relay
+}
+
+// Name -- the name of the device, e.g. after a rename through the settings
+func (d *Device) Name() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.config.Name
+}
+
+// Requests -- the number of requests of the endpoint, including the rejected ones
+func (d *Device) Requests(path string) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.requests[path]
+}
+
+// serve --
+func (d *Device) serve(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.requests[r.URL.Path]++
+	quirks := d.config.Quirks
+
+	if quirks.Token != "" && r.Header.Get("Token") != quirks.Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	for _, missing := range quirks.MissingEndpoints {
+		if r.URL.Path == missing {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/api/v1/info":
+		if quirks.HTML {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>login</body></html>")
+			return
+		}
+		info := map[string]interface{}{
+			"version":   d.config.Version,
+			"mac":       d.config.Mac,
+			"type":      d.number(float64(d.config.Type)),
+			"name":      d.config.Name,
+			"ssid":      "fake",
+			"ip":        "127.0.0.1",
+			"static":    false,
+			"connected": true,
+			"uptime":    d.number(float64(int(time.Since(d.start).Seconds()))),
+		}
+		if quirks.RSSI {
+			info["rssi"] = d.number(-61)
+		} else {
+			info["signal"] = d.number(-61)
+		}
+		d.reply(w, info)
+	case "/report":
+		report := map[string]interface{}{
+			"power":       d.number(d.config.Power),
+			"Ws":          d.number(d.config.Power),
+			"relay":       d.config.Relay,
+			"temperature": d.number(d.temperature()),
+		}
+		if quirks.NoPower {
+			report["power"], report["Ws"] = nil, nil
+		}
+		d.reply(w, report)
+	case "/temp":
+		d.reply(w, map[string]interface{}{
+			"measured":     d.number(d.temperature() + 2.5),
+			"compensation": d.number(2.5),
+			"compensated":  d.number(d.temperature()),
+		})
+	case "/relay":
+		switch r.URL.Query().Get("state") {
+		case "1":
+			d.config.Relay = true
+		case "0":
+			d.config.Relay = false
+		default:
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+		d.reply(w, map[string]interface{}{})
+	case "/toggle":
+		d.config.Relay = !d.config.Relay
+		d.reply(w, map[string]interface{}{"relay": d.config.Relay})
+	case "/api/v1/settings":
+		if r.Method == http.MethodPost {
+			var settings struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				http.Error(w, "invalid settings", http.StatusBadRequest)
+				return
+			}
+			d.config.Name = settings.Name
+			d.reply(w, map[string]interface{}{})
+			return
+		}
+		d.reply(w, map[string]interface{}{"name": d.config.Name, "ntp": "pool.ntp.org", "led": true})
+	case "/api/v1/reboot":
+		d.reply(w, map[string]interface{}{})
+	case "/api/v1/firmware/check":
+		d.reply(w, map[string]interface{}{"update": false})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// temperature -- the temperature reported, -3276.8 by a failed sensor
+func (d *Device) temperature() float64 {
+	if d.config.Quirks.BrokenTemperature {
+		return -3276.8
+	}
+	return d.config.Temperature
+}
+
+// number -- the value as number or, with StringNumbers, as string with a decimal comma
+func (d *Device) number(value float64) interface{} {
+	if !d.config.Quirks.StringNumbers {
+		return value
+	}
+	return strings.Replace(fmt.Sprintf("%g", value), ".", ",", 1)
+}
+
+// reply --
+func (d *Device) reply(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,35 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/prometheus/common/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuth -- only passes requests authenticated as one of the users to the next handler,
+// the passwords of the users are bcrypt hashes
+func BasicAuth(users map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if ok && authenticate(users, user, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Warnf("unauthorized request from '%v' for %v", r.RemoteAddr, r.URL.Path)
+		w.Header().Set("WWW-Authenticate", `Basic realm="mystrom-exporter"`)
+		Error(w, r, "", http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// authenticate -- unknown users are checked against a dummy hash to not leak their existence by timing
+func authenticate(users map[string]string, user string, password string) bool {
+	hash, known := users[user]
+	if !known {
+		hash = "$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi"
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return known && err == nil
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// errorResponse -- the body of an error for clients asking for JSON
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Target  string `json:"target,omitempty"`
+}
+
+// Error -- replies with the error as JSON when the client accepts application/json, as plain text
+// otherwise like http.Error, which keeps the answers to Prometheus unchanged
+func Error(w http.ResponseWriter, r *http.Request, target string, message string, code int) {
+	if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+		http.Error(w, message, code)
+		return
+	}
+
+	data, err := json.Marshal(errorResponse{Code: code, Message: message, Target: target})
+	if err != nil {
+		http.Error(w, message, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(data)
+	w.Write([]byte("\n"))
+}
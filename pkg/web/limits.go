@@ -0,0 +1,163 @@
+package web
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// LimitBody -- limits the size of the request bodies read by the next handler, reading beyond the
+// limit fails and the connection is closed
+func LimitBody(max int64, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			Error(w, r, "", http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// -- the time clients are asked to wait before retrying a rejected request if the endpoint has no timeout
+const defaultRetryAfter = 5 * time.Second
+
+var (
+	inFlightGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of requests being served by endpoint",
+		},
+		[]string{"endpoint"})
+	rejectedCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_rejected_requests_total",
+			Help:      "Number of requests answered with 503 by endpoint and reason (in_flight or timeout)",
+		},
+		[]string{"endpoint", "reason"})
+)
+
+// Limiter -- limits the duration and the number of concurrent requests of each endpoint, the endpoint
+// being the path of the matched route
+type Limiter struct {
+	timeout     time.Duration
+	maxInFlight int
+	endpoints   map[string]config.EndpointLimits
+	// -- endpoints waiting on purpose, only limited by a timeout of their own
+	longPolls map[string]bool
+
+	mutex    sync.Mutex
+	inFlight map[string]int
+}
+
+// NewLimiter -- creates the limiter from the default limits of all endpoints, 0 disables them, and the
+// limits of single endpoints
+func NewLimiter(timeout time.Duration, maxInFlight int, endpoints map[string]config.EndpointLimits, longPolls ...string) *Limiter {
+	l := &Limiter{
+		timeout:     timeout,
+		maxInFlight: maxInFlight,
+		endpoints:   endpoints,
+		longPolls:   make(map[string]bool),
+		inFlight:    make(map[string]int),
+	}
+	for _, path := range longPolls {
+		l.longPolls[path] = true
+	}
+	return l
+}
+
+// Middleware -- answers with 503 and Retry-After once the maximum number of requests of the endpoint
+// is in flight or the request takes longer than the timeout of the endpoint
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				endpoint = template
+			}
+		}
+		timeout, maxInFlight := l.limits(endpoint)
+
+		retryAfter := defaultRetryAfter
+		if timeout > 0 {
+			retryAfter = timeout
+		}
+		retryAfterSeconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+		if !l.acquire(endpoint, maxInFlight) {
+			rejectedCounterVec.WithLabelValues(endpoint, "in_flight").Inc()
+			log.Debugf("request from '%v' for %v rejected, %d requests in flight", r.RemoteAddr, endpoint, maxInFlight)
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			Error(w, r, "", "too many requests in flight", http.StatusServiceUnavailable)
+			return
+		}
+
+		if timeout <= 0 {
+			defer l.release(endpoint)
+			next.ServeHTTP(w, r)
+			return
+		}
+		// -- the client is answered after the timeout, the handler keeps its place until it returned
+		// on its canceled context
+		counted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer l.release(endpoint)
+			next.ServeHTTP(w, r)
+			if r.Context().Err() == context.DeadlineExceeded {
+				rejectedCounterVec.WithLabelValues(endpoint, "timeout").Inc()
+			}
+		})
+		serveWithTimeout(w, r, counted, timeout, retryAfterSeconds)
+	})
+}
+
+// limits -- the timeout and maximum number of requests in flight of the endpoint
+func (l *Limiter) limits(endpoint string) (time.Duration, int) {
+	timeout, maxInFlight := l.timeout, l.maxInFlight
+	if l.longPolls[endpoint] {
+		timeout = 0
+	}
+	if limits, ok := l.endpoints[endpoint]; ok {
+		if limits.Timeout > 0 {
+			timeout = limits.Timeout
+		}
+		if limits.MaxInFlight > 0 {
+			maxInFlight = limits.MaxInFlight
+		}
+	}
+	return timeout, maxInFlight
+}
+
+// acquire -- counts the request of the endpoint if less than the maximum are in flight
+func (l *Limiter) acquire(endpoint string, maxInFlight int) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if maxInFlight > 0 && l.inFlight[endpoint] >= maxInFlight {
+		return false
+	}
+	l.inFlight[endpoint]++
+	inFlightGaugeVec.WithLabelValues(endpoint).Set(float64(l.inFlight[endpoint]))
+	return true
+}
+
+// release --
+func (l *Limiter) release(endpoint string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight[endpoint]--
+	inFlightGaugeVec.WithLabelValues(endpoint).Set(float64(l.inFlight[endpoint]))
+}
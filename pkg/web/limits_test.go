@@ -0,0 +1,64 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterTimeout(t *testing.T) {
+	limiter := NewLimiter(50*time.Millisecond, 0, nil)
+	handler := Recover(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			<-r.Context().Done()
+			w.Write([]byte("too late"))
+		case "/panic":
+			panic("broken handler")
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("done"))
+		}
+	})))
+
+	tests := []struct {
+		path       string
+		code       int
+		retryAfter string
+		body       string
+	}{
+		{"/fast", http.StatusAccepted, "", "done"},
+		{"/slow", http.StatusServiceUnavailable, "1", "request timed out\n"},
+		{"/panic", http.StatusInternalServerError, "", "Internal Server Error\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code || w.Header().Get("Retry-After") != test.retryAfter || w.Body.String() != test.body {
+			t.Errorf("%v: got %v with Retry-After %q and body %q, want %v with %q and %q", test.path, w.Code,
+				w.Header().Get("Retry-After"), w.Body.String(), test.code, test.retryAfter, test.body)
+		}
+	}
+}
+
+func TestLimiterInFlight(t *testing.T) {
+	limiter := NewLimiter(0, 1, nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/device", nil))
+	<-started
+	defer close(release)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/device", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("got %v with Retry-After %q, want 503 with 5", w.Code, w.Header().Get("Retry-After"))
+	}
+}
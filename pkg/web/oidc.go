@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the discovery of the issuer is retried at most once in the interval while it fails
+const discoveryMinInterval = time.Minute
+
+// signingAlgs -- the asymmetric algorithms accepted, symmetric ones and none are rejected
+var signingAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.PS256, oidc.PS384, oidc.PS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+}
+
+// oidcBackend -- accepts the bearer tokens signed by the keys of the issuer for the audience; the
+// verification is left to go-oidc, which fetches the key set again on an unknown key id
+type oidcBackend struct {
+	cfg    config.OIDC
+	client *http.Client
+
+	verifier  *oidc.IDTokenVerifier
+	attempted time.Time
+	mutex     sync.Mutex
+}
+
+// NewOIDC -- returns the authenticator of the bearer tokens of the OpenID Connect provider, the issuer
+// is discovered on the first token so the exporter starts while it's unavailable
+func NewOIDC(cfg config.OIDC) Authenticator {
+	return &oidcBackend{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Authenticate -- the principal is the subject of the token
+func (o *oidcBackend) Authenticate(r *http.Request) (string, []string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", nil, false
+	}
+
+	subject, claims, err := o.verify(r.Context(), strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	if err != nil {
+		log.Warnf("invalid bearer token from '%v': %v", r.RemoteAddr, err)
+		return "", nil, false
+	}
+	return "sub=" + subject, o.cfg.Roles(claimValues(claims[o.cfg.RolesClaim])), true
+}
+
+// challenge --
+func (o *oidcBackend) challenge() string {
+	return `Bearer realm="mystrom-exporter"`
+}
+
+// verify -- checks the signature, issuer, audience and validity period of the token and returns its
+// subject and claims
+func (o *oidcBackend) verify(ctx context.Context, token string) (string, map[string]interface{}, error) {
+	verifier, err := o.tokenVerifier()
+	if err != nil {
+		return "", nil, err
+	}
+	verified, err := verifier.Verify(oidc.ClientContext(ctx, o.client), token)
+	if err != nil {
+		return "", nil, err
+	}
+	claims := make(map[string]interface{})
+	if err := verified.Claims(&claims); err != nil {
+		return "", nil, fmt.Errorf("malformed claims: %v", err.Error())
+	}
+	return verified.Subject, claims, nil
+}
+
+// tokenVerifier -- the verifier of the issuer, with the key set of jwks_url or of the discovery document
+func (o *oidcBackend) tokenVerifier() (*oidc.IDTokenVerifier, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.verifier != nil {
+		return o.verifier, nil
+	}
+	if time.Since(o.attempted) < discoveryMinInterval {
+		return nil, fmt.Errorf("the oidc issuer isn't discovered yet")
+	}
+	o.attempted = time.Now()
+
+	// -- the context is kept by the key set for fetching the keys later on
+	ctx := oidc.ClientContext(context.Background(), o.client)
+	verifierConfig := &oidc.Config{ClientID: o.cfg.Audience, SupportedSigningAlgs: signingAlgs}
+	if o.cfg.JWKSURL != "" {
+		o.verifier = oidc.NewVerifier(o.cfg.Issuer, oidc.NewRemoteKeySet(ctx, o.cfg.JWKSURL), verifierConfig)
+		return o.verifier, nil
+	}
+	provider, err := oidc.NewProvider(ctx, o.cfg.Issuer)
+	if err != nil {
+		log.Errorf("failed to discover the oidc issuer: %v", err)
+		return nil, fmt.Errorf("the oidc issuer isn't discovered yet")
+	}
+	o.verifier = provider.Verifier(verifierConfig)
+	return o.verifier, nil
+}
+
+// claimValues -- the values of a claim being a string or a list of strings
+func claimValues(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
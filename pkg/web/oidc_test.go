@@ -0,0 +1,164 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// fakeIssuer -- serves the discovery document and the key set of an OpenID Connect provider
+type fakeIssuer struct {
+	server *httptest.Server
+	keys   jose.JSONWebKeySet
+}
+
+func newFakeIssuer(t *testing.T, keys ...jose.JSONWebKey) *fakeIssuer {
+	issuer := &fakeIssuer{}
+	for _, key := range keys {
+		issuer.keys.Keys = append(issuer.keys.Keys, key.Public())
+	}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                                issuer.server.URL,
+				"jwks_uri":                              issuer.server.URL + "/keys",
+				"id_token_signing_alg_values_supported": []string{"RS256", "ES256"},
+			})
+		case "/keys":
+			json.NewEncoder(w).Encode(issuer.keys)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return issuer
+}
+
+// sign -- a token with the claims signed by the key with the algorithm
+func sign(t *testing.T, key interface{}, alg jose.SignatureAlgorithm, kid string, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// unsigned -- a token with the claims and the algorithm none
+func unsigned(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload) + "."
+}
+
+func TestOIDCTokens(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := newFakeIssuer(t,
+		jose.JSONWebKey{Key: rsaKey, KeyID: "rsa", Algorithm: "RS256", Use: "sig"},
+		jose.JSONWebKey{Key: ecKey, KeyID: "ec", Algorithm: "ES256", Use: "sig"})
+	defer issuer.server.Close()
+
+	backend := NewOIDC(config.OIDC{
+		Issuer:     issuer.server.URL,
+		Audience:   "mystrom-exporter",
+		RolesClaim: "groups",
+		ClaimRoles: map[string][]string{"ops": {RoleAdmin}},
+	})
+
+	now := time.Now()
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":    issuer.server.URL,
+			"aud":    "mystrom-exporter",
+			"sub":    "alice",
+			"exp":    now.Add(time.Hour).Unix(),
+			"iat":    now.Unix(),
+			"groups": []string{"ops", RoleReadMetrics},
+		}
+		for name, value := range changes {
+			c[name] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"rsa", sign(t, rsaKey, jose.RS256, "rsa", claims(nil)), true},
+		{"ecdsa", sign(t, ecKey, jose.ES256, "ec", claims(nil)), true},
+		{"audience list", sign(t, rsaKey, jose.RS256, "rsa", claims(map[string]interface{}{
+			"aud": []string{"other", "mystrom-exporter"}})), true},
+		{"expired", sign(t, rsaKey, jose.RS256, "rsa", claims(map[string]interface{}{
+			"exp": now.Add(-time.Hour).Unix()})), false},
+		{"wrong audience", sign(t, rsaKey, jose.RS256, "rsa", claims(map[string]interface{}{
+			"aud": "grafana"})), false},
+		{"wrong issuer", sign(t, rsaKey, jose.RS256, "rsa", claims(map[string]interface{}{
+			"iss": "https://evil.example.com"})), false},
+		{"symmetric alg", sign(t, []byte("0123456789abcdef0123456789abcdef"), jose.HS256, "rsa", claims(nil)), false},
+		{"alg of another key type", sign(t, rsaKey, jose.PS256, "ec", claims(nil)), false},
+		{"bad signature", sign(t, otherKey, jose.RS256, "rsa", claims(nil)), false},
+		{"unknown key", sign(t, otherKey, jose.RS256, "other", claims(nil)), false},
+		{"unsigned", unsigned(t, claims(nil)), false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/inventory", nil)
+		r.Header.Set("Authorization", "Bearer "+test.token)
+		principal, roles, ok := backend.Authenticate(r)
+		if ok != test.valid {
+			t.Errorf("%v: got valid %v, want %v", test.name, ok, test.valid)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if principal != "sub=alice" {
+			t.Errorf("%v: got principal %v, want sub=alice", test.name, principal)
+		}
+		if want := []string{RoleAdmin, RoleReadMetrics}; !reflect.DeepEqual(roles, want) {
+			t.Errorf("%v: got roles %v, want %v", test.name, roles, want)
+		}
+	}
+}
+
+func TestOIDCWithoutBearer(t *testing.T) {
+	backend := NewOIDC(config.OIDC{Issuer: "http://127.0.0.1:1", Audience: "mystrom-exporter"})
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/inventory", nil)
+	r.SetBasicAuth("alice", "secret")
+	if _, _, ok := backend.Authenticate(r); ok {
+		t.Errorf("basic auth accepted as bearer token")
+	}
+}
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// the roles required by the routes of the exporter
+const (
+	RoleReadMetrics = "read-metrics"
+	RoleReadDevices = "read-devices"
+	RoleControl     = "control"
+	RoleAdmin       = "admin"
+)
+
+// Authenticator -- a backend identifying the principal of a request and its roles, false if the request
+// doesn't carry credentials of the backend or they are invalid
+type Authenticator interface {
+	Authenticate(r *http.Request) (string, []string, bool)
+}
+
+// challenger -- a backend asking clients for its credentials on unauthorized requests
+type challenger interface {
+	challenge() string
+}
+
+// Authorizer -- enforces the roles of the principals identified by the backends, the first backend
+// identifying the principal wins
+type Authorizer struct {
+	users        map[string]string
+	rolesEnabled bool
+	backends     []Authenticator
+}
+
+// NewAuthorizer -- client certificates take precedence over bearer tokens and basic auth
+func NewAuthorizer(cfg config.Web) *Authorizer {
+	a := &Authorizer{users: cfg.BasicAuthUsers, rolesEnabled: cfg.RolesEnabled()}
+	if len(cfg.ClientCertRoles) > 0 {
+		a.backends = append(a.backends, clientCertBackend{roles: cfg.ClientCertRoles})
+	}
+	if cfg.OIDC != nil {
+		a.backends = append(a.backends, NewOIDC(*cfg.OIDC))
+	}
+	if len(cfg.BasicAuthUsers) > 0 {
+		a.backends = append(a.backends, basicAuthBackend{users: cfg.BasicAuthUsers, roles: cfg.UserRoles})
+	}
+	return a
+}
+
+// Require -- only passes requests of principals having the role to the next handler. Without any roles
+// configured, every role but reading metrics requires one of the basic auth users if there are any, the
+// metrics stay open
+func (a *Authorizer) Require(role string, next http.Handler) http.Handler {
+	if !a.rolesEnabled {
+		if role != RoleReadMetrics && len(a.users) > 0 {
+			return BasicAuth(a.users, next)
+		}
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, roles, ok := a.identify(r)
+		if !ok {
+			log.Warnf("unauthorized request from '%v' for %v", r.RemoteAddr, r.URL.Path)
+			for _, backend := range a.backends {
+				if c, ok := backend.(challenger); ok {
+					w.Header().Add("WWW-Authenticate", c.challenge())
+				}
+			}
+			Error(w, r, "", http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		for _, granted := range roles {
+			if granted == role || granted == RoleAdmin {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		log.Warnf("forbidden request from '%v' as %v for %v, role %v required", r.RemoteAddr, principal, r.URL.Path, role)
+		Error(w, r, "", http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// identify -- returns the principal of the request and its roles
+func (a *Authorizer) identify(r *http.Request) (string, []string, bool) {
+	for _, backend := range a.backends {
+		if principal, roles, ok := backend.Authenticate(r); ok {
+			return principal, roles, true
+		}
+	}
+	return "", nil, false
+}
+
+// clientCertBackend -- the principal is the common name of a verified client certificate
+type clientCertBackend struct {
+	roles map[string][]string
+}
+
+// Authenticate --
+func (c clientCertBackend) Authenticate(r *http.Request) (string, []string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", nil, false
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	roles, ok := c.roles[cn]
+	if !ok {
+		return "", nil, false
+	}
+	return "CN=" + cn, roles, true
+}
+
+// basicAuthBackend -- the principal is the basic auth user
+type basicAuthBackend struct {
+	users map[string]string
+	roles map[string][]string
+}
+
+// Authenticate --
+func (b basicAuthBackend) Authenticate(r *http.Request) (string, []string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok || !authenticate(b.users, user, password) {
+		return "", nil, false
+	}
+	return user, b.roles[user], true
+}
+
+// challenge --
+func (b basicAuthBackend) challenge() string {
+	return `Basic realm="mystrom-exporter"`
+}
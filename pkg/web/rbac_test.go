@@ -0,0 +1,42 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- the bcrypt hash of "secret"
+const secretHash = "$2a$10$lkXQClFPUnby/SHalFHFIO8tZGjDvvQEtRYqcb2HXb2ozYzFLZ9LG"
+
+func TestRequireWithoutRoles(t *testing.T) {
+	auth := NewAuthorizer(config.Web{BasicAuthUsers: map[string]string{"ops": secretHash}})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		role     string
+		user     string
+		password string
+		want     int
+	}{
+		{RoleReadMetrics, "", "", http.StatusOK},
+		{RoleReadDevices, "", "", http.StatusUnauthorized},
+		{RoleControl, "", "", http.StatusUnauthorized},
+		{RoleControl, "ops", "wrong", http.StatusUnauthorized},
+		{RoleControl, "ops", "secret", http.StatusOK},
+		{RoleAdmin, "", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/relay", nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		w := httptest.NewRecorder()
+		auth.Require(test.role, ok).ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Errorf("role %v as '%v': got status %d, want %d", test.role, test.user, w.Code, test.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+const namespace = "mystrom_exporter"
+
+var panicsCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Number of panics in http handlers recovered by the exporter",
+	})
+
+// Collectors -- returns the metrics of the web layer to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{panicsCounter, deniedSourcesCounter, inFlightGaugeVec, rejectedCounterVec}
+}
+
+// Recover -- converts panics of the next handler into 500 responses instead of crashing the process,
+// aborted handlers are passed on to the server as usual
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			panicsCounter.Inc()
+			log.Errorf("panic serving %v for '%v': %v\n%s", r.URL.Path, r.RemoteAddr, err, debug.Stack())
+			Error(w, r, "", http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
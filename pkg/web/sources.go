@@ -0,0 +1,66 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var deniedSourcesCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_denied_sources_total",
+		Help:      "Number of requests rejected because their source address isn't in the allowed networks",
+	})
+
+// ParseNetworks -- parses a comma separated list of networks in CIDR notation, single addresses are
+// taken as networks of their own
+func ParseNetworks(networks string) ([]*net.IPNet, error) {
+	var parsed []*net.IPNet
+	for _, network := range splitList(networks) {
+		if ip := net.ParseIP(network); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			parsed = append(parsed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network '%v': %v", network, err.Error())
+		}
+		parsed = append(parsed, ipnet)
+	}
+	return parsed, nil
+}
+
+// AllowSources -- rejects requests whose remote address isn't in one of the networks with 403 before
+// they reach the next handler, without networks all requests pass. Only the address of the connection
+// counts, forwarded headers are ignored as they are set by the client
+func AllowSources(networks []*net.IPNet, next http.Handler) http.Handler {
+	if len(networks) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		deniedSourcesCounter.Inc()
+		log.Debugf("request from '%v' for %v denied, source isn't allowed", r.RemoteAddr, r.URL.Path)
+		Error(w, r, "", http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -- the time to resolve the name of a target
+const resolveTimeout = 5 * time.Second
+
+// TargetPolicy -- restricts the targets accepted as request parameter, to keep the exporter
+// from being used as a generic http prober
+type TargetPolicy struct {
+	mutex    sync.RWMutex
+	targets  map[string]bool
+	offered  map[string]bool
+	ports    map[string]bool
+	networks []*net.IPNet
+	// -- resolves the names of the targets, replaceable for tests
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewTargetPolicy -- creates the policy from comma separated lists of allowed ports and of
+// networks in CIDR notation, which are allowed although they are loopback or link-local
+func NewTargetPolicy(ports, networks string) (*TargetPolicy, error) {
+	p := &TargetPolicy{targets: make(map[string]bool), offered: make(map[string]bool), ports: make(map[string]bool),
+		lookup: net.DefaultResolver.LookupIPAddr}
+
+	for _, port := range splitList(ports) {
+		p.ports[port] = true
+	}
+
+	for _, network := range splitList(networks) {
+		_, ipnet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network '%v': %v", network, err.Error())
+		}
+		p.networks = append(p.networks, ipnet)
+	}
+
+	return p, nil
+}
+
+// Allow -- accepts the given targets regardless of their port or address, e.g. the configured devices
+func (p *TargetPolicy) Allow(targets ...string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, target := range targets {
+		p.targets[target] = true
+	}
+}
+
+// Offer -- accepts the targets of the providers regardless of their port or address, replacing the ones
+// offered before so targets no longer offered are refused again
+func (p *TargetPolicy) Offer(targets ...string) {
+	offered := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		offered[target] = true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.offered = offered
+}
+
+// Validate -- returns an error describing why the target isn't accepted; names are resolved and refused
+// if any of their addresses is local. As a name may resolve differently when dialing, e.g. by DNS
+// rebinding, the addresses dialed are checked again by CheckAddress
+func (p *TargetPolicy) Validate(target string) error {
+	if p.allowed(target) {
+		return nil
+	}
+	// -- e.g. devices behind a reverse proxy terminating tls
+	if i := strings.Index(target, "://"); i >= 0 {
+		if scheme := target[:i]; scheme != "http" && scheme != "https" {
+			return fmt.Errorf("scheme must be http or https")
+		}
+		target = target[i+len("://"):]
+	}
+	if strings.ContainsAny(target, "/?#@ \t") {
+		return fmt.Errorf("target must be a host with an optional port")
+	}
+
+	host := target
+	if net.ParseIP(target) == nil && strings.Contains(target, ":") {
+		var port string
+		var err error
+		if host, port, err = net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("invalid target: %v", err.Error())
+		}
+		if !p.ports[port] {
+			return fmt.Errorf("port %v isn't allowed", port)
+		}
+	}
+	if host == "" {
+		return fmt.Errorf("target must contain a host")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+	if name := strings.TrimSuffix(host, "."); name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return p.checkIP(net.IPv4(127, 0, 0, 1))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := p.lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %v: %v", host, err.Error())
+	}
+	for _, addr := range addrs {
+		if err := p.checkIP(addr.IP); err != nil {
+			return fmt.Errorf("%v resolves to a %v", host, err.Error())
+		}
+	}
+	return nil
+}
+
+// CheckAddress -- returns an error if the target must not be connected at the address, called for every
+// connection to a device so the address checked is the one dialed
+func (p *TargetPolicy) CheckAddress(target string, ip net.IP) error {
+	if p.allowed(target) {
+		return nil
+	}
+	return p.checkIP(ip)
+}
+
+// allowed -- whether the target is accepted regardless of its port and address
+func (p *TargetPolicy) allowed(target string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.targets[target] || p.offered[target]
+}
+
+// checkIP -- loopback, link-local, unspecified and multicast addresses are only accepted within the
+// allowed networks
+func (p *TargetPolicy) checkIP(ip net.IP) error {
+	if !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsUnspecified() && !ip.IsMulticast() {
+		return nil
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("local address %v isn't allowed", ip)
+}
+
+// splitList -- splits a comma separated list, ignoring empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
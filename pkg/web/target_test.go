@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// fakeLookup -- resolves the names of the map, the others fail
+func fakeLookup(names map[string][]string) func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ips, ok := names[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host")
+		}
+		var addrs []net.IPAddr
+		for _, ip := range ips {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		return addrs, nil
+	}
+}
+
+func TestTargetPolicyValidate(t *testing.T) {
+	policy, err := NewTargetPolicy("80,443", "10.0.0.0/8,fe80::/10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.lookup = fakeLookup(map[string][]string{
+		"plug.example.com":   {"192.168.1.10"},
+		"rebind.example.com": {"192.168.1.11", "127.0.0.1"},
+		"meta.example.com":   {"169.254.169.254"},
+		"v6.example.com":     {"fe80::1"},
+	})
+	policy.Allow("127.0.0.1:8080")
+
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{"192.168.1.10", true},
+		{"192.168.1.10:80", true},
+		{"192.168.1.10:8080", false},
+		{"https://192.168.1.10:443", true},
+		{"ftp://192.168.1.10", false},
+		{"127.0.0.1", false},
+		{"127.0.0.1:8080", true},
+		{"[::1]:80", false},
+		{"169.254.169.254", false},
+		{"10.0.0.1", true},
+		{"localhost", false},
+		{"localhost.", false},
+		{"plug.localhost", false},
+		{"plug.example.com", true},
+		{"rebind.example.com", false},
+		{"meta.example.com:80", false},
+		{"v6.example.com", true},
+		{"unknown.example.com", false},
+		{"plug.example.com/path", false},
+	}
+	for _, test := range tests {
+		if err := policy.Validate(test.target); (err == nil) != test.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", test.target, err, test.valid)
+		}
+	}
+}
+
+func TestTargetPolicyCheckAddress(t *testing.T) {
+	policy, err := NewTargetPolicy("80", "10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Allow("127.0.0.1:8080")
+
+	tests := []struct {
+		target string
+		ip     string
+		valid  bool
+	}{
+		{"plug.example.com", "192.168.1.10", true},
+		// -- the name resolved to a public address when validated, to loopback when dialed
+		{"plug.example.com", "127.0.0.1", false},
+		{"plug.example.com", "::1", false},
+		{"plug.example.com", "169.254.169.254", false},
+		{"plug.example.com", "10.1.2.3", true},
+		{"127.0.0.1:8080", "127.0.0.1", true},
+	}
+	for _, test := range tests {
+		if err := policy.CheckAddress(test.target, net.ParseIP(test.ip)); (err == nil) != test.valid {
+			t.Errorf("CheckAddress(%q, %v) = %v, want valid %v", test.target, test.ip, err, test.valid)
+		}
+	}
+}
+
+func TestTargetPolicyOffer(t *testing.T) {
+	policy, err := NewTargetPolicy("80,443", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Allow("127.0.0.1:8080")
+	policy.Offer("127.0.0.1:8081", "127.0.0.1:8082")
+	policy.Offer("127.0.0.1:8082")
+
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"127.0.0.1:8081", false},
+		{"127.0.0.1:8082", true},
+	}
+	for _, test := range tests {
+		if err := policy.Validate(test.target); (err == nil) != test.valid {
+			t.Errorf("Validate(%v) = %v, want valid %v", test.target, err, test.valid)
+		}
+	}
+}
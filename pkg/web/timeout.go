@@ -0,0 +1,95 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutWriter -- buffers the response of a handler until it returned, so it can be replaced by the
+// answer to a timeout; writes after the timeout fail
+type timeoutWriter struct {
+	mutex    sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+// Header --
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write --
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.body.Write(p)
+}
+
+// WriteHeader --
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// serveWithTimeout -- serves the request with the handler on a context canceled after the timeout, the
+// client is answered with 503 and the Retry-After header if the handler didn't return by then; like
+// http.TimeoutHandler, but the answer to a timeout is that of the other rejected requests
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, timeout time.Duration, retryAfter string) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	tw := &timeoutWriter{header: make(http.Header)}
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				panicked <- err
+			}
+		}()
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case err := <-panicked:
+		// -- raised again in the goroutine of the server, for Recover and the server to handle it
+		panic(err)
+	case <-done:
+		tw.mutex.Lock()
+		defer tw.mutex.Unlock()
+
+		for name, values := range tw.header {
+			w.Header()[name] = values
+		}
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		w.WriteHeader(tw.code)
+		w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		tw.mutex.Lock()
+		tw.timedOut = true
+		tw.mutex.Unlock()
+
+		w.Header().Set("Retry-After", retryAfter)
+		Error(w, r, "", "request timed out", http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,35 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// ConfigureTLS -- prepares the server to verify client certificates signed by the configured CA,
+// clients without a certificate may still authenticate with basic auth
+func ConfigureTLS(server *http.Server, cfg config.Web) error {
+	if cfg.ClientCAFile == "" {
+		return nil
+	}
+
+	pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("unable to read client CA: %v", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in client CA %v", cfg.ClientCAFile)
+	}
+
+	server.TLSConfig = &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}
+	return nil
+}
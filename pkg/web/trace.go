@@ -0,0 +1,22 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TraceID -- returns the trace id of the W3C trace context propagated with the request,
+// e.g. by Prometheus with tracing enabled, or an empty string
+func TraceID(r *http.Request) string {
+	// -- version-traceid-parentid-flags, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	parts := strings.Split(strings.TrimSpace(r.Header.Get("traceparent")), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ""
+	}
+
+	traceID := strings.ToLower(parts[1])
+	if strings.Trim(traceID, "0123456789abcdef") != "" || traceID == strings.Repeat("0", 32) {
+		return ""
+	}
+	return traceID
+}
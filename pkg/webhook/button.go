@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var wheelGaugeVec = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "mystrom",
+		Name:      "button_wheel",
+		Help:      "The latest wheel value reported by a button plus through its action webhook",
+	},
+	[]string{"mac"})
+
+// ObserveWheel -- records the wheel value of the button
+func ObserveWheel(mac string, value float64) {
+	touch(mac)
+	wheelGaugeVec.WithLabelValues(mac).Set(value)
+}
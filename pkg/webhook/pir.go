@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	motionEventsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mystrom",
+			Name:      "motion_events_total",
+			Help:      "Number of motions detected by a motion sensor, reported through its action webhook",
+		},
+		[]string{"mac"})
+	motionActiveGaugeVec = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "mystrom",
+			Name:      "motion_active",
+			Help:      "Whether a motion sensor currently detects motion, reported through its action webhook",
+		},
+		[]string{"mac"})
+)
+
+// ObserveMotion -- records the start or the stop of a motion, the actions start and motion mark the
+// start, stop and no_motion the stop
+func ObserveMotion(mac string, action string) error {
+	switch action {
+	case "start", "motion":
+		touch(mac)
+		motionEventsCounterVec.WithLabelValues(mac).Inc()
+		motionActiveGaugeVec.WithLabelValues(mac).Set(1)
+	case "stop", "no_motion":
+		touch(mac)
+		motionEventsCounterVec.WithLabelValues(mac).Add(0)
+		motionActiveGaugeVec.WithLabelValues(mac).Set(0)
+	default:
+		return fmt.Errorf("invalid action '%v', must be start, motion, stop or no_motion", action)
+	}
+	return nil
+}
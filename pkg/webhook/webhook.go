@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	// -- the networks devices may call the webhooks from, any if empty
+	allowedNetworks []*net.IPNet
+	// -- 0 doesn't limit the number of devices
+	maxDevices int
+	lastSeen   = make(map[string]time.Time)
+	mutex      sync.Mutex
+
+	eventsCounterVec = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "mystrom_exporter",
+			Name:      "webhook_events_total",
+			Help:      "Number of webhook calls by devices by kind and result",
+		},
+		[]string{"kind", "result"})
+)
+
+// Initialize -- restricts the webhooks to the comma separated networks in CIDR notation and limits
+// the number of devices remembered, the least recently seen ones are dropped first
+func Initialize(networks string, max int) error {
+	allowed := []*net.IPNet{}
+	for _, network := range strings.Split(networks, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(network)
+		if err != nil {
+			return fmt.Errorf("invalid network '%v': %v", network, err.Error())
+		}
+		allowed = append(allowed, ipnet)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	allowedNetworks = allowed
+	maxDevices = max
+	return nil
+}
+
+// Collectors -- returns the metrics of the webhooks to be registered by the exporter
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{eventsCounterVec, wheelGaugeVec, motionEventsCounterVec, motionActiveGaugeVec}
+}
+
+// Allowed -- returns whether the remote address of a request may call the webhooks
+func Allowed(remoteAddr string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(allowedNetworks) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	for _, network := range allowedNetworks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count -- counts a webhook call by its kind and result
+func Count(kind, result string) {
+	eventsCounterVec.WithLabelValues(kind, result).Inc()
+}
+
+// ParseMac -- returns the mac address of the calling device as 12 upper case hex digits
+func ParseMac(value string) (string, error) {
+	mac := strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(value))
+	if _, err := hex.DecodeString(mac); err != nil || len(mac) != 12 {
+		return "", fmt.Errorf("invalid mac address '%v'", value)
+	}
+	return mac, nil
+}
+
+// touch -- marks the device as seen and drops the metrics of the least recently seen devices above the maximum
+func touch(mac string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lastSeen[mac] = time.Now()
+	for maxDevices > 0 && len(lastSeen) > maxDevices {
+		var oldest string
+		for m, seen := range lastSeen {
+			if oldest == "" || seen.Before(lastSeen[oldest]) {
+				oldest = m
+			}
+		}
+		log.Infof("dropping webhook metrics of least recently seen device '%v'", oldest)
+		delete(lastSeen, oldest)
+		forget(oldest)
+	}
+}
+
+// forget -- removes the metrics of the device
+func forget(mac string) {
+	wheelGaugeVec.DeleteLabelValues(mac)
+	motionEventsCounterVec.DeleteLabelValues(mac)
+	motionActiveGaugeVec.DeleteLabelValues(mac)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/discover"
+	"mystrom-exporter/pkg/mystrom"
+	"mystrom-exporter/pkg/poller"
+	"mystrom-exporter/pkg/provider"
+	"mystrom-exporter/pkg/shard"
+)
+
+var (
+	// -- the targets currently polled
+	polled      = make(map[string]bool)
+	polledMutex sync.Mutex
+)
+
+// setupProviders -- registers the providers of the targets, the devices of the configuration first
+func setupProviders(cfg *config.Config) error {
+	configured := make([]provider.Target, 0, len(cfg.Devices))
+	for _, d := range cfg.Devices {
+		configured = append(configured, provider.Target{Target: d.Target, Mac: mystrom.NormalizeMac(d.Mac)})
+	}
+	provider.Register(provider.NewStatic("config", configured))
+
+	if *enableDiscovery {
+		provider.Register(discover.Provider{})
+	}
+
+	if cfg.Providers.Kubernetes != nil {
+		kubernetes, err := provider.NewKubernetes(*cfg.Providers.Kubernetes)
+		if err != nil {
+			return err
+		}
+		provider.Register(kubernetes)
+	}
+
+	if cfg.Providers.HTTP != nil {
+		provider.Register(provider.NewHTTP(*cfg.Providers.HTTP))
+	}
+
+	if cfg.Providers.File != nil {
+		provider.Register(provider.NewFile(cfg.Providers.File.Path))
+	}
+
+	if cfg.Providers.Netbox != nil {
+		provider.Register(provider.NewNetbox(*cfg.Providers.Netbox))
+	}
+
+	if cfg.Providers.PhpIPAM != nil {
+		provider.Register(provider.NewPhpIPAM(*cfg.Providers.PhpIPAM))
+	}
+
+	if leases := cfg.Providers.DHCPLeases; leases != nil {
+		provider.Register(provider.NewLeases(leases.Path, leases.Format, probeDevice))
+	}
+	return nil
+}
+
+// probeDevice -- asks the target for its mac address and type, for the providers offering candidates
+func probeDevice(target string) (string, string, error) {
+	info, err := mystrom.NewExporter(target).FetchInfo()
+	if err != nil {
+		return "", "", err
+	}
+	return info.Mac, fmt.Sprintf("%v", info.SwType), nil
+}
+
+// discoveryRequired -- whether the service discovery is served
+func discoveryRequired(cfg *config.Config) bool {
+	return *enableDiscovery || cfg.Providers.Enabled()
+}
+
+// discoveryAddress -- the address of the exporter offered in the service discovery
+func discoveryAddress() string {
+	host, port, err := net.SplitHostPort(*listenAddress)
+	if err != nil || host != "" {
+		return *listenAddress
+	}
+	return net.JoinHostPort(discover.OutboundIP().String(), port)
+}
+
+// pollTargets -- returns the targets belonging to the shard of this instance, assigned the same way as
+// the ones offered by the service discovery
+func pollTargets(all []provider.Target) []string {
+	owned := provider.Owned(all)
+	targets := make([]string, 0, len(owned))
+	for _, t := range owned {
+		targets = append(targets, t.Target)
+	}
+	if shard.Enabled() {
+		log.Infof("shard %v owns %d of %d targets", shard.String(), len(targets), len(all))
+	}
+	return targets
+}
+
+// startPolling -- polls the targets and stops polling the ones no longer offered by any provider
+func startPolling(targets []string) {
+	polledMutex.Lock()
+	defer polledMutex.Unlock()
+
+	current := make(map[string]bool)
+	for _, target := range targets {
+		current[target] = true
+	}
+	for target := range polled {
+		if !current[target] {
+			log.Infof("target '%v' is no longer offered, stopping to poll it", target)
+			poller.Remove(target)
+		}
+	}
+	polled = current
+
+	if *pollInterval > 0 {
+		poller.Initialize(targets, *pollInterval)
+	}
+	if *relayPollInterval > 0 {
+		poller.InitializeRelay(targets, *relayPollInterval)
+	}
+}
+
+// offerTargets -- accepts the targets of the providers as request parameter, only as long as they are offered
+func offerTargets(all []provider.Target) {
+	offered := make([]string, 0, len(all))
+	for _, t := range all {
+		offered = append(offered, t.Target)
+	}
+	targetPolicy.Offer(offered...)
+}
+
+// targetsChanged -- called by the providers with all targets after the targets of one of them changed
+func targetsChanged(all []provider.Target) {
+	offerTargets(all)
+	if *pollInterval > 0 || *relayPollInterval > 0 {
+		startPolling(pollTargets(all))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/exposition"
+	"mystrom-exporter/pkg/web"
+)
+
+// scrapeRoutes -- registers the exporters own metrics and the device path
+func scrapeRoutes(router *mux.Router, auth *web.Authorizer, telemetryRegistry *prometheus.Registry) {
+	router.Handle(*metricsPath, auth.Require(web.RoleReadMetrics,
+		promhttp.HandlerFor(exposition.WithLabels(telemetryRegistry, exporterLabels),
+			promhttp.HandlerOpts{EnableOpenMetrics: true})))
+	router.Handle(*devicePath, auth.Require(web.RoleReadMetrics, http.HandlerFunc(scrapeHandler)))
+}
+
+// discoveryRoutes -- registers the service discovery and the scrapes of discovered devices
+func discoveryRoutes(router *mux.Router, auth *web.Authorizer, cfg *config.Config) {
+	if *enableDiscovery {
+		router.Handle("/device_by_mac/{macaddr}", auth.Require(web.RoleReadMetrics, http.HandlerFunc(scrapeHandlerByMac)))
+	}
+	if discoveryRequired(cfg) {
+		router.Handle("/discover", auth.Require(web.RoleReadMetrics, http.HandlerFunc(discoverHandler)))
+	}
+}
+
+// webhookRoutes -- registers the receivers of the action urls called by the devices, they can't
+// authenticate and are restricted by their source address instead
+func webhookRoutes(router *mux.Router) {
+	if *enableWebhooks {
+		router.HandleFunc("/api/v1/webhook/button", buttonWebhookHandler).Methods(http.MethodGet, http.MethodPost)
+		router.HandleFunc("/api/v1/webhook/pir", pirWebhookHandler).Methods(http.MethodGet, http.MethodPost)
+	}
+}
+
+// apiRoutes -- registers the api and the admin endpoints
+func apiRoutes(router *mux.Router, auth *web.Authorizer, cfg *config.Config) {
+	router.Handle("/api/v1/config", auth.Require(web.RoleReadDevices, configHandler(cfg))).Methods(http.MethodGet)
+	router.Handle("/api/v1/targets", auth.Require(web.RoleReadDevices, http.HandlerFunc(targetsHandler))).Methods(http.MethodGet)
+	router.Handle("/api/v1/metrics/legacy", auth.Require(web.RoleReadDevices, http.HandlerFunc(legacyNamesHandler))).Methods(http.MethodGet)
+	router.Handle("/api/v1/inventory", auth.Require(web.RoleReadDevices, http.HandlerFunc(inventoryHandler))).Methods(http.MethodGet)
+	router.Handle("/api/v1/groups/{name}/summary", auth.Require(web.RoleReadMetrics, http.HandlerFunc(groupSummaryHandler))).Methods(http.MethodGet)
+	if *enableDiscovery && *discoveryRawBuffer > 0 {
+		router.Handle("/api/v1/discovery/raw", auth.Require(web.RoleReadDevices, http.HandlerFunc(rawDiscoveryHandler))).Methods(http.MethodGet)
+	}
+	if *enableDiscovery {
+		router.Handle("/api/v1/discovery/registry", auth.Require(web.RoleReadDevices, http.HandlerFunc(discoveryRegistryHandler))).Methods(http.MethodGet)
+	}
+	router.Handle("/api/v1/relay/wait", auth.Require(web.RoleReadMetrics, http.HandlerFunc(relayWaitHandler)))
+	if *enableControl {
+		router.Handle("/api/v1/relay", auth.Require(web.RoleControl, http.HandlerFunc(relayControlHandler))).Methods(http.MethodPost)
+	}
+	if cfg.Web.AuthEnabled() {
+		admin := router.PathPrefix("/api/v1/devices/{mac}").Subrouter()
+		admin.Handle("/reboot", auth.Require(web.RoleAdmin, http.HandlerFunc(rebootHandler))).Methods(http.MethodPost)
+		admin.Handle("/firmware/check", auth.Require(web.RoleAdmin, http.HandlerFunc(firmwareCheckHandler))).Methods(http.MethodPost)
+		admin.Handle("/settings", auth.Require(web.RoleReadDevices, http.HandlerFunc(settingsHandler))).Methods(http.MethodGet)
+		admin.Handle("/settings/diff", auth.Require(web.RoleReadDevices, http.HandlerFunc(settingsDiffHandler))).Methods(http.MethodGet)
+		admin.Handle("/name", auth.Require(web.RoleAdmin, http.HandlerFunc(setNameHandler))).Methods(http.MethodPut)
+		admin.Handle("/annotations", auth.Require(web.RoleReadDevices, http.HandlerFunc(annotationsHandler))).Methods(http.MethodGet)
+		admin.Handle("/annotations", auth.Require(web.RoleAdmin, http.HandlerFunc(setAnnotationsHandler))).Methods(http.MethodPut)
+		admin.Handle("/proxy/{path:.*}", auth.Require(web.RoleReadDevices, proxyHandler(cfg.Web.ProxyPaths))).Methods(http.MethodGet)
+	} else {
+		log.Info("admin endpoints are disabled, no basic_auth_users, client_cert_roles or oidc configured")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"mystrom-exporter/pkg/mystrom"
+)
+
+// runSelftest -- exercises all known endpoints of a device and prints what it supports,
+// returns the exit code of the command
+func runSelftest(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	target := flags.String("target", "", "Address of the device to test")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "selftest: --target must be specified")
+		return 2
+	}
+
+	exporter := mystrom.NewExporter(*target)
+	fmt.Fprintf(out, "target:   %v\n", *target)
+
+	info, infoErr := exporter.FetchInfo()
+	if infoErr == nil {
+		fmt.Fprintf(out, "device:   type %v (%v), firmware %v, mac %v\n",
+			info.SwType, mystrom.DeviceKind(info.SwType), info.Version, info.Mac)
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tSUPPORTED\tDURATION\tDETAILS")
+	supported := 0
+	capabilities := make(map[string]bool)
+	for _, result := range exporter.Probe() {
+		capabilities[result.Path] = result.Err == nil
+		if result.Err != nil {
+			fmt.Fprintf(tw, "%v\tno\t%v\t%v\n", result.Path, result.Duration.Round(time.Millisecond), result.Err)
+			continue
+		}
+		supported++
+		fmt.Fprintf(tw, "%v\tyes\t%v\t\n", result.Path, result.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+	fmt.Fprintln(out)
+
+	switch {
+	case supported == 0:
+		fmt.Fprintln(out, "the target answered none of the endpoints, check the address and that it is a myStrom device")
+		return 1
+	case infoErr != nil:
+		fmt.Fprintf(out, "unable to determine the device type: %v\n", infoErr)
+		return 1
+	}
+
+	fmt.Fprintf(out, "selected module: %v\n", mystrom.SelectModule(info.SwType, capabilities))
+	return 0
+}
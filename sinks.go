@@ -0,0 +1,44 @@
+package main
+
+import (
+	"mystrom-exporter/pkg/config"
+	"mystrom-exporter/pkg/history"
+	"mystrom-exporter/pkg/poller"
+	"mystrom-exporter/pkg/sink"
+)
+
+// setupSinks -- registers the cache of the scrapes, the history of the ui and the configured sinks the
+// polls are pushed to
+func setupSinks(cfg *config.Config) error {
+	sink.Register(poller.Cache())
+	sink.Register(history.Sink())
+
+	if cfg.Sinks.JSONFile != nil {
+		jsonFile, err := sink.NewJSONFile(cfg.Sinks.JSONFile.Path)
+		if err != nil {
+			return err
+		}
+		if err := sink.RegisterPush(jsonFile, cfg.Sinks.JSONFile.SinkOptions); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Sinks.InfluxDB != nil {
+		if err := sink.RegisterPush(sink.NewInfluxDB(*cfg.Sinks.InfluxDB), cfg.Sinks.InfluxDB.SinkOptions); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Sinks.MQTT != nil {
+		if err := sink.RegisterPush(sink.NewMQTT(*cfg.Sinks.MQTT), cfg.Sinks.MQTT.SinkOptions); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Sinks.RemoteWrite != nil {
+		if err := sink.RegisterPush(sink.NewRemoteWrite(*cfg.Sinks.RemoteWrite), cfg.Sinks.RemoteWrite.SinkOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/config"
+)
+
+// -- flags whose names contain one of these are redacted in the summary
+var secretFlagNames = []string{"password", "secret", "token"}
+
+// configSummary -- the effective configuration, the flags including their defaults and the file
+type configSummary struct {
+	Flags      map[string]string      `json:"flags"`
+	ConfigFile string                 `json:"config_file,omitempty"`
+	Config     map[string]interface{} `json:"config"`
+}
+
+// summarizeConfig -- returns the effective configuration with the secrets redacted
+func summarizeConfig(cfg *config.Config) configSummary {
+	flags := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		if isDeprecatedAlias(f.Name) {
+			return
+		}
+		value := f.Value.String()
+		for _, secret := range secretFlagNames {
+			if strings.Contains(f.Name, secret) && value != "" {
+				value = "REDACTED"
+			}
+		}
+		flags[f.Name] = value
+	})
+
+	return configSummary{Flags: flags, ConfigFile: *configFile, Config: cfg.Redacted()}
+}
+
+// logConfig -- logs the effective configuration as single line on startup
+func logConfig(cfg *config.Config) {
+	summary := summarizeConfig(cfg)
+	flags, _ := json.Marshal(summary.Flags)
+	file, _ := json.Marshal(summary.Config)
+	log.With("flags", string(flags)).With("config_file", summary.ConfigFile).With("config", string(file)).
+		Info("effective configuration")
+}
+
+// configHandler -- returns the effective configuration with the secrets redacted
+func configHandler(cfg *config.Config) http.Handler {
+	summary := summarizeConfig(cfg)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, summary)
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/history"
+)
+
+// -- the size of a sparkline in pixels
+const (
+	sparklineWidth  = 240
+	sparklineHeight = 32
+)
+
+// sparkline -- the points of a metric of a device scaled to the sparkline
+type sparkline struct {
+	Width    int
+	Height   int
+	Points   string
+	Latest   string
+	Min, Max string
+}
+
+// uiDevice -- a row of the ui
+type uiDevice struct {
+	Target      string
+	Power       *sparkline
+	Temperature *sparkline
+}
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<html>
+<head>
+	<title>myStrom Exporter</title>
+	<meta http-equiv="refresh" content="60">
+	<style>
+		body { font-family: sans-serif; }
+		td, th { padding: 4px 12px; text-align: left; }
+		polyline { fill: none; stroke: #1f77b4; stroke-width: 1.5; }
+		.range { color: #888; font-size: smaller; }
+	</style>
+</head>
+<body>
+<h1>myStrom Exporter</h1>
+{{if not .Polling}}<p>The history is only recorded in polling mode, see <code>poll.interval</code>.</p>{{end}}
+<table>
+<tr><th>Target</th><th colspan="2">Power (W), last hour</th><th colspan="2">Temperature (°C), last hour</th></tr>
+{{range .Devices}}<tr>
+	<td><a href="{{$.DevicePath}}?target={{.Target}}">{{.Target}}</a></td>
+	{{template "sparkline" .Power}}
+	{{template "sparkline" .Temperature}}
+</tr>
+{{end}}</table>
+</body>
+</html>
+{{define "sparkline"}}{{if .}}<td><svg width="{{.Width}}" height="{{.Height}}"><polyline points="{{.Points}}"/></svg></td>
+	<td>{{.Latest}} <span class="range">{{.Min}} - {{.Max}}</span></td>{{else}}<td></td><td></td>{{end}}{{end}}`))
+
+// uiHandler -- renders the power and temperature of the last hour of the polled devices
+func uiHandler(w http.ResponseWriter, r *http.Request) {
+	var devices []uiDevice
+	for _, target := range history.Targets() {
+		devices = append(devices, uiDevice{
+			Target:      target,
+			Power:       newSparkline(history.Series(target, "mystrom_power")),
+			Temperature: newSparkline(history.Series(target, "mystrom_temperature")),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := uiTemplate.Execute(w, map[string]interface{}{
+		"Polling":    *pollInterval > 0,
+		"DevicePath": *devicePath,
+		"Devices":    devices,
+	})
+	if err != nil {
+		log.Errorf("failed to render the ui: %v", err)
+	}
+}
+
+// newSparkline -- scales the points to the sparkline, the time axis covers the whole window
+func newSparkline(points []history.Point) *sparkline {
+	if len(points) == 0 {
+		return nil
+	}
+	min, max := points[0].Value, points[0].Value
+	for _, p := range points {
+		if p.Value < min {
+			min = p.Value
+		}
+		if p.Value > max {
+			max = p.Value
+		}
+	}
+	span := max - min
+
+	start := time.Now().Add(-history.Window)
+	coordinates := make([]string, 0, len(points))
+	for _, p := range points {
+		x := float64(p.Time.Sub(start)) / float64(history.Window) * sparklineWidth
+		y := float64(sparklineHeight) / 2
+		if max > min {
+			y = sparklineHeight - 1 - (p.Value-min)/span*(sparklineHeight-2)
+		}
+		coordinates = append(coordinates, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return &sparkline{
+		Width:  sparklineWidth,
+		Height: sparklineHeight,
+		Points: strings.Join(coordinates, " "),
+		Latest: fmt.Sprintf("%.1f", points[len(points)-1].Value),
+		Min:    fmt.Sprintf("%.1f", min),
+		Max:    fmt.Sprintf("%.1f", max),
+	}
+}
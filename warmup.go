@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// warmUp -- scrapes the targets once with bounded concurrency, to populate the caches and
+// counters before the first scrape by Prometheus
+func warmUp(targets []string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	start := time.Now()
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var failedMutex sync.Mutex
+	failed := 0
+
+	for _, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(target string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if _, _, err := scrapeTarget(context.Background(), target, ""); err != nil {
+				failedMutex.Lock()
+				failed++
+				failedMutex.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	log.Infof("warm-up scrape of %d targets finished in %v, %d failed", len(targets), time.Since(start), failed)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/common/log"
+
+	"mystrom-exporter/pkg/web"
+	"mystrom-exporter/pkg/webhook"
+)
+
+// webhookMac -- checks the caller may use the webhooks and returns its mac parameter, writes
+// the error and returns false otherwise
+func webhookMac(w http.ResponseWriter, r *http.Request, kind string) (string, bool) {
+	if !webhook.Allowed(r.RemoteAddr) {
+		webhook.Count(kind, "forbidden")
+		web.Error(w, r, "", fmt.Sprintf("webhooks aren't allowed from '%v'", r.RemoteAddr), http.StatusForbidden)
+		return "", false
+	}
+	if err := r.ParseForm(); err != nil {
+		webhook.Count(kind, "invalid")
+		web.Error(w, r, "", fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return "", false
+	}
+	mac, err := webhook.ParseMac(r.Form.Get("mac"))
+	if err != nil {
+		webhook.Count(kind, "invalid")
+		web.Error(w, r, "", err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return mac, true
+}
+
+// buttonWebhookHandler -- receives the action urls of a button, called with the parameters mac,
+// action and for the wheel of a button plus its value
+func buttonWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	mac, ok := webhookMac(w, r, "button")
+	if !ok {
+		return
+	}
+
+	if value := r.Form.Get("wheel"); value != "" {
+		wheel, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			webhook.Count("button", "invalid")
+			web.Error(w, r, "", fmt.Sprintf("invalid 'wheel' parameter '%v'", value), http.StatusBadRequest)
+			return
+		}
+		webhook.ObserveWheel(mac, wheel)
+	}
+
+	log.Debugf("got button webhook from '%v' for device '%v': %v", r.RemoteAddr, mac, r.Form)
+	webhook.Count("button", "ok")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pirWebhookHandler -- receives the action urls of a motion sensor, called with the parameters mac
+// and action for the start and the stop of a motion
+func pirWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	mac, ok := webhookMac(w, r, "pir")
+	if !ok {
+		return
+	}
+
+	if err := webhook.ObserveMotion(mac, r.Form.Get("action")); err != nil {
+		webhook.Count("pir", "invalid")
+		web.Error(w, r, "", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Debugf("got motion webhook from '%v' for device '%v': %v", r.RemoteAddr, mac, r.Form)
+	webhook.Count("pir", "ok")
+	w.WriteHeader(http.StatusNoContent)
+}